@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+)
+
+var accountsCmd = &cobra.Command{
+	Use:   "accounts",
+	Short: "Manage accounts",
+	Long:  `List the chat network accounts connected to Beeper.`,
+}
+
+var accountsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List connected accounts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := getOutputOptions(output.AccountColumns)
+		if err != nil {
+			return err
+		}
+
+		client := getAPIClient()
+
+		accounts, err := client.ListAccounts()
+		if err != nil {
+			return fmt.Errorf("failed to list accounts: %w", err)
+		}
+
+		opts.Envelope = newEnvelope(cmd, client, false, "")
+		return output.WriteAccounts(cmd.OutOrStdout(), accounts, getOutputFormat(), opts)
+	},
+}
+
+func init() {
+	accountsCmd.AddCommand(accountsListCmd)
+	rootCmd.AddCommand(accountsCmd)
+}
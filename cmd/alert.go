@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/alert"
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/watch"
+)
+
+var (
+	alertMatch       string
+	alertChatIDs     []string
+	alertSender      string
+	alertExec        string
+	alertInterval    time.Duration
+	alertDebounce    time.Duration
+	alertConcurrency int
+	alertTimeout     time.Duration
+	alertStateFile   string
+)
+
+var alertCmd = &cobra.Command{
+	Use:   "alert --match <regex> --exec <command>",
+	Short: "Run a command when an incoming message matches a pattern",
+	Long: `Watch for incoming messages matching --match, a regular expression, and run
+--exec through the shell for each one. Messages are polled every --interval
+from the given --chat-id chats, or from the most recently active chats. Your
+own messages never fire an alert.
+
+The command gets the message as JSON on stdin and these environment variables:
+  BEEPER_CHAT_ID, BEEPER_CHAT_TITLE, BEEPER_NETWORK
+  BEEPER_MESSAGE_ID, BEEPER_SENDER, BEEPER_SENDER_ID, BEEPER_TEXT, BEEPER_TIMESTAMP
+  BEEPER_MATCH          The text the pattern matched
+
+After an alert fires in a chat, further matches there are suppressed for
+--debounce. At most --concurrency commands run at a time, each killed after
+--timeout; a failing command is reported, not retried.
+
+How far each chat has been read is saved to a state file once each poll's
+commands have finished, so a restart picks up where the last poll left off.
+Delivery is at-least-once: if the process dies while commands are running,
+that poll's messages are read again and their commands may run twice. After
+a downtime every message since the previous poll is read, however many pages
+back that goes. Messages sent before the first run don't fire. The default
+state file is derived from the flags (~/.beeper-api-cli/alerts/<hash>.json);
+pass --state to keep it elsewhere.`,
+	Example: `  beeper alert --match '(?i)site (is )?down' --chat-id acme --exec 'notify-send "$BEEPER_CHAT_TITLE" "$BEEPER_TEXT"'
+  beeper alert --match '(?i)urgent' --sender 'Ann Lee' --exec './page-oncall.sh'
+  beeper alert --match . --chat-id ops --debounce 0 --exec 'jq -r .text >> ops.log'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if alertMatch == "" {
+			return fmt.Errorf("--match is required")
+		}
+		if alertExec == "" {
+			return fmt.Errorf("--exec is required")
+		}
+		pattern, err := regexp.Compile(alertMatch)
+		if err != nil {
+			return fmt.Errorf("invalid --match: %w", err)
+		}
+		if alertInterval < time.Second {
+			return fmt.Errorf("--interval must be at least 1s")
+		}
+		if alertConcurrency < 1 {
+			return fmt.Errorf("--concurrency must be positive")
+		}
+
+		client := getAPIClient()
+		chats, err := resolveChats(client, alertChatIDs)
+		if err != nil {
+			return err
+		}
+
+		statePath := alertStateFile
+		if statePath == "" {
+			statePath = defaultAlertStatePath(chats)
+		}
+		var state alert.State
+		if err := watch.LoadState(statePath, &state); err != nil {
+			return err
+		}
+
+		watcher := &watch.Watcher{Source: client, Chats: chats, State: &state.State}
+		runner := &alert.Runner{
+			Command:     alertExec,
+			Matcher:     alert.Matcher{Text: pattern, Sender: alertSender},
+			Debounce:    alertDebounce,
+			Concurrency: alertConcurrency,
+			Timeout:     alertTimeout,
+			State:       &state,
+			Stdout:      cmd.OutOrStdout(),
+			Stderr:      cmd.ErrOrStderr(),
+		}
+		if !quietMode {
+			runner.Log = os.Stderr
+			scope := "the most recently active chats"
+			if len(chats) > 0 {
+				scope = fmt.Sprintf("%d chats", len(chats))
+			}
+			fmt.Fprintf(os.Stderr, "Watching %s every %s for /%s/ (state: %s)\n", scope, alertInterval, alertMatch, statePath)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return watcher.Run(ctx, alertInterval, func(events []watch.Event) error {
+			// Commands aren't tied to ctx, so an interrupt lets them finish
+			// before the state is saved
+			runner.Handle(cmd.Context(), events)
+			return watch.SaveState(statePath, &state)
+		}, func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		})
+	},
+}
+
+// defaultAlertStatePath names the state file after the flags defining the
+// alert, so different alerts keep separate state
+func defaultAlertStatePath(chats []api.Chat) string {
+	var chatIDs []string
+	for _, chat := range chats {
+		chatIDs = append(chatIDs, chat.ID)
+	}
+	slices.Sort(chatIDs)
+	sum := sha256.Sum256([]byte(strings.Join([]string{alertMatch, alertSender, alertExec, strings.Join(chatIDs, ",")}, "\x00")))
+	return filepath.Join(filepath.Dir(config.GetConfigPath()), "alerts", hex.EncodeToString(sum[:6])+".json")
+}
+
+func init() {
+	alertCmd.Flags().StringVar(&alertMatch, "match", "", "Regular expression the message text must match (e.g. '(?i)site down')")
+	alertCmd.Flags().StringArrayVar(&alertChatIDs, "chat-id", nil, "Watch only this chat, by ID, alias or title (repeatable)")
+	alertCmd.Flags().StringVar(&alertSender, "sender", "", "Only alert on messages from this sender name or ID (case-insensitive)")
+	alertCmd.Flags().StringVar(&alertExec, "exec", "", "Shell command to run for each matching message")
+	alertCmd.Flags().DurationVar(&alertInterval, "interval", 5*time.Second, "How often to poll for new messages")
+	alertCmd.Flags().DurationVar(&alertDebounce, "debounce", time.Minute, "Suppress further alerts in a chat for this long after one fires (0 to fire every time)")
+	alertCmd.Flags().IntVar(&alertConcurrency, "concurrency", 4, "Number of commands run at the same time")
+	alertCmd.Flags().DurationVar(&alertTimeout, "timeout", time.Minute, "Kill a command that runs longer than this (0 for no limit)")
+	alertCmd.Flags().StringVar(&alertStateFile, "state", "", "State file recording the messages already processed")
+	registerFlagCompletion(alertCmd, "chat-id", completeChats)
+	rootCmd.AddCommand(alertCmd)
+}
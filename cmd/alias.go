@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/chatref"
+	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+)
+
+// aliasEntry is the structured form of one alias in 'beeper alias list'
+type aliasEntry struct {
+	Name   string `json:"name"`
+	ChatID string `json:"chatID"`
+}
+
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Name chats so commands can refer to them",
+	Long: `Give chats short names usable anywhere a chat is expected.
+
+Every --chat-id flag (and 'chats get') accepts, in order of precedence:
+  an alias             beeper send --chat-id family --message hi
+  a chat ID            !abc123:beeper.local
+  an exact title       "Family Group" (case-insensitive)
+  a fuzzy title        fam, "fam grp"
+
+A title that matches several chats equally well is refused with the
+candidates listed. Chats written to (send, alias set, rule forward
+targets) don't accept letters-in-order matches like "mmdd", so a loose
+guess can't send to the wrong chat. The chat list used for titles is
+cached for a minute.
+
+Aliases are stored in ~/.beeper-api-cli/aliases.yaml.`,
+}
+
+var aliasSetCmd = &cobra.Command{
+	Use:   "set <name> <chat>",
+	Short: "Create or change an alias",
+	Long: `Point an alias at a chat. The chat may be given by ID or title, and is
+resolved to its ID when the alias is saved.`,
+	Example: `  beeper alias set family '!abc123:beeper.local'
+  beeper alias set team "Eng Team"`,
+	Args: cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 1 {
+			return completeChats(cmd, args, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := strings.ToLower(args[0])
+		if err := chatref.ValidateAliasName(name); err != nil {
+			return err
+		}
+
+		aliases, err := chatref.LoadAliases(aliasesPath())
+		if err != nil {
+			return err
+		}
+		// Resolve without the alias itself, so re-pointing one isn't circular
+		delete(aliases, name)
+		resolver, err := newChatResolver(getAPIClient())
+		if err != nil {
+			return err
+		}
+		resolver.Aliases = aliases
+		chatID, err := resolver.ResolveTarget(args[1])
+		if err != nil {
+			return err
+		}
+
+		aliases[name] = chatID
+		if err := aliases.Save(aliasesPath()); err != nil {
+			return err
+		}
+		if !quietMode {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s -> %s\n", name, chatID)
+		}
+		return nil
+	},
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List aliases",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		aliases, err := chatref.LoadAliases(aliasesPath())
+		if err != nil {
+			return err
+		}
+
+		entries := make([]aliasEntry, 0, len(aliases))
+		for _, name := range aliases.Names() {
+			entries = append(entries, aliasEntry{Name: name, ChatID: aliases[name]})
+		}
+
+		if format, ok := getReportFormat(cmd); ok {
+			return output.WriteValue(cmd.OutOrStdout(), entries, format)
+		}
+
+		w := cmd.OutOrStdout()
+		if len(entries) == 0 {
+			fmt.Fprintln(w, "No aliases (create one with 'beeper alias set <name> <chat>')")
+			return nil
+		}
+		for _, e := range entries {
+			fmt.Fprintf(w, "%s\t%s\n", e.Name, e.ChatID)
+		}
+		return nil
+	},
+}
+
+var aliasRemoveCmd = &cobra.Command{
+	Use:               "remove <name>",
+	Aliases:           []string{"rm"},
+	Short:             "Delete an alias",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeAliases,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		aliases, err := chatref.LoadAliases(aliasesPath())
+		if err != nil {
+			return err
+		}
+		if _, ok := aliases.Lookup(args[0]); !ok {
+			return api.NewAPIError(fmt.Sprintf("no alias named %q", args[0]), api.CategoryNotFound).
+				WithHint("Use 'beeper alias list' to see aliases.")
+		}
+		delete(aliases, strings.ToLower(args[0]))
+		return aliases.Save(aliasesPath())
+	},
+}
+
+// aliasesPath returns the alias file next to the config file
+func aliasesPath() string {
+	return filepath.Join(filepath.Dir(config.GetConfigPath()), chatref.AliasesFile)
+}
+
+// newChatResolver returns a resolver using the saved aliases and the
+// client's chat list, cached on disk
+func newChatResolver(client *api.Client) (*chatref.Resolver, error) {
+	aliases, err := chatref.LoadAliases(aliasesPath())
+	if err != nil {
+		return nil, err
+	}
+	cache := chatListCache(client)
+	return &chatref.Resolver{
+		Aliases: aliases,
+		Chats: func() ([]api.Chat, error) {
+			return cache.Get(func() ([]api.Chat, error) {
+				return fetchChatList(client)
+			})
+		},
+	}, nil
+}
+
+// resolveChatIDs resolves each chat reference to a chat ID
+func resolveChatIDs(resolver *chatref.Resolver, refs []string) ([]string, error) {
+	if len(refs) == 0 {
+		return refs, nil
+	}
+	ids := make([]string, len(refs))
+	for i, ref := range refs {
+		id, err := resolver.Resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// resolveChats resolves each chat reference and fetches the chat, without
+// its participants
+func resolveChats(client *api.Client, refs []string) ([]api.Chat, error) {
+	resolver, err := newChatResolver(client)
+	if err != nil {
+		return nil, err
+	}
+	chatIDs, err := resolveChatIDs(resolver, refs)
+	if err != nil {
+		return nil, err
+	}
+	var chats []api.Chat
+	for _, id := range chatIDs {
+		chat, err := client.GetChat(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chat %s: %w", id, err)
+		}
+		chat.Participants = nil
+		chats = append(chats, *chat)
+	}
+	return chats, nil
+}
+
+func init() {
+	aliasCmd.AddCommand(aliasSetCmd)
+	aliasCmd.AddCommand(aliasListCmd)
+	aliasCmd.AddCommand(aliasRemoveCmd)
+	rootCmd.AddCommand(aliasCmd)
+}
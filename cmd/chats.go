@@ -8,6 +8,11 @@ import (
 	"github.com/nerveband/beeper-api-cli/internal/output"
 )
 
+var (
+	chatsAll    bool
+	chatsCursor string
+)
+
 var chatsCmd = &cobra.Command{
 	Use:   "chats",
 	Short: "Manage chats",
@@ -17,27 +22,79 @@ var chatsCmd = &cobra.Command{
 var chatsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all chats",
+	Long: `List Beeper chats, most recently active first.
+
+By default only the first page of chats is returned. Use --all to page through
+every chat; with --output ndjson each page is written as soon as it arrives.
+To fetch one page at a time, pass the nextCursor of an --envelope result as
+--cursor.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := getOutputOptions(output.ChatColumns)
+		if err != nil {
+			return err
+		}
+
 		client := getAPIClient()
+		format := getOutputFormat()
+		w := cmd.OutOrStdout()
 
-		chats, err := client.ListChats()
+		if chatsAll && chatsCursor != "" {
+			return fmt.Errorf("--cursor and --all cannot be used together")
+		}
+		if !chatsAll {
+			page, err := client.ListChatsPage(chatsCursor)
+			if err != nil {
+				return fmt.Errorf("failed to list chats: %w", err)
+			}
+			nextCursor := ""
+			if page.HasMore {
+				nextCursor = page.OldestCursor
+			}
+			opts.Envelope = newEnvelope(cmd, client, page.HasMore, nextCursor)
+			return output.WriteChats(w, page.Items, format, opts)
+		}
+
+		stream := streamOutput(format)
+		var chats []api.Chat
+		err = fetchAllChats(client, func(page []api.Chat) error {
+			if stream {
+				return output.WriteChats(w, page, format, opts)
+			}
+			chats = append(chats, page...)
+			return nil
+		})
 		if err != nil {
 			return fmt.Errorf("failed to list chats: %w", err)
 		}
-
-		formatted := output.FormatChats(chats, getOutputFormat())
-		fmt.Print(formatted)
-		return nil
+		if stream {
+			return nil
+		}
+		opts.Envelope = newEnvelope(cmd, client, false, "")
+		return output.WriteChats(w, chats, format, opts)
 	},
 }
 
 var chatsGetCmd = &cobra.Command{
-	Use:   "get <chat-id>",
-	Short: "Get details of a specific chat",
-	Args:  cobra.ExactArgs(1),
+	Use:               "get <chat>",
+	Short:             "Get details of a specific chat",
+	Long:              `Get details of a chat, given by ID, alias or title (see 'beeper alias --help').`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeChatArg,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := getOutputOptions(output.ChatColumns)
+		if err != nil {
+			return err
+		}
+
 		client := getAPIClient()
-		chatID := args[0]
+		resolver, err := newChatResolver(client)
+		if err != nil {
+			return err
+		}
+		chatID, err := resolver.Resolve(args[0])
+		if err != nil {
+			return err
+		}
 
 		chat, err := client.GetChat(chatID)
 		if err != nil {
@@ -46,13 +103,33 @@ var chatsGetCmd = &cobra.Command{
 
 		// Format as single-item array for consistent output
 		chats := []api.Chat{*chat}
-		formatted := output.FormatChats(chats, getOutputFormat())
-		fmt.Print(formatted)
-		return nil
+		opts.Envelope = newEnvelope(cmd, client, false, "")
+		return output.WriteChats(cmd.OutOrStdout(), chats, getOutputFormat(), opts)
 	},
 }
 
+// fetchAllChats pages through every chat, passing each page to fn as it arrives
+func fetchAllChats(client *api.Client, fn func([]api.Chat) error) error {
+	cursor := ""
+	for {
+		page, err := client.ListChatsPage(cursor)
+		if err != nil {
+			return err
+		}
+		if err := fn(page.Items); err != nil {
+			return err
+		}
+		if !page.HasMore || page.OldestCursor == "" || page.OldestCursor == cursor {
+			return nil
+		}
+		cursor = page.OldestCursor
+	}
+}
+
 func init() {
+	chatsListCmd.Flags().BoolVar(&chatsAll, "all", false, "Fetch every chat by paging through the full list")
+	chatsListCmd.Flags().StringVar(&chatsCursor, "cursor", "", "Fetch the page after this cursor (the envelope's nextCursor)")
+
 	chatsCmd.AddCommand(chatsListCmd)
 	chatsCmd.AddCommand(chatsGetCmd)
 	rootCmd.AddCommand(chatsCmd)
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/chatref"
+	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/diskcache"
+)
+
+const (
+	// cacheTTL is how long cached chat and account lists are reused
+	cacheTTL = time.Minute
+	// completionTimeout bounds API calls made while completing, so a slow
+	// Desktop doesn't stall the shell; the last cached list answers instead
+	completionTimeout = 2 * time.Second
+)
+
+var completionNoDescriptions bool
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Generate a shell completion script",
+	Long: `Print a completion script for your shell. Besides commands and flags it
+completes chats for --chat-id and 'chats get' (IDs and aliases, with titles
+as descriptions), networks of connected accounts for --network, aliases,
+and the values of --output and other fixed-choice flags.
+
+Chat and account lists are cached for a minute in ~/.beeper-api-cli/cache/.
+If Beeper Desktop doesn't answer within 2s, the last cached list is used.
+
+Bash (needs the bash-completion package):
+  beeper completion bash > ~/.local/share/bash-completion/completions/beeper
+
+Zsh (the directory must be in $fpath, before compinit runs):
+  beeper completion zsh > "${fpath[1]}/_beeper"
+
+Fish:
+  beeper completion fish > ~/.config/fish/completions/beeper.fish
+
+PowerShell (add to your profile to keep it):
+  beeper completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := cmd.OutOrStdout()
+		descriptions := !completionNoDescriptions
+		switch args[0] {
+		case "bash":
+			return rootCmd.GenBashCompletionV2(w, descriptions)
+		case "zsh":
+			if descriptions {
+				return rootCmd.GenZshCompletion(w)
+			}
+			return rootCmd.GenZshCompletionNoDesc(w)
+		case "fish":
+			return rootCmd.GenFishCompletion(w, descriptions)
+		case "powershell":
+			if descriptions {
+				return rootCmd.GenPowerShellCompletionWithDesc(w)
+			}
+			return rootCmd.GenPowerShellCompletion(w)
+		}
+		return fmt.Errorf("unsupported shell %q", args[0])
+	},
+}
+
+// isCompletionCommand reports whether cmd produces or serves completions,
+// which must print nothing but completions
+func isCompletionCommand(cmd *cobra.Command) bool {
+	switch cmd.Name() {
+	case "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	return cmd.HasParent() && cmd.Parent().Name() == "completion"
+}
+
+// cacheDir holds short-lived API results next to the config file
+func cacheDir() string {
+	return filepath.Join(filepath.Dir(config.GetConfigPath()), "cache")
+}
+
+// chatListCache caches every chat, without participants, per API URL
+func chatListCache(client *api.Client) *diskcache.Cache[[]api.Chat] {
+	return &diskcache.Cache[[]api.Chat]{
+		Path: filepath.Join(cacheDir(), "chats.json"),
+		Key:  client.GetBaseURL(),
+		TTL:  cacheTTL,
+	}
+}
+
+// accountsCache caches the connected accounts per API URL
+func accountsCache(client *api.Client) *diskcache.Cache[[]api.Account] {
+	return &diskcache.Cache[[]api.Account]{
+		Path: filepath.Join(cacheDir(), "accounts.json"),
+		Key:  client.GetBaseURL(),
+		TTL:  cacheTTL,
+	}
+}
+
+// fetchChatList pages through every chat, dropping participants, which
+// resolution and completion don't need
+func fetchChatList(client *api.Client) ([]api.Chat, error) {
+	var chats []api.Chat
+	err := fetchAllChats(client, func(page []api.Chat) error {
+		for _, chat := range page {
+			chat.Participants = nil
+			chats = append(chats, chat)
+		}
+		return nil
+	})
+	return chats, err
+}
+
+// completionClient returns an API client with a short timeout. Config is
+// loaded here too, as completion may run before the root command's hooks.
+func completionClient() *api.Client {
+	if cfg == nil {
+		if err := loadConfig(false); err != nil {
+			cfg = config.DefaultConfig()
+		}
+	}
+	client := getAPIClient()
+	client.SetTimeout(completionTimeout)
+	return client
+}
+
+// completionValue returns the cached or freshly fetched value, falling back
+// to a stale cached one when the fetch fails
+func completionValue[T any](cache *diskcache.Cache[T], fetch func() (T, error)) T {
+	value, err := cache.Get(fetch)
+	if err != nil {
+		value, _ = cache.Stale()
+	}
+	return value
+}
+
+// completeChats suggests aliases and chat IDs, described by chat title.
+// Chats whose title contains the typed text are offered by ID as well.
+func completeChats(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	client := completionClient()
+	chats := completionValue(chatListCache(client), func() ([]api.Chat, error) {
+		return fetchChatList(client)
+	})
+	aliases, _ := chatref.LoadAliases(aliasesPath())
+
+	byID := make(map[string]api.Chat, len(chats))
+	for _, chat := range chats {
+		byID[chat.ID] = chat
+	}
+
+	prefix := strings.ToLower(toComplete)
+	var completions []string
+	for _, name := range aliases.Names() {
+		if strings.HasPrefix(name, prefix) {
+			completions = append(completions, completion(name, "alias: "+describeChat(byID[aliases[name]], aliases[name])))
+		}
+	}
+	for _, chat := range chats {
+		titleMatch := prefix != "" && strings.Contains(strings.ToLower(chat.Title), prefix)
+		if strings.HasPrefix(chat.ID, toComplete) || titleMatch {
+			completions = append(completions, completion(chat.ID, describeChat(chat, chat.ID)))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeChatArg completes a single chat argument
+func completeChatArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completeChats(cmd, args, toComplete)
+}
+
+// completeAliases suggests alias names, described by their chat
+func completeAliases(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	aliases, _ := chatref.LoadAliases(aliasesPath())
+	var completions []string
+	for _, name := range aliases.Names() {
+		completions = append(completions, completion(name, aliases[name]))
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeNetworks suggests the networks of connected accounts, described
+// by the account's user
+func completeNetworks(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	client := completionClient()
+	accounts := completionValue(accountsCache(client), client.ListAccounts)
+
+	seen := make(map[string]bool)
+	var completions []string
+	for _, account := range accounts {
+		network := strings.ToLower(account.Network)
+		if network == "" || seen[network] {
+			continue
+		}
+		seen[network] = true
+		user := account.User.FullName
+		if user == "" {
+			user = account.User.Username
+		}
+		completions = append(completions, completion(network, user))
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func describeChat(chat api.Chat, fallback string) string {
+	desc := chat.Title
+	if desc == "" {
+		desc = fallback
+	}
+	if chat.Network != "" {
+		desc += " (" + chat.Network + ")"
+	}
+	return desc
+}
+
+// completion joins a value and its description as cobra expects, keeping
+// tabs and newlines out of the description
+func completion(value, description string) string {
+	description = strings.Join(strings.Fields(description), " ")
+	if description == "" {
+		return value
+	}
+	return value + "\t" + description
+}
+
+// fixedCompletions completes a flag from a fixed list of values
+func fixedCompletions(values ...string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp)
+}
+
+// registerFlagCompletion registers a flag completion, panicking on a
+// misspelt flag so the mistake surfaces in tests
+func registerFlagCompletion(cmd *cobra.Command, flag string, fn func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)) {
+	if err := cmd.RegisterFlagCompletionFunc(flag, fn); err != nil {
+		panic(fmt.Sprintf("failed to register completion for --%s: %v", flag, err))
+	}
+}
+
+func init() {
+	completionCmd.Flags().BoolVar(&completionNoDescriptions, "no-descriptions", false, "Leave descriptions out of completions")
+	rootCmd.AddCommand(completionCmd)
+}
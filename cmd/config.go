@@ -2,9 +2,12 @@ package cmd
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/output"
 )
 
 var configCmd = &cobra.Command{
@@ -17,11 +20,15 @@ Configuration File:
 
 Configuration Fields:
   api_url        The Beeper Desktop API URL (default: http://localhost:39867)
-  output_format  Default output format: json, text, or markdown (default: json)
+  output_format  Default output format (default: json); run
+                 "beeper config set-format --help" for the formats
+  envelope       Wrap json, ndjson and yaml results with pagination and
+                 source metadata, like --envelope (default: false)
 
 Environment Variables (override config file):
   BEEPER_API_URL        Override api_url
   BEEPER_OUTPUT_FORMAT  Override output_format
+  BEEPER_ENVELOPE       true or false to turn envelope on or off
   BEEPER_TOKEN          API authentication token (required for most operations)
 
 Example config.yaml:
@@ -40,6 +47,7 @@ var configShowCmd = &cobra.Command{
 		fmt.Printf("Config File:   %s\n", config.GetConfigPath())
 		fmt.Printf("API URL:       %s\n", cfg.APIURL)
 		fmt.Printf("Output Format: %s\n", cfg.OutputFormat)
+		fmt.Printf("Envelope:      %t\n", cfg.Envelope)
 		return nil
 	},
 }
@@ -60,12 +68,12 @@ var configSetURLCmd = &cobra.Command{
 
 var configSetFormatCmd = &cobra.Command{
 	Use:   "set-format <format>",
-	Short: "Set the default output format (json, text, markdown)",
+	Short: "Set the default output format",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		format := args[0]
-		if format != "json" && format != "text" && format != "markdown" {
-			return fmt.Errorf("invalid format: %s (must be json, text, or markdown)", format)
+		if err := output.ValidateFormat(format); err != nil {
+			return err
 		}
 
 		cfg.OutputFormat = format
@@ -77,9 +85,41 @@ var configSetFormatCmd = &cobra.Command{
 	},
 }
 
+var configSetEnvelopeCmd = &cobra.Command{
+	Use:   "set-envelope <true|false>",
+	Short: "Set whether results are wrapped in an envelope by default",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		envelope, err := strconv.ParseBool(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid value: %s (must be true or false)", args[0])
+		}
+
+		cfg.Envelope = envelope
+		if err := config.Save(cfg); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("Envelope set to: %t\n", cfg.Envelope)
+		return nil
+	},
+}
+
+// formatHelp lists the registered output formats with their descriptions
+func formatHelp() string {
+	var sb strings.Builder
+	sb.WriteString("Available formats:\n")
+	for _, r := range output.Formats() {
+		sb.WriteString(fmt.Sprintf("  %-10s %s\n", r.Name, r.Description))
+	}
+	return sb.String()
+}
+
 func init() {
+	configSetFormatCmd.Long = "Set the default output format used when --output is not given.\n\n" + formatHelp()
+
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configSetURLCmd)
 	configCmd.AddCommand(configSetFormatCmd)
+	configCmd.AddCommand(configSetEnvelopeCmd)
 	rootCmd.AddCommand(configCmd)
 }
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/export"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+)
+
+var (
+	exportDir             string
+	exportChatIDs         []string
+	exportNetwork         string
+	exportSince           string
+	exportPageSize        int
+	exportSkipAttachments bool
+	exportFormat          string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export --dir <path>",
+	Short: "Archive chats, messages and attachments to a directory",
+	Long: `Export chats to a directory for backup or compliance archiving.
+
+Each chat gets its own directory under <dir>/chats/, named after its ID and
+a short hash of it, containing:
+  chat.json          The chat's details
+  messages.ndjson    One message per line, in the order they were fetched
+                     (each run appends messages newest first)
+  attachments/       Downloaded attachments
+
+With --format mbox, messages.mbox replaces messages.ndjson. Each message is
+an RFC 5322 email that mail clients and e-discovery tools can import: From
+is the sender, Date the message time, the Subject is the chat title (so each
+chat forms one thread), replies carry In-Reply-To, and attachments are
+embedded as MIME parts. An export directory keeps the format it was created
+with.
+
+<dir>/manifest.json records the last exported message of every chat, so
+running the same export again only fetches new messages. Progress is
+checkpointed after every page; an interrupted export resumes where it stopped.
+Attachments that fail to download are listed in the manifest and retried at
+the start of every run until they succeed.`,
+	Example: `  beeper export --dir ./backup
+  beeper export --dir ./backup --network whatsapp --since 90d
+  beeper export --dir ./legal-hold --format mbox
+  beeper export --dir ./backup --chat-id '!abc:beeper.local' --chat-id '!def:beeper.local'`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if exportDir == "" {
+			return fmt.Errorf("--dir is required")
+		}
+		since, err := export.ParseSince(exportSince)
+		if err != nil {
+			return err
+		}
+
+		client := getAPIClient()
+		resolver, err := newChatResolver(client)
+		if err != nil {
+			return err
+		}
+		chatIDs, err := resolveChatIDs(resolver, exportChatIDs)
+		if err != nil {
+			return err
+		}
+
+		opts := export.Options{
+			Dir:             exportDir,
+			ChatIDs:         chatIDs,
+			Network:         exportNetwork,
+			Since:           since,
+			PageSize:        exportPageSize,
+			SkipAttachments: exportSkipAttachments,
+			Format:          exportFormat,
+		}
+		if !quietMode {
+			opts.Progress = printExportProgress
+		}
+
+		summary, err := export.Run(client, opts)
+		if err != nil {
+			return fmt.Errorf("failed to export: %w", err)
+		}
+
+		if format, ok := getReportFormat(cmd); ok {
+			if err := output.WriteValue(cmd.OutOrStdout(), summary, format); err != nil {
+				return err
+			}
+		} else {
+			printExportSummary(summary)
+		}
+
+		if failed := summary.Failed(); failed > 0 {
+			return fmt.Errorf("failed to export %d of %d chats", failed, len(summary.Chats))
+		}
+		return nil
+	},
+}
+
+// printExportProgress reports each exported chat on stderr
+func printExportProgress(r export.ChatResult) {
+	title := r.Title
+	if title == "" {
+		title = r.ChatID
+	}
+	if r.Error != "" {
+		fmt.Fprintf(os.Stderr, "✗ %s: %s\n", title, r.Error)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "✓ %s: %d new messages, %d attachments", title, r.NewMessages, r.Attachments)
+	if r.AttachmentErrors > 0 {
+		fmt.Fprintf(os.Stderr, " (%d failed, retried next run)", r.AttachmentErrors)
+	}
+	fmt.Fprintln(os.Stderr)
+}
+
+func printExportSummary(s *export.Summary) {
+	messages, attachments := 0, 0
+	for _, c := range s.Chats {
+		messages += c.NewMessages
+		attachments += c.Attachments
+	}
+	fmt.Printf("Exported %d chats to %s: %d new messages, %d attachments\n", len(s.Chats), s.Dir, messages, attachments)
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportDir, "dir", "", "Directory to export to (created if needed)")
+	exportCmd.Flags().StringArrayVar(&exportChatIDs, "chat-id", nil, "Export only this chat, by ID, alias or title (repeatable)")
+	exportCmd.Flags().StringVar(&exportNetwork, "network", "", "Export only chats on this network (e.g. whatsapp)")
+	exportCmd.Flags().StringVar(&exportSince, "since", "", "Skip messages before this time: a duration (30d, 12h), a date (2024-01-31) or an RFC 3339 timestamp")
+	exportCmd.Flags().IntVar(&exportPageSize, "page-size", export.DefaultPageSize, "Messages requested per page")
+	exportCmd.Flags().BoolVar(&exportSkipAttachments, "skip-attachments", false, "Don't download attachments")
+	exportCmd.Flags().StringVar(&exportFormat, "format", export.FormatNDJSON, "Message file format: ndjson or mbox (RFC 5322 emails)")
+	registerFlagCompletion(exportCmd, "chat-id", completeChats)
+	registerFlagCompletion(exportCmd, "network", completeNetworks)
+	registerFlagCompletion(exportCmd, "format", fixedCompletions(export.FormatNDJSON, export.FormatMbox))
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+)
+
+var (
+	inboxLimit       int
+	inboxConcurrency int
+	inboxMarkRead    bool
+)
+
+var inboxCmd = &cobra.Command{
+	Use:   "inbox",
+	Short: "Show a digest of unread messages",
+	Long: `List every chat with unread messages and those messages, grouped by
+network. Networks and the chats in them are ordered by their newest unread
+message; each chat's messages are shown oldest first, after a summary of the
+whole inbox.
+
+Up to --limit unread messages are shown per chat, fetching --concurrency chats
+at a time. A chat whose messages can't be fetched is still listed, with the
+error, and doesn't fail the command.
+
+With --mark-read the chats shown are marked read once the digest has been
+written. A chat is left unread if it had more unread messages than --limit,
+or received new ones while the digest was written, so nothing unseen is
+marked read.
+
+Table output has one row per message (see --columns); csv and tsv leave out
+the summary. With -o ndjson the summary is the first line, then one line per
+chat.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if inboxLimit < 1 {
+			return fmt.Errorf("--limit must be positive")
+		}
+		if inboxConcurrency < 1 {
+			return fmt.Errorf("--concurrency must be positive")
+		}
+
+		if err := rejectQueryFlags(cmd); err != nil {
+			return err
+		}
+		opts, err := getOutputOptions(output.InboxColumns)
+		if err != nil {
+			return err
+		}
+
+		client := getAPIClient()
+		var chats []output.InboxChat
+		err = fetchAllChats(client, func(page []api.Chat) error {
+			for _, chat := range page {
+				if chat.UnreadCount > 0 {
+					chat.Participants = nil
+					chats = append(chats, output.InboxChat{Chat: chat})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list chats: %w", err)
+		}
+
+		forEachConcurrently(len(chats), inboxConcurrency, func(i int) {
+			chat := &chats[i]
+			messages, err := client.ListMessages(chat.Chat.ID, min(chat.Chat.UnreadCount, inboxLimit))
+			if err != nil {
+				chat.Error = err.Error()
+				return
+			}
+			// The API returns newest first; a digest reads oldest first
+			slices.Reverse(messages)
+			chat.Messages = messages
+		})
+
+		inbox := output.NewInbox(chats)
+		if err := output.WriteInbox(cmd.OutOrStdout(), inbox, getOutputFormat(), opts); err != nil {
+			return err
+		}
+
+		if inboxMarkRead {
+			return markInboxRead(client, chats)
+		}
+		return nil
+	},
+}
+
+// errUnreadNotShown marks a chat left unread because it holds messages the
+// digest didn't show
+var errUnreadNotShown = errors.New("unread messages not shown")
+
+// markInboxRead marks the chats whose messages were shown as read, reporting
+// progress on stderr so it stays out of the digest. The API marks a whole
+// chat read, so a chat is skipped when it had more unread messages than were
+// shown or its unread count has changed since the digest was fetched.
+func markInboxRead(client *api.Client, chats []output.InboxChat) error {
+	var shown []output.InboxChat
+	for _, chat := range chats {
+		if chat.Error == "" {
+			shown = append(shown, chat)
+		}
+	}
+
+	errs := make([]error, len(shown))
+	forEachConcurrently(len(shown), inboxConcurrency, func(i int) {
+		errs[i] = markChatReadIfShown(client, shown[i])
+	})
+
+	failed, skipped := 0, 0
+	var firstErr error
+	for _, err := range errs {
+		switch {
+		case errors.Is(err, errUnreadNotShown):
+			skipped++
+		case err != nil:
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Marked %d of %d chats read", len(shown)-failed-skipped, len(shown))
+	if skipped > 0 {
+		fmt.Fprintf(os.Stderr, " (%d left unread: messages not shown)", skipped)
+	}
+	fmt.Fprintln(os.Stderr)
+	if firstErr != nil {
+		return fmt.Errorf("failed to mark %d chats read: %w", failed, firstErr)
+	}
+	return nil
+}
+
+// markChatReadIfShown marks a chat read if every unread message in it was
+// shown, returning errUnreadNotShown otherwise
+func markChatReadIfShown(client *api.Client, chat output.InboxChat) error {
+	if chat.Chat.UnreadCount > len(chat.Messages) {
+		return errUnreadNotShown
+	}
+	current, err := client.GetChat(chat.Chat.ID)
+	if err != nil {
+		return err
+	}
+	if current.UnreadCount != chat.Chat.UnreadCount {
+		return errUnreadNotShown
+	}
+	return client.MarkChatRead(chat.Chat.ID)
+}
+
+// forEachConcurrently calls fn for each index below n, running at most limit
+// calls at a time, and returns once all have finished
+func forEachConcurrently(n, limit int, fn func(i int)) {
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+func init() {
+	inboxCmd.Flags().IntVar(&inboxLimit, "limit", 20, "Maximum unread messages shown per chat")
+	inboxCmd.Flags().IntVar(&inboxConcurrency, "concurrency", 4, "Number of chats fetched at the same time")
+	inboxCmd.Flags().BoolVar(&inboxMarkRead, "mark-read", false, "Mark the chats shown as read after writing the digest, unless they hold unread messages not shown")
+	rootCmd.AddCommand(inboxCmd)
+}
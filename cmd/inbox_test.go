@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestForEachConcurrently tests that every index is visited without
+// exceeding the limit
+func TestForEachConcurrently(t *testing.T) {
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	var seen []int
+
+	forEachConcurrently(10, 3, func(i int) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+
+		mu.Lock()
+		seen = append(seen, i)
+		mu.Unlock()
+	})
+
+	sort.Ints(seen)
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, seen)
+	assert.LessOrEqual(t, peak.Load(), int32(3))
+}
+
+// TestMarkInboxRead tests that only chats whose unread messages were all
+// shown, and haven't changed since, are marked
+func TestMarkInboxRead(t *testing.T) {
+	unread := map[string]int{"a": 1, "c": 2, "d": 5, "e": 2}
+	var mu sync.Mutex
+	var marked []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/chats/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":%q,"unreadCount":%d}`, r.PathValue("id"), unread[r.PathValue("id")])
+	})
+	mux.HandleFunc("POST /v1/chats/{id}/read", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		marked = append(marked, r.URL.Path)
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	msgs := func(n int) []api.Message { return make([]api.Message, n) }
+	chats := []output.InboxChat{
+		{Chat: api.Chat{ID: "a", UnreadCount: 1}, Messages: msgs(1)},
+		{Chat: api.Chat{ID: "b", UnreadCount: 1}, Error: "timed out"},
+		{Chat: api.Chat{ID: "c", UnreadCount: 2}, Messages: msgs(2)},
+		// More unread than --limit showed
+		{Chat: api.Chat{ID: "d", UnreadCount: 5}, Messages: msgs(3)},
+		// A message arrived after the digest was fetched
+		{Chat: api.Chat{ID: "e", UnreadCount: 1}, Messages: msgs(1)},
+	}
+	require.NoError(t, markInboxRead(api.NewClient(server.URL), chats))
+
+	sort.Strings(marked)
+	assert.Equal(t, []string{"/v1/chats/a/read", "/v1/chats/c/read"}, marked)
+}
@@ -8,19 +8,45 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/nerveband/beeper-api-cli/internal/api"
 	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/output"
 )
 
 var testPermissions bool
 
+// infoReport is the structured form of 'beeper info'
+type infoReport struct {
+	Version        string             `json:"version"`
+	GoVersion      string             `json:"go_version"`
+	Platform       string             `json:"platform"`
+	ConfigFile     string             `json:"config_file"`
+	ConfigExists   bool               `json:"config_exists"`
+	APIURL         string             `json:"api_url"`
+	OutputFormat   string             `json:"output_format"`
+	TokenSet       bool               `json:"token_set"`
+	Token          string             `json:"token,omitempty"` // masked
+	APIStatus      string             `json:"api_status"`
+	APIError       string             `json:"api_error,omitempty"`
+	DesktopVersion string             `json:"desktop_version,omitempty"`
+	Permissions    []permissionResult `json:"permissions,omitempty"`
+}
+
+// permissionResult is the outcome of one --test-permissions check
+type permissionResult struct {
+	Check  string `json:"check"`
+	OK     bool   `json:"ok"`
+	Reason string `json:"reason,omitempty"`
+}
+
 var infoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Display CLI and environment information",
 	Long: `Display comprehensive information about the CLI, configuration,
 and API connectivity status. Useful for troubleshooting and verification.
 
-The --test-permissions flag tests actual API access to verify your token works.`,
+The --test-permissions flag tests actual API access to verify your token works.
+Pass --output json, ndjson or yaml for a machine-readable report.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return runInfo()
+		return runInfo(cmd)
 	},
 }
 
@@ -29,43 +55,91 @@ func init() {
 	rootCmd.AddCommand(infoCmd)
 }
 
-func runInfo() error {
+func runInfo(cmd *cobra.Command) error {
+	report := collectInfo()
+
+	if format, ok := getReportFormat(cmd); ok {
+		return output.WriteValue(cmd.OutOrStdout(), report, format)
+	}
+
+	printInfoText(report)
+	return nil
+}
+
+// collectInfo gathers version, configuration and connectivity details
+func collectInfo() infoReport {
+	configPath := config.GetConfigPath()
+	_, statErr := os.Stat(configPath)
+
+	report := infoReport{
+		Version:      Version,
+		GoVersion:    runtime.Version(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+		ConfigFile:   configPath,
+		ConfigExists: statErr == nil,
+		APIURL:       cfg.APIURL,
+		OutputFormat: cfg.OutputFormat,
+	}
+
+	if token := os.Getenv("BEEPER_TOKEN"); token != "" {
+		report.TokenSet = true
+		report.Token = maskToken(token)
+	}
+
+	client := getAPIClient()
+	if err := client.Ping(); err != nil {
+		report.APIStatus = "unreachable"
+		report.APIError = err.Error()
+	} else {
+		report.APIStatus = "connected"
+		report.DesktopVersion = client.GetDesktopVersion()
+	}
+
+	if testPermissions {
+		report.Permissions = testAPIPermissions(client)
+	}
+
+	return report
+}
+
+// maskToken hides all but the first and last four characters of a token
+func maskToken(token string) string {
+	if len(token) < 12 {
+		return "****"
+	}
+	return token[:4] + "..." + token[len(token)-4:]
+}
+
+func printInfoText(report infoReport) {
 	fmt.Println("Beeper API CLI Information")
 	fmt.Println("==========================")
 	fmt.Println()
 
 	// CLI Version
-	fmt.Printf("Version:        %s\n", Version)
-	fmt.Printf("Go Version:     %s\n", runtime.Version())
-	fmt.Printf("Platform:       %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("Version:        %s\n", report.Version)
+	fmt.Printf("Go Version:     %s\n", report.GoVersion)
+	fmt.Printf("Platform:       %s\n", report.Platform)
 	fmt.Println()
 
 	// Configuration
 	fmt.Println("Configuration")
 	fmt.Println("-------------")
-	configPath := config.GetConfigPath()
-	fmt.Printf("Config File:    %s\n", configPath)
+	fmt.Printf("Config File:    %s\n", report.ConfigFile)
 
 	// Check if config file exists
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+	if !report.ConfigExists {
 		fmt.Printf("                (not created yet, using defaults)\n")
 	}
 
-	fmt.Printf("API URL:        %s\n", cfg.APIURL)
-	fmt.Printf("Output Format:  %s\n", cfg.OutputFormat)
+	fmt.Printf("API URL:        %s\n", report.APIURL)
+	fmt.Printf("Output Format:  %s\n", report.OutputFormat)
 	fmt.Println()
 
 	// Authentication
 	fmt.Println("Authentication")
 	fmt.Println("--------------")
-	token := os.Getenv("BEEPER_TOKEN")
-	if token != "" {
-		// Mask token for display
-		maskedToken := token[:4] + "..." + token[len(token)-4:]
-		if len(token) < 12 {
-			maskedToken = "****"
-		}
-		fmt.Printf("BEEPER_TOKEN:   Set (%s)\n", maskedToken)
+	if report.TokenSet {
+		fmt.Printf("BEEPER_TOKEN:   Set (%s)\n", report.Token)
 	} else {
 		fmt.Printf("BEEPER_TOKEN:   Not set\n")
 		fmt.Printf("                (Set this environment variable to authenticate API requests)\n")
@@ -75,11 +149,9 @@ func runInfo() error {
 	// API Connectivity
 	fmt.Println("API Connectivity")
 	fmt.Println("----------------")
-	client := getAPIClient()
-
-	if err := client.Ping(); err != nil {
+	if report.APIStatus != "connected" {
 		fmt.Printf("Status:         Unreachable\n")
-		fmt.Printf("Error:          %v\n", err)
+		fmt.Printf("Error:          %s\n", report.APIError)
 		if !quietMode {
 			fmt.Println()
 			fmt.Println("Hint: Make sure Beeper Desktop is running and the API is enabled.")
@@ -87,8 +159,8 @@ func runInfo() error {
 		}
 	} else {
 		fmt.Printf("Status:         Connected\n")
-		if version := client.GetDesktopVersion(); version != "" {
-			fmt.Printf("Desktop Ver:    %s\n", version)
+		if report.DesktopVersion != "" {
+			fmt.Printf("Desktop Ver:    %s\n", report.DesktopVersion)
 		}
 	}
 
@@ -97,57 +169,52 @@ func runInfo() error {
 		fmt.Println()
 		fmt.Println("Permission Test")
 		fmt.Println("---------------")
-		testAPIPermissions(client)
-	}
+		for _, result := range report.Permissions {
+			fmt.Printf("%-20s", result.Check+":")
+			if result.OK {
+				fmt.Println("OK")
+			} else {
+				fmt.Printf("FAILED - %s\n", result.Reason)
+			}
+		}
 
-	return nil
+		fmt.Println()
+		fmt.Println("Note: Write permissions cannot be tested without making actual changes.")
+	}
 }
 
-func testAPIPermissions(client *api.Client) {
+func testAPIPermissions(client *api.Client) []permissionResult {
 	// Test read permission by listing chats
-	fmt.Print("Read (list chats):  ")
 	_, err := client.ListChats()
-	if err != nil {
-		if apiErr, ok := err.(*api.APIError); ok {
-			switch apiErr.Category {
-			case api.CategoryAuth:
-				fmt.Println("FAILED - Authentication required")
-			case api.CategoryPermission:
-				fmt.Println("FAILED - Insufficient permissions")
-			case api.CategoryNetwork:
-				fmt.Println("FAILED - Network error")
-			default:
-				fmt.Printf("FAILED - %s\n", apiErr.Message)
-			}
-		} else {
-			fmt.Printf("FAILED - %v\n", err)
-		}
-	} else {
-		fmt.Println("OK")
-	}
+	results := []permissionResult{permissionCheck("Read (list chats)", err)}
 
 	// Test search capability
-	fmt.Print("Search messages:    ")
 	_, err = client.SearchMessages("test", 1)
-	if err != nil {
-		if apiErr, ok := err.(*api.APIError); ok {
-			switch apiErr.Category {
-			case api.CategoryAuth:
-				fmt.Println("FAILED - Authentication required")
-			case api.CategoryPermission:
-				fmt.Println("FAILED - Insufficient permissions")
-			case api.CategoryNetwork:
-				fmt.Println("FAILED - Network error")
-			default:
-				fmt.Printf("FAILED - %s\n", apiErr.Message)
-			}
-		} else {
-			fmt.Printf("FAILED - %v\n", err)
+	results = append(results, permissionCheck("Search messages", err))
+
+	return results
+}
+
+// permissionCheck converts the error from a test request into a result
+func permissionCheck(check string, err error) permissionResult {
+	result := permissionResult{Check: check, OK: err == nil}
+	if err == nil {
+		return result
+	}
+
+	if apiErr, ok := err.(*api.APIError); ok {
+		switch apiErr.Category {
+		case api.CategoryAuth:
+			result.Reason = "Authentication required"
+		case api.CategoryPermission:
+			result.Reason = "Insufficient permissions"
+		case api.CategoryNetwork:
+			result.Reason = "Network error"
+		default:
+			result.Reason = apiErr.Message
 		}
 	} else {
-		fmt.Println("OK")
+		result.Reason = err.Error()
 	}
-
-	fmt.Println()
-	fmt.Println("Note: Write permissions cannot be tested without making actual changes.")
+	return result
 }
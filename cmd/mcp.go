@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/mcp"
+)
+
+var mcpAllowSend bool
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve Beeper tools to AI agents over the Model Context Protocol",
+	Long: `Run a Model Context Protocol (MCP) server on stdin/stdout, so agents can
+call Beeper directly instead of shelling out and parsing output.
+
+Tools:
+  list_chats       List chats, one page at a time
+  get_chat         Get a chat's details
+  list_messages    List a chat's messages, newest first
+  search_messages  Search messages across all chats
+  send_message     Send a message (only with --allow-send)
+
+Input and output schemas follow the JSON the CLI prints with -o json. API
+errors are returned as tool errors carrying their category, hint and whether
+a retry may help. The API URL and BEEPER_TOKEN are read as for any command.
+
+Register it with an MCP client, e.g. in its JSON configuration:
+  {"mcpServers": {"beeper": {"command": "beeper", "args": ["mcp"]}}}`,
+	Example: `  beeper mcp
+  beeper mcp --allow-send`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		server := mcp.NewServer(getAPIClient(), mcp.Options{
+			AllowSend: mcpAllowSend,
+			Version:   Version,
+		})
+		return server.Serve(os.Stdin, cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	mcpCmd.Flags().BoolVar(&mcpAllowSend, "allow-send", false, "Expose the send_message tool, letting agents send messages as you")
+	rootCmd.AddCommand(mcpCmd)
+}
@@ -4,11 +4,15 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/api"
 	"github.com/nerveband/beeper-api-cli/internal/output"
 )
 
 var (
-	messagesLimit int
+	messagesLimit          int
+	messagesAll            bool
+	messagesCursor         string
+	messagesAttachmentsDir string
 )
 
 var messagesCmd = &cobra.Command{
@@ -18,30 +22,113 @@ var messagesCmd = &cobra.Command{
 }
 
 var messagesListCmd = &cobra.Command{
-	Use:   "list --chat-id <chat-id>",
+	Use:   "list --chat-id <chat>",
 	Short: "List messages from a chat",
+	Long: `List messages from a chat, newest first.
+
+Use --all to page back through the complete history, --limit messages per
+request. With --output ndjson each page is written as soon as it arrives, so
+large histories stream with constant memory. To fetch one page at a time,
+pass the nextCursor of an --envelope result as --cursor.
+
+With --output html a standalone transcript is written; attachments are linked
+from the directory given by --attachments-dir, relative to the HTML file.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		chatID, _ := cmd.Flags().GetString("chat-id")
 		if chatID == "" {
 			return fmt.Errorf("--chat-id is required")
 		}
 
+		if messagesAll && messagesCursor != "" {
+			return fmt.Errorf("--cursor and --all cannot be used together")
+		}
+
+		opts, err := getOutputOptions(output.MessageColumns)
+		if err != nil {
+			return err
+		}
+
 		client := getAPIClient()
+		format := getOutputFormat()
+		w := cmd.OutOrStdout()
 
-		messages, err := client.ListMessages(chatID, messagesLimit)
+		resolver, err := newChatResolver(client)
 		if err != nil {
-			return fmt.Errorf("failed to list messages: %w", err)
+			return err
+		}
+		chatID, err = resolver.Resolve(chatID)
+		if err != nil {
+			return err
+		}
+
+		if format == output.FormatHTML || format == output.FormatLLM {
+			opts.AttachmentsDir = messagesAttachmentsDir
+			opts.Title = chatID
+			if chat, err := client.GetChat(chatID); err == nil && chat.Title != "" {
+				opts.Title = chat.Title
+			}
 		}
 
-		formatted := output.FormatMessages(messages, getOutputFormat())
-		fmt.Print(formatted)
-		return nil
+		if !messagesAll {
+			page, err := client.ListMessagesPage(chatID, messagesCursor, messagesLimit)
+			if err != nil {
+				return fmt.Errorf("failed to list messages: %w", err)
+			}
+			nextCursor := ""
+			if page.HasMore {
+				nextCursor = page.NextCursor()
+			}
+			opts.Envelope = newEnvelope(cmd, client, page.HasMore, nextCursor)
+			return output.WriteMessages(w, page.Items, format, opts)
+		}
+
+		stream := streamOutput(format)
+		var messages []api.Message
+		err = fetchAllMessages(client, chatID, messagesLimit, func(page []api.Message) error {
+			if stream {
+				return output.WriteMessages(w, page, format, opts)
+			}
+			messages = append(messages, page...)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		if stream {
+			return nil
+		}
+		opts.Envelope = newEnvelope(cmd, client, false, "")
+		return output.WriteMessages(w, messages, format, opts)
 	},
 }
 
+// fetchAllMessages pages back through a chat's history, passing each page to
+// fn as it arrives
+func fetchAllMessages(client *api.Client, chatID string, pageSize int, fn func([]api.Message) error) error {
+	cursor := ""
+	for {
+		page, err := client.ListMessagesPage(chatID, cursor, pageSize)
+		if err != nil {
+			return err
+		}
+		if err := fn(page.Items); err != nil {
+			return err
+		}
+		next := page.NextCursor()
+		if !page.HasMore || next == "" || next == cursor {
+			return nil
+		}
+		cursor = next
+	}
+}
+
 func init() {
-	messagesListCmd.Flags().String("chat-id", "", "Chat ID to retrieve messages from")
-	messagesListCmd.Flags().IntVar(&messagesLimit, "limit", 50, "Maximum number of messages to retrieve")
+	messagesListCmd.Flags().String("chat-id", "", "Chat to retrieve messages from: an ID, alias or title")
+	messagesListCmd.Flags().IntVar(&messagesLimit, "limit", 50, "Maximum number of messages to retrieve (page size with --all)")
+	messagesListCmd.Flags().BoolVar(&messagesAll, "all", false, "Fetch the complete history by paging back through all messages")
+	messagesListCmd.Flags().StringVar(&messagesCursor, "cursor", "", "Fetch the page of older messages after this cursor (the envelope's nextCursor)")
+	messagesListCmd.Flags().StringVar(&messagesAttachmentsDir, "attachments-dir", output.DefaultAttachmentsDir, "Directory HTML transcripts link attachments from, relative to the HTML file")
+	registerFlagCompletion(messagesListCmd, "chat-id", completeChats)
 
 	messagesCmd.AddCommand(messagesListCmd)
 	rootCmd.AddCommand(messagesCmd)
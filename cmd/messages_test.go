@@ -2,10 +2,15 @@ package cmd
 
 import (
 	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 
+	"github.com/nerveband/beeper-api-cli/internal/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestMessagesListCommand tests the messages list command
@@ -77,3 +82,34 @@ func TestMessagesListCommand_Limit(t *testing.T) {
 	result := output.String()
 	assert.NotEmpty(t, result)
 }
+
+// TestFetchAllMessages tests paging through history until hasMore is false
+func TestFetchAllMessages(t *testing.T) {
+	pages := map[string]string{
+		"":  `{"items":[{"id":"m4","sortKey":"4"},{"id":"m3","sortKey":"3"}],"hasMore":true}`,
+		"3": `{"items":[{"id":"m2","sortKey":"2"},{"id":"m1","sortKey":"1"}],"hasMore":false}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Query().Get("cursor")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	var pageSizes []int
+	var ids []string
+	err := fetchAllMessages(api.NewClient(server.URL), "chat1", 2, func(page []api.Message) error {
+		pageSizes = append(pageSizes, len(page))
+		for _, msg := range page {
+			ids = append(ids, msg.ID)
+		}
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 2}, pageSizes)
+	assert.Equal(t, []string{"m4", "m3", "m2", "m1"}, ids)
+}
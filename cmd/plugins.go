@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+	"github.com/nerveband/beeper-api-cli/internal/plugin"
+)
+
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "Manage external beeper-<name> commands",
+	Long: `Plugins are executables named beeper-<name> on your PATH. When no builtin
+command matches, "beeper <name> [args...]" runs beeper-<name> with the
+remaining args; "beeper foo bar" prefers beeper-foo-bar over beeper-foo.
+
+Global flags given before the plugin name are resolved by beeper and handed
+to the plugin, along with the config file and environment, as:
+  BEEPER_API_URL        Desktop API URL
+  BEEPER_TOKEN          API token (when set)
+  BEEPER_OUTPUT_FORMAT  Output format
+  BEEPER_ENVELOPE       true or false
+  BEEPER_CONFIG         Config file the values came from
+  BEEPER_CLI_PATH       The beeper executable, for calling back into it
+  BEEPER_CLI_VERSION    The beeper version`,
+}
+
+var pluginsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List plugins found on PATH",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		plugins := plugin.List(os.Getenv("PATH"), isBuiltinCommand)
+		if plugins == nil {
+			plugins = []plugin.Plugin{}
+		}
+
+		if format, ok := getReportFormat(cmd); ok {
+			return output.WriteValue(cmd.OutOrStdout(), plugins, format)
+		}
+
+		w := cmd.OutOrStdout()
+		if len(plugins) == 0 {
+			fmt.Fprintln(w, "No plugins found on PATH (looked for executables named beeper-<name>)")
+			return nil
+		}
+		for _, p := range plugins {
+			fmt.Fprintf(w, "%s\t%s\n", p.Name, p.Path)
+			if p.ShadowedBy != "" {
+				fmt.Fprintf(w, "  warning: shadowed by %s\n", p.ShadowedBy)
+			}
+		}
+		return nil
+	},
+}
+
+// isBuiltinCommand reports whether name runs a builtin command, including
+// the help and completion commands cobra adds when it executes
+func isBuiltinCommand(name string) bool {
+	switch name {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPlugin dispatches to a plugin when args name one and no builtin
+// command. It reports whether a plugin ran, and its exit code.
+func runPlugin(args []string) (int, bool) {
+	globalArgs, rest, ok := splitGlobalFlags(args)
+	if !ok || len(rest) == 0 || isBuiltinCommand(rest[0]) {
+		return 0, false
+	}
+
+	// The plugin name is the run of args up to the first flag
+	nameArgs := rest
+	for i, arg := range rest {
+		if strings.HasPrefix(arg, "-") {
+			nameArgs = rest[:i]
+			break
+		}
+	}
+	path, n, found := plugin.Find(nameArgs, exec.LookPath)
+	if !found {
+		return 0, false
+	}
+
+	if err := rootCmd.PersistentFlags().Parse(globalArgs); err != nil {
+		exitWithError(err)
+	}
+	if err := loadConfig(rootCmd.PersistentFlags().Changed("envelope")); err != nil {
+		exitWithError(err)
+	}
+
+	self, _ := os.Executable()
+	env := plugin.Env{
+		APIURL:       cfg.APIURL,
+		Token:        os.Getenv("BEEPER_TOKEN"),
+		OutputFormat: cfg.OutputFormat,
+		Envelope:     cfg.Envelope,
+		ConfigPath:   config.GetConfigPath(),
+		CLIPath:      self,
+		Version:      Version,
+	}
+	code, err := plugin.Run(path, rest[n:], env.Environ(os.Environ()))
+	if err != nil {
+		exitWithError(err)
+	}
+	return code, true
+}
+
+// splitGlobalFlags splits leading global flags from the command args that
+// follow them. It returns false when the args ask for help, end the flags
+// with --, or use a flag beeper doesn't know, leaving cobra to handle them.
+func splitGlobalFlags(args []string) ([]string, []string, bool) {
+	flags := rootCmd.PersistentFlags()
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return nil, nil, false
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			return args[:i], args[i:], true
+		}
+
+		takesValue := false
+		if strings.HasPrefix(arg, "--") {
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			f := flags.Lookup(name)
+			if f == nil {
+				return nil, nil, false
+			}
+			takesValue = f.NoOptDefVal == "" && !hasValue
+		} else {
+			// Shorthands may be combined (-qo json) or carry a value (-ojson)
+			shorthands := arg[1:]
+			for j := 0; j < len(shorthands); j++ {
+				f := flags.ShorthandLookup(shorthands[j : j+1])
+				if f == nil {
+					return nil, nil, false
+				}
+				if f.NoOptDefVal == "" {
+					takesValue = j == len(shorthands)-1
+					break
+				}
+			}
+		}
+		if takesValue {
+			i++
+		}
+	}
+	return args, nil, true
+}
+
+func init() {
+	pluginsCmd.AddCommand(pluginsListCmd)
+	rootCmd.AddCommand(pluginsCmd)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitGlobalFlags(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		global []string
+		rest   []string
+		ok     bool
+	}{
+		{"no flags", []string{"foo", "bar"}, []string{}, []string{"foo", "bar"}, true},
+		{"value flag", []string{"--output", "json", "foo"}, []string{"--output", "json"}, []string{"foo"}, true},
+		{"value with equals", []string{"--output=json", "foo", "-x"}, []string{"--output=json"}, []string{"foo", "-x"}, true},
+		{"bool flag", []string{"--quiet", "foo"}, []string{"--quiet"}, []string{"foo"}, true},
+		{"combined shorthands", []string{"-qo", "text", "foo"}, []string{"-qo", "text"}, []string{"foo"}, true},
+		{"attached shorthand value", []string{"-ojson", "foo"}, []string{"-ojson"}, []string{"foo"}, true},
+		{"unknown flag", []string{"--nope", "foo"}, nil, nil, false},
+		{"help", []string{"--help", "foo"}, nil, nil, false},
+		{"double dash", []string{"--", "foo"}, nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global, rest, ok := splitGlobalFlags(tt.args)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.global, global)
+				assert.Equal(t, tt.rest, rest)
+			}
+		})
+	}
+}
+
+func TestIsBuiltinCommand(t *testing.T) {
+	assert.True(t, isBuiltinCommand("chats"))
+	assert.True(t, isBuiltinCommand("plugins"))
+	assert.True(t, isBuiltinCommand("help"))
+	assert.False(t, isBuiltinCommand("foo"))
+}
@@ -3,20 +3,32 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/nerveband/beeper-api-cli/internal/api"
 	"github.com/nerveband/beeper-api-cli/internal/config"
 	"github.com/nerveband/beeper-api-cli/internal/output"
+	"github.com/nerveband/beeper-api-cli/internal/query"
 	"github.com/nerveband/beeper-api-cli/internal/update"
 )
 
 var (
-	cfg           *config.Config
-	outputFormat  string
-	quietMode     bool
-	jsonErrors    bool
-	updateCheckCh <-chan *update.UpdateInfo
+	cfg                *config.Config
+	outputFormat       string
+	outputColumns      string
+	outputTemplate     string
+	outputTemplateFile string
+	outputFields       string
+	outputWhere        string
+	outputTZ           string
+	outputTimeFormat   string
+	colorMode          string
+	maxTokens          int
+	envelopeOutput     bool
+	quietMode          bool
+	jsonErrors         bool
+	updateCheckCh      <-chan *update.UpdateInfo
 	// Version is set at build time via ldflags
 	Version = "dev"
 )
@@ -26,24 +38,13 @@ var rootCmd = &cobra.Command{
 	Short: "Beeper API CLI - Command-line interface for Beeper Desktop API",
 	Long:  BannerWithVersion() + "\nA cross-platform CLI for the Beeper Desktop API.\nProvides LLM-friendly interfaces for reading and sending messages\nacross all Beeper-supported chat networks.",
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		var err error
-		cfg, err = config.Load()
-		if err != nil {
-			return fmt.Errorf("failed to load config: %w", err)
+		if err := loadConfig(cmd.Flags().Changed("envelope")); err != nil {
+			return err
 		}
 
-		// Override with environment variables if set
-		envCfg := config.LoadFromEnv()
-		cfg = cfg.Merge(envCfg)
-
-		// Override output format if flag is set
-		if outputFormat != "" {
-			cfg.OutputFormat = outputFormat
-		}
-
-		// Start async update check (skip for version, upgrade, and help commands)
+		// Start async update check (skip for version, upgrade, help and completion commands)
 		cmdName := cmd.Name()
-		if !quietMode && cmdName != "version" && cmdName != "upgrade" && cmdName != "help" {
+		if !quietMode && cmdName != "version" && cmdName != "upgrade" && cmdName != "help" && !isCompletionCommand(cmd) {
 			updateCheckCh = update.CheckAsync(Version)
 		}
 
@@ -64,7 +65,36 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// loadConfig resolves cfg from the config file, environment variables and
+// the global output flags, in increasing order of precedence
+func loadConfig(envelopeChanged bool) error {
+	var err error
+	cfg, err = config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Override with environment variables if set
+	envCfg := config.LoadFromEnv()
+	cfg = cfg.Merge(envCfg)
+
+	// Override output format if flag is set
+	if outputFormat != "" {
+		if err := output.ValidateFormat(outputFormat); err != nil {
+			return err
+		}
+		cfg.OutputFormat = outputFormat
+	}
+	if envelopeChanged {
+		cfg.Envelope = envelopeOutput
+	}
+	return nil
+}
+
 func Execute() {
+	if code, ok := runPlugin(os.Args[1:]); ok {
+		os.Exit(code)
+	}
 	if err := rootCmd.Execute(); err != nil {
 		exitWithError(err)
 	}
@@ -79,10 +109,24 @@ Documentation & Support:
 `
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "Output format (json, text, markdown)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "Output format ("+strings.Join(output.FormatNames(), ", ")+")")
+	rootCmd.PersistentFlags().StringVar(&outputColumns, "columns", "", "Comma-separated columns for table, csv and tsv output (e.g. id,title,network,unread)")
+	rootCmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Render output with a Go text/template (overrides --output)")
+	rootCmd.PersistentFlags().StringVar(&outputTemplateFile, "template-file", "", "Render output with a Go text/template read from a file")
+	rootCmd.PersistentFlags().StringVar(&outputFields, "fields", "", "Comma-separated JSON fields to keep in each result (e.g. id,title,unreadCount)")
+	rootCmd.PersistentFlags().StringVar(&outputWhere, "where", "", "Filter results with an expression (e.g. 'unreadCount > 0 && network == \"whatsapp\"'); in quoted strings \\ escapes only quotes and \\, so 'text =~ \"\\d+\"' works")
+	rootCmd.PersistentFlags().StringVar(&outputTZ, "tz", "", "Time zone for message timestamps: a zone name (e.g. America/New_York, UTC) or local")
+	rootCmd.PersistentFlags().StringVar(&outputTimeFormat, "time-format", "", "Timestamp format: rfc3339, short, relative (e.g. \"5m ago\") or a Go layout (e.g. \"Jan 2 15:04\")")
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", output.ColorAuto, "Color text and table output: auto (only on a terminal), always or never; NO_COLOR disables auto")
+	rootCmd.PersistentFlags().IntVar(&maxTokens, "max-tokens", 0, "With --output llm, keep the newest messages (or first chats and accounts) that fit in about this many tokens")
+	rootCmd.PersistentFlags().BoolVar(&envelopeOutput, "envelope", false, "Wrap json, ndjson and yaml results in {items, hasMore, nextCursor, count, fetchedAt, desktopVersion, command}")
 	rootCmd.PersistentFlags().BoolVarP(&quietMode, "quiet", "q", false, "Suppress non-essential output (hints, update notifications)")
 	rootCmd.PersistentFlags().BoolVar(&jsonErrors, "json-errors", false, "Output errors as JSON to stderr")
 
+	registerFlagCompletion(rootCmd, "output", fixedCompletions(output.FormatNames()...))
+	registerFlagCompletion(rootCmd, "color", fixedCompletions(output.ColorAuto, output.ColorAlways, output.ColorNever))
+	registerFlagCompletion(rootCmd, "time-format", fixedCompletions(output.TimeFormatRFC3339, output.TimeFormatShort, output.TimeFormatRelative))
+
 	// Add help footer with documentation links
 	defaultUsageTemplate := rootCmd.UsageTemplate()
 	rootCmd.SetUsageTemplate(defaultUsageTemplate + helpFooter)
@@ -90,9 +134,119 @@ func init() {
 
 // getOutputFormat returns the configured output format
 func getOutputFormat() output.Format {
+	if outputTemplate != "" || outputTemplateFile != "" {
+		return output.FormatTemplate
+	}
 	return output.Format(cfg.OutputFormat)
 }
 
+// getReportFormat returns the format for commands whose default output is a
+// human-readable report (info, version). They switch to structured output when
+// --output explicitly asks for json or ndjson, or whenever yaml is selected.
+func getReportFormat(cmd *cobra.Command) (output.Format, bool) {
+	format := getOutputFormat()
+	if format == output.FormatYAML || (format.IsStructured() && cmd.Flags().Changed("output")) {
+		return format, true
+	}
+	return "", false
+}
+
+// getOutputOptions returns formatter options from the output flags, validating
+// the output format (which may come from config) and --columns against the
+// columns available for the result type, and parsing --where, --tz,
+// --time-format, --color and any --template or --template-file
+func getOutputOptions(available []string) (output.Options, error) {
+	if err := output.ValidateFormat(string(getOutputFormat())); err != nil {
+		return output.Options{}, err
+	}
+
+	columns, err := output.ParseColumns(outputColumns, available)
+	if err != nil {
+		return output.Options{}, err
+	}
+	if err := output.ValidateTimeFormat(outputTimeFormat); err != nil {
+		return output.Options{}, err
+	}
+	location, err := output.ParseLocation(outputTZ)
+	if err != nil {
+		return output.Options{}, err
+	}
+
+	if maxTokens < 0 {
+		return output.Options{}, fmt.Errorf("--max-tokens must not be negative")
+	}
+	if maxTokens > 0 && getOutputFormat() != output.FormatLLM {
+		return output.Options{}, fmt.Errorf("--max-tokens is only supported with llm output")
+	}
+
+	color, err := output.ColorEnabled(colorMode, os.Stdout)
+	if err != nil {
+		return output.Options{}, err
+	}
+
+	opts := output.Options{
+		Columns:    columns,
+		Fields:     query.ParseFields(outputFields),
+		Location:   location,
+		TimeFormat: outputTimeFormat,
+		Color:      color,
+		MaxTokens:  maxTokens,
+	}
+
+	if outputWhere != "" {
+		if opts.Where, err = query.Parse(outputWhere); err != nil {
+			return output.Options{}, err
+		}
+	}
+
+	switch {
+	case outputTemplate != "" && outputTemplateFile != "":
+		return output.Options{}, fmt.Errorf("--template and --template-file cannot be used together")
+	case outputTemplate != "":
+		opts.Template, err = output.ParseTemplate(outputTemplate)
+	case outputTemplateFile != "":
+		opts.Template, err = output.ParseTemplateFile(outputTemplateFile)
+	}
+	if err != nil {
+		return output.Options{}, err
+	}
+
+	return opts, nil
+}
+
+// rejectQueryFlags fails if --where or --fields is set on a command whose
+// output they don't apply to, rather than silently ignoring them
+func rejectQueryFlags(cmd *cobra.Command) error {
+	for _, name := range []string{"where", "fields"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s is not supported by %s", name, cmd.CommandPath())
+		}
+	}
+	return nil
+}
+
+// newEnvelope returns the envelope for a command's results if --envelope or
+// the envelope config setting is on, and nil otherwise. It must be called
+// after the results are fetched so the Desktop version is known.
+func newEnvelope(cmd *cobra.Command, client *api.Client, hasMore bool, nextCursor string) *output.Envelope {
+	if !cfg.Envelope {
+		return nil
+	}
+	command := strings.TrimPrefix(cmd.CommandPath(), rootCmd.Name()+" ")
+	// Results served from the local database have no desktop version
+	desktopVersion := ""
+	if client != nil {
+		desktopVersion = client.GetDesktopVersion()
+	}
+	return output.NewEnvelope(command, desktopVersion, hasMore, nextCursor)
+}
+
+// streamOutput reports whether results should be written page by page. An
+// envelope needs the complete result set, so it turns streaming off.
+func streamOutput(format output.Format) bool {
+	return format.Streams() && !cfg.Envelope
+}
+
 // getAPIClient returns an API client with auth token
 func getAPIClient() *api.Client {
 	client := api.NewClient(cfg.APIURL)
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+	"github.com/nerveband/beeper-api-cli/internal/rules"
+	"github.com/nerveband/beeper-api-cli/internal/watch"
+)
+
+var (
+	rulesFile      string
+	rulesDryRun    bool
+	rulesOnce      bool
+	rulesInterval  time.Duration
+	rulesStateFile string
+	rulesLog       string
+)
+
+var rulesCmd = &cobra.Command{
+	Use:   "rules",
+	Short: "Act on incoming messages with rules",
+	Long: `Run rules that act on incoming messages, such as an out-of-office reply.
+
+A rules file lists rules, each with conditions and actions. Every condition is
+optional; a message must meet all that are set:
+
+  rules:
+    - name: out-of-office
+      chats: [acme, "!abc:beeper.local"]  # IDs, aliases or titles
+      networks: [slack, whatsapp]
+      senders: ["Ann Lee", "@ann:beeper.com"]  # names or IDs
+      text: '(?i)\b(help|urgent)\b'       # regular expression
+      hours: "18:00-09:00"                 # time of day, may wrap midnight
+      days: [sat, sun]
+      actions:
+        - reply: "Hi {{.Message.Sender}}, I'm away until Monday."
+        - react: "👍"
+        - mark_read: true
+        - archive: true
+        - forward: ops                     # chat ID, alias or title
+          forward_text: "{{.Chat.Title}}: {{.Message.Text}}"
+        - webhook: https://hooks.example.com/beeper
+      rate_limit:                          # at most max times per window
+        max: 1
+        per: 24h
+        scope: chat                        # rule (default), chat or sender
+
+Reply and forward texts are Go templates over .Rule, .Chat and .Message, with
+the --template helper functions. Webhooks get the rule, chat and message as a
+JSON POST. Hours and days follow the message time in the --tz time zone.
+
+Your own messages never match, nor do the messages the rules send, so rules
+can't answer themselves.`,
+}
+
+var rulesRunCmd = &cobra.Command{
+	Use:   "run --file <rules.yaml>",
+	Short: "Watch for new messages and apply rules to them",
+	Long: `Poll for new messages every --interval and run the actions of every rule
+matching each one, in file order. Only the chats named in the rules are
+polled, or the most recently active chats when any rule applies to all.
+
+Each action is logged to --log as a JSON line with the rule, chat, message,
+action and status: ok, error, dry_run or rate_limited. With --dry-run actions
+are only logged, rate limits are checked but never used up, and no state is
+saved.
+
+How far each chat has been read and the rate limits are saved to a state
+file once each poll's actions have run, so a restart misses no messages:
+after a downtime every message since the previous poll is read, however
+many pages back that goes. Actions are at-least-once: if the process dies
+mid-poll, that poll's actions may run again. Messages sent before the first
+run are never acted on. The default state file is derived from the rules
+file path (~/.beeper-api-cli/rules/<hash>.json).`,
+	Example: `  beeper rules run --file rules.yaml --dry-run
+  beeper rules run --file ~/ooo.yaml --log ~/ooo.log
+  beeper rules run --file rules.yaml --once   # one poll, e.g. from cron`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if rulesFile == "" {
+			return fmt.Errorf("--file is required")
+		}
+		if rulesInterval < time.Second {
+			return fmt.Errorf("--interval must be at least 1s")
+		}
+		location, err := output.ParseLocation(outputTZ)
+		if err != nil {
+			return err
+		}
+
+		file, err := rules.Load(rulesFile)
+		if err != nil {
+			return err
+		}
+		client := getAPIClient()
+		if err := resolveRuleChats(client, file); err != nil {
+			return err
+		}
+		var chats []api.Chat
+		if chatIDs := file.ChatIDs(); chatIDs != nil {
+			if chats, err = resolveChats(client, chatIDs); err != nil {
+				return err
+			}
+		}
+
+		statePath := rulesStateFile
+		if statePath == "" {
+			statePath, err = defaultRulesStatePath(rulesFile)
+			if err != nil {
+				return err
+			}
+		}
+		var state rules.State
+		if err := watch.LoadState(statePath, &state); err != nil {
+			return err
+		}
+
+		log, closeLog, err := openLog(rulesLog)
+		if err != nil {
+			return fmt.Errorf("failed to open log: %w", err)
+		}
+		defer closeLog()
+
+		watcher := &watch.Watcher{Source: client, Chats: chats, State: &state.State}
+		engine := &rules.Engine{
+			Rules:    file.Rules,
+			Client:   client,
+			State:    &state,
+			DryRun:   rulesDryRun,
+			Location: location,
+			Log:      log,
+		}
+		if !quietMode {
+			scope := "the most recently active chats"
+			if len(chats) > 0 {
+				scope = fmt.Sprintf("%d chats", len(chats))
+			}
+			when := "every " + rulesInterval.String()
+			if rulesOnce {
+				when = "once"
+			}
+			mode := ""
+			if rulesDryRun {
+				mode = ", dry run"
+			}
+			fmt.Fprintf(os.Stderr, "Applying %d rules to %s %s (state: %s%s)\n", len(file.Rules), scope, when, statePath, mode)
+		}
+
+		handle := func(events []watch.Event) error {
+			engine.Handle(events)
+			if rulesDryRun {
+				return nil
+			}
+			return watch.SaveState(statePath, &state)
+		}
+		onError := func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+
+		if rulesOnce {
+			events, err := watcher.Poll()
+			if err != nil {
+				onError(err)
+			}
+			return handle(events)
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return watcher.Run(ctx, rulesInterval, handle, onError)
+	},
+}
+
+// resolveRuleChats replaces the chat aliases and titles in rules and forward
+// actions with chat IDs
+func resolveRuleChats(client *api.Client, file *rules.File) error {
+	resolver, err := newChatResolver(client)
+	if err != nil {
+		return err
+	}
+	for _, r := range file.Rules {
+		chatIDs, err := resolveChatIDs(resolver, r.Chats)
+		if err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+		r.Chats = chatIDs
+		for i := range r.Actions {
+			if a := &r.Actions[i]; a.Forward != "" {
+				if a.Forward, err = resolver.ResolveTarget(a.Forward); err != nil {
+					return fmt.Errorf("rule %s: forward: %w", r.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// defaultRulesStatePath names the state file after the rules file, so each
+// rules file keeps its own state
+func defaultRulesStatePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve rules file path: %w", err)
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(filepath.Dir(config.GetConfigPath()), "rules", hex.EncodeToString(sum[:6])+".json"), nil
+}
+
+func init() {
+	rulesRunCmd.Flags().StringVar(&rulesFile, "file", "", "Rules file (YAML)")
+	rulesRunCmd.Flags().BoolVar(&rulesDryRun, "dry-run", false, "Log the actions rules would take without taking them")
+	rulesRunCmd.Flags().BoolVar(&rulesOnce, "once", false, "Poll once and exit instead of watching")
+	rulesRunCmd.Flags().DurationVar(&rulesInterval, "interval", 5*time.Second, "How often to poll for new messages")
+	rulesRunCmd.Flags().StringVar(&rulesStateFile, "state", "", "State file recording the messages already processed and rate limits")
+	rulesRunCmd.Flags().StringVar(&rulesLog, "log", "-", "Log file, - for stderr or off")
+	rulesCmd.AddCommand(rulesRunCmd)
+	rootCmd.AddCommand(rulesCmd)
+}
@@ -2,40 +2,162 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/nerveband/beeper-api-cli/internal/output"
+	"github.com/nerveband/beeper-api-cli/internal/store"
 )
 
 var (
-	searchLimit int
+	searchLimit          int
+	searchAttachmentsDir string
+	searchLocal          bool
+	searchDB             string
+	searchChatID         string
+	searchNetwork        string
+	searchCursor         string
 )
 
 var searchCmd = &cobra.Command{
 	Use:   "search --query <text>",
 	Short: "Search messages across all chats",
-	Long:  `Search for messages containing the specified query text across all Beeper chats.`,
+	Long: `Search for messages containing the specified query text across all Beeper chats.
+
+With --local, the search runs against the database built by 'beeper sync'
+instead of the API. Local queries are ranked by relevance, each result
+carries a snippet of the matching text, and the query supports full-text
+syntax:
+  ramen lunch          both words, anywhere in the message
+  "release candidate"  the exact phrase
+  deploy*              words starting with "deploy"
+  lunch OR dinner      either word (also AND, NOT and parentheses)
+  sender:alice         search the sender name instead of the text
+
+API results come a page at a time; pass the nextCursor of an --envelope
+result as --cursor to fetch the next page.`,
+	Example: `  beeper search --query "invoice"
+  beeper search --local --query '"release candidate" OR deploy*'
+  beeper search --local --query ramen --network whatsapp --limit 50`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		query, _ := cmd.Flags().GetString("query")
 		if query == "" {
 			return fmt.Errorf("--query is required")
 		}
 
+		opts, err := getOutputOptions(output.MessageColumns)
+		if err != nil {
+			return err
+		}
+
+		if searchLocal {
+			if searchCursor != "" {
+				return fmt.Errorf("--cursor is not supported with --local")
+			}
+			return runLocalSearch(cmd, query, opts)
+		}
+		if searchChatID != "" || searchNetwork != "" {
+			return fmt.Errorf("--chat-id and --network require --local")
+		}
+
 		client := getAPIClient()
 
-		messages, err := client.SearchMessages(query, searchLimit)
+		page, err := client.SearchMessagesPage(query, searchCursor, searchLimit)
 		if err != nil {
 			return fmt.Errorf("failed to search messages: %w", err)
 		}
 
-		formatted := output.FormatMessages(messages, getOutputFormat())
-		fmt.Print(formatted)
-		return nil
+		opts.Title = fmt.Sprintf("Search results for %q", query)
+		opts.AttachmentsDir = searchAttachmentsDir
+		opts.Highlight = highlightTerms(query)
+		nextCursor := ""
+		if page.HasMore {
+			nextCursor = page.OldestCursor
+		}
+		opts.Envelope = newEnvelope(cmd, client, page.HasMore, nextCursor)
+
+		return output.WriteMessages(cmd.OutOrStdout(), page.Items, getOutputFormat(), opts)
 	},
 }
 
+// runLocalSearch searches the database built by beeper sync
+func runLocalSearch(cmd *cobra.Command, query string, opts output.Options) error {
+	db, err := openStore(searchDB)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	chatID := searchChatID
+	if chatID != "" {
+		// Titles are matched against the mirror, keeping local search offline
+		resolver, err := newChatResolver(getAPIClient())
+		if err != nil {
+			return err
+		}
+		resolver.Chats = db.Chats
+		if chatID, err = resolver.Resolve(chatID); err != nil {
+			return err
+		}
+	}
+
+	messages, err := db.Search(query, store.SearchOptions{
+		Limit:   searchLimit,
+		ChatID:  chatID,
+		Network: searchNetwork,
+	})
+	if err != nil {
+		return err
+	}
+
+	opts.Title = fmt.Sprintf("Search results for %q", query)
+	opts.AttachmentsDir = searchAttachmentsDir
+	opts.Highlight = localHighlightTerms(query)
+	opts.Envelope = newEnvelope(cmd, nil, false, "")
+
+	return output.WriteMessages(cmd.OutOrStdout(), messages, getOutputFormat(), opts)
+}
+
+// localHighlightTerms returns the words of a full-text query, without its
+// operators, column filters and prefix markers
+func localHighlightTerms(query string) []string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		switch word {
+		case "AND", "OR", "NOT", "NEAR":
+			continue
+		}
+		if i := strings.Index(word, ":"); i >= 0 {
+			word = word[i+1:]
+		}
+		if word = strings.Trim(word, `"'()*^+`); word != "" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// highlightTerms splits a search query into the words highlighted in results
+func highlightTerms(query string) []string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		if word = strings.Trim(word, `"'`); word != "" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
 func init() {
 	searchCmd.Flags().String("query", "", "Search query text")
-	searchCmd.Flags().IntVar(&searchLimit, "limit", 20, "Maximum number of results (max: 20)")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 20, "Maximum number of results (max: 20 unless --local)")
+	searchCmd.Flags().StringVar(&searchAttachmentsDir, "attachments-dir", output.DefaultAttachmentsDir, "Directory HTML transcripts link attachments from, relative to the HTML file")
+	searchCmd.Flags().BoolVar(&searchLocal, "local", false, "Search the local database built by 'beeper sync'")
+	searchCmd.Flags().StringVar(&searchDB, "db", "", "Database file for --local (default ~/.beeper-api-cli/beeper.db)")
+	searchCmd.Flags().StringVar(&searchChatID, "chat-id", "", "Only search this chat, by ID, alias or title (requires --local)")
+	searchCmd.Flags().StringVar(&searchNetwork, "network", "", "Only search chats on this network (requires --local)")
+	searchCmd.Flags().StringVar(&searchCursor, "cursor", "", "Fetch the page of results after this cursor (the envelope's nextCursor; not with --local)")
+	registerFlagCompletion(searchCmd, "chat-id", completeChats)
+	registerFlagCompletion(searchCmd, "network", completeNetworks)
 	rootCmd.AddCommand(searchCmd)
 }
@@ -77,3 +77,13 @@ func TestSearchCommand_EmptyQuery(t *testing.T) {
 	err := rootCmd.Execute()
 	assert.Error(t, err)
 }
+
+func TestHighlightTerms(t *testing.T) {
+	assert.Equal(t, []string{"lunch", "friday"}, highlightTerms(`"lunch"  friday`))
+	assert.Nil(t, highlightTerms("  "))
+}
+
+func TestLocalHighlightTerms(t *testing.T) {
+	assert.Equal(t, []string{"release", "candidate", "deploy", "alice"}, localHighlightTerms(`"release candidate" OR deploy* AND sender:alice`))
+	assert.Nil(t, localHighlightTerms("NOT"))
+}
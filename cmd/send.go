@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 
 	"github.com/spf13/cobra"
@@ -9,7 +8,7 @@ import (
 )
 
 var sendCmd = &cobra.Command{
-	Use:   "send --chat-id <chat-id> --message <text>",
+	Use:   "send --chat-id <chat> --message <text>",
 	Short: "Send a message to a chat",
 	Long:  `Send a new message to the specified Beeper chat.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -23,36 +22,41 @@ var sendCmd = &cobra.Command{
 			return fmt.Errorf("--message is required")
 		}
 
+		if err := rejectQueryFlags(cmd); err != nil {
+			return err
+		}
+		opts, err := getOutputOptions(nil)
+		if err != nil {
+			return err
+		}
+
 		client := getAPIClient()
+		resolver, err := newChatResolver(client)
+		if err != nil {
+			return err
+		}
+		chatID, err = resolver.ResolveTarget(chatID)
+		if err != nil {
+			return err
+		}
 
 		messageID, err := client.SendMessage(chatID, message)
 		if err != nil {
 			return fmt.Errorf("failed to send message: %w", err)
 		}
 
-		// Format output based on format preference
-		format := getOutputFormat()
-		switch format {
-		case output.FormatJSON:
-			result := map[string]interface{}{
-				"success":    true,
-				"message_id": messageID,
-				"chat_id":    chatID,
-			}
-			jsonData, _ := json.MarshalIndent(result, "", "  ")
-			fmt.Println(string(jsonData))
-		case output.FormatMarkdown:
-			fmt.Printf("**Message sent successfully**\n\nID: `%s`\n", messageID)
-		default: // text
-			fmt.Printf("Message sent successfully. ID: %s\n", messageID)
-		}
-
-		return nil
+		result := output.SendResult{
+			Success:   true,
+			MessageID: messageID,
+			ChatID:    chatID,
+		}
+		return output.WriteSendResult(cmd.OutOrStdout(), result, getOutputFormat(), opts)
 	},
 }
 
 func init() {
-	sendCmd.Flags().String("chat-id", "", "Chat ID to send message to")
+	sendCmd.Flags().String("chat-id", "", "Chat to send message to: an ID, alias or title")
 	sendCmd.Flags().String("message", "", "Message text to send")
+	registerFlagCompletion(sendCmd, "chat-id", completeChats)
 	rootCmd.AddCommand(sendCmd)
 }
@@ -5,7 +5,9 @@ import (
 	"os"
 	"testing"
 
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // TestSendCommand tests sending a message
@@ -45,6 +47,18 @@ func TestSendCommand_MissingChatID(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// TestRejectQueryFlags tests that --where and --fields fail on commands that
+// can't apply them
+func TestRejectQueryFlags(t *testing.T) {
+	cmd := &cobra.Command{Use: "send"}
+	cmd.Flags().String("where", "", "")
+	cmd.Flags().String("fields", "", "")
+	assert.NoError(t, rejectQueryFlags(cmd))
+
+	require.NoError(t, cmd.Flags().Set("fields", "id"))
+	assert.EqualError(t, rejectQueryFlags(cmd), "--fields is not supported by send")
+}
+
 // TestSendCommand_MissingMessage tests error handling for missing message
 func TestSendCommand_MissingMessage(t *testing.T) {
 	output := &bytes.Buffer{}
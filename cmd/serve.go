@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/gateway"
+)
+
+// defaultKeysFile is the gateway key file name in the config directory
+const defaultKeysFile = "gateway-keys.yaml"
+
+var (
+	serveListen    string
+	serveKeysFile  string
+	serveAccessLog string
+	keygenName     string
+	keygenAccess   []string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve --listen <addr>",
+	Short: "Run a REST gateway with scoped API keys in front of Beeper Desktop",
+	Long: `Serve a curated REST API that proxies to Beeper Desktop, so internal services
+get limited access without the Desktop token.
+
+Endpoints (authenticate with "Authorization: Bearer <key>" or "X-API-Key"):
+  GET  /v1/chats                     ?cursor=&network=
+  GET  /v1/chats/{chatID}
+  GET  /v1/chats/{chatID}/messages   ?cursor=&limit=
+  POST /v1/chats/{chatID}/messages   {"text": "..."}
+  GET  /v1/search                    ?q=&limit=
+
+Keys are defined in a YAML file (default ~/.beeper-api-cli/gateway-keys.yaml):
+
+  keys:
+    - name: support-dashboard
+      key_sha256: <from 'beeper serve keygen'>
+      networks: [whatsapp]        # optional: only these networks
+      chats: ["!abc:beeper.local"] # optional: only these chats
+      access: [read]               # read, write or both (default read)
+      rate_limit: 60               # requests per minute (0 = unlimited)
+
+Chats outside a key's scope answer 404, and search results outside it are
+dropped. Every request is written to the access log as a JSON line.`,
+	Example: `  beeper serve keygen --name support-dashboard >> ~/.beeper-api-cli/gateway-keys.yaml
+  beeper serve --listen 127.0.0.1:8080
+  curl -H "Authorization: Bearer $KEY" http://127.0.0.1:8080/v1/chats`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keysPath := serveKeysFile
+		if keysPath == "" {
+			keysPath = filepath.Join(filepath.Dir(config.GetConfigPath()), defaultKeysFile)
+		}
+		keys, err := gateway.LoadKeys(keysPath)
+		if err != nil {
+			return err
+		}
+
+		accessLog, closeLog, err := openLog(serveAccessLog)
+		if err != nil {
+			return fmt.Errorf("failed to open access log: %w", err)
+		}
+		defer closeLog()
+
+		listener, err := net.Listen("tcp", serveListen)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", serveListen, err)
+		}
+
+		server := &http.Server{
+			Handler:           gateway.New(getAPIClient(), gateway.Options{Keys: keys, AccessLog: accessLog}),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		if !quietMode {
+			plural := "s"
+			if len(keys.Keys) == 1 {
+				plural = ""
+			}
+			fmt.Fprintf(os.Stderr, "Gateway listening on http://%s (%d key%s)\n", listener.Addr(), len(keys.Keys), plural)
+			if !isLoopback(listener.Addr()) {
+				fmt.Fprintln(os.Stderr, "Warning: listening beyond localhost; traffic is unencrypted, put a TLS proxy in front")
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to serve: %w", err)
+		}
+		return nil
+	},
+}
+
+var serveKeygenCmd = &cobra.Command{
+	Use:   "keygen --name <name>",
+	Short: "Generate a gateway API key",
+	Long: `Generate a random gateway API key. The key is printed once, as a comment,
+followed by a keys file entry holding only its SHA-256, so the file never
+contains the key itself.
+
+The entry belongs under the file's "keys:" list. When output is appended to
+an empty or new file, the "keys:" line is written first, so the result is a
+complete keys file; appending to an existing keys file adds the entry to its
+list.`,
+	Example: `  beeper serve keygen --name support-dashboard >> ~/.beeper-api-cli/gateway-keys.yaml
+  beeper serve keygen --name ops-bot --access read --access write >> ~/.beeper-api-cli/gateway-keys.yaml`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if keygenName == "" {
+			return fmt.Errorf("--name is required")
+		}
+		for _, a := range keygenAccess {
+			if a != gateway.AccessRead && a != gateway.AccessWrite {
+				return fmt.Errorf("invalid access %q (use read or write)", a)
+			}
+		}
+
+		key, err := gateway.GenerateKey()
+		if err != nil {
+			return err
+		}
+		w := cmd.OutOrStdout()
+		if isEmptyFile(w) {
+			fmt.Fprintln(w, "keys:")
+		}
+		fmt.Fprintf(w, `# API key for %s (shown once): %s
+  - name: %s
+    key_sha256: %s
+    access: [%s]
+`, keygenName, key, keygenName, gateway.HashKey(key), strings.Join(keygenAccess, ", "))
+		return nil
+	},
+}
+
+// isEmptyFile reports whether w is an empty regular file, such as a new file
+// that output is redirected to
+func isEmptyFile(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode().IsRegular() && info.Size() == 0
+}
+
+// openLog opens a log named by a flag: a file to append to, - for stderr or
+// off for none (a nil writer). The returned function closes it.
+func openLog(name string) (io.Writer, func() error, error) {
+	switch name {
+	case "-":
+		return os.Stderr, func() error { return nil }, nil
+	case "off":
+		return nil, func() error { return nil }, nil
+	}
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
+
+// isLoopback reports whether addr only accepts local connections
+func isLoopback(addr net.Addr) bool {
+	tcp, ok := addr.(*net.TCPAddr)
+	return ok && tcp.IP.IsLoopback()
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveKeysFile, "keys", "", "Keys file (default ~/.beeper-api-cli/gateway-keys.yaml)")
+	serveCmd.Flags().StringVar(&serveAccessLog, "access-log", "-", "Access log file, - for stderr or off")
+	serveKeygenCmd.Flags().StringVar(&keygenName, "name", "", "Name of the key, shown in the access log")
+	serveKeygenCmd.Flags().StringSliceVar(&keygenAccess, "access", []string{gateway.AccessRead}, "Access rights: read, write or read,write")
+	registerFlagCompletion(serveKeygenCmd, "access", fixedCompletions(gateway.AccessRead, gateway.AccessWrite))
+	serveCmd.AddCommand(serveKeygenCmd)
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/gateway"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestServeKeygen tests that appending keys to a new file builds a valid
+// keys file
+func TestServeKeygen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gateway-keys.yaml")
+	for _, name := range []string{"dashboard", "bot"} {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		require.NoError(t, err)
+		rootCmd.SetOut(f)
+		rootCmd.SetArgs([]string{"serve", "keygen", "--name", name})
+		err = rootCmd.Execute()
+		f.Close()
+		require.NoError(t, err)
+	}
+	rootCmd.SetOut(nil)
+
+	keys, err := gateway.LoadKeys(path)
+	require.NoError(t, err)
+	require.Len(t, keys.Keys, 2)
+	assert.Equal(t, "dashboard", keys.Keys[0].Name)
+	assert.Equal(t, "bot", keys.Keys[1].Name)
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/export"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+	"github.com/nerveband/beeper-api-cli/internal/stats"
+)
+
+var (
+	statsChatIDs     []string
+	statsNetwork     string
+	statsSince       string
+	statsTop         int
+	statsHistogram   bool
+	statsConcurrency int
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show messaging statistics",
+	Long: `Count messages per chat and per sender, show activity by hour and
+weekday, and measure how quickly you and others reply.
+
+A reply is the first message after the other side's turn, timed from the
+first message of that turn; the median is reported per chat and overall.
+Hours and weekdays are counted in the --tz time zone (local by default).
+
+Only messages since --since are counted (the last 7 days by default); pass
+--since '' to count the whole history, which fetches every message.
+
+Output is a set of tables, bar charts with --histogram, or the full report
+with --output json, ndjson or yaml. --top limits the chats and senders shown,
+not the JSON report.`,
+	Example: `  beeper stats
+  beeper stats --histogram --since 30d
+  beeper stats --network slack --since 2024-01-01 -o json
+  beeper stats --chat-id acme --chat-id globex --since 1w`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := export.ParseSince(statsSince)
+		if err != nil {
+			return err
+		}
+		if statsConcurrency < 1 {
+			return fmt.Errorf("--concurrency must be positive")
+		}
+		if err := rejectQueryFlags(cmd); err != nil {
+			return err
+		}
+		location, err := output.ParseLocation(outputTZ)
+		if err != nil {
+			return err
+		}
+		if location == nil {
+			location = time.Local
+		}
+
+		client := getAPIClient()
+		chats, err := selectStatsChats(client)
+		if err != nil {
+			return err
+		}
+
+		until := time.Now()
+		threads := make([]stats.Thread, len(chats))
+		errs := make([]error, len(chats))
+		forEachConcurrently(len(chats), statsConcurrency, func(i int) {
+			threads[i].Chat = chats[i]
+			threads[i].Messages, errs[i] = stats.Fetch(client, chats[i].ID, since, stats.DefaultPageSize)
+		})
+
+		report := stats.Compute(threads, location)
+		report.Until = until.In(location).Format(time.RFC3339)
+		if !since.IsZero() {
+			report.Since = since.In(location).Format(time.RFC3339)
+		}
+		for i, err := range errs {
+			if err != nil {
+				report.Errors = append(report.Errors, stats.ChatError{ChatID: chats[i].ID, Title: chats[i].Title, Error: err.Error()})
+			}
+		}
+
+		w := cmd.OutOrStdout()
+		if format, ok := getReportFormat(cmd); ok {
+			err = output.WriteValue(w, report, format)
+		} else if statsHistogram {
+			err = stats.WriteHistogram(w, report, statsTop)
+		} else {
+			err = stats.WriteTable(w, report, statsTop)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(report.Errors) > 0 {
+			return fmt.Errorf("failed to fetch %d of %d chats", len(report.Errors), len(chats))
+		}
+		return nil
+	},
+}
+
+// selectStatsChats returns the chats given by --chat-id, or every chat, in
+// either case narrowed to --network
+func selectStatsChats(client *api.Client) ([]api.Chat, error) {
+	if len(statsChatIDs) == 0 {
+		chats, err := fetchChatList(client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list chats: %w", err)
+		}
+		return filterNetwork(chats, statsNetwork), nil
+	}
+
+	chats, err := resolveChats(client, statsChatIDs)
+	if err != nil {
+		return nil, err
+	}
+	return filterNetwork(chats, statsNetwork), nil
+}
+
+// filterNetwork keeps the chats on network, case-insensitively (all of them
+// when network is empty)
+func filterNetwork(chats []api.Chat, network string) []api.Chat {
+	if network == "" {
+		return chats
+	}
+	var selected []api.Chat
+	for _, chat := range chats {
+		if strings.EqualFold(chat.Network, network) {
+			selected = append(selected, chat)
+		}
+	}
+	return selected
+}
+
+func init() {
+	statsCmd.Flags().StringArrayVar(&statsChatIDs, "chat-id", nil, "Count only this chat, by ID, alias or title (repeatable)")
+	statsCmd.Flags().StringVar(&statsNetwork, "network", "", "Count only chats on this network (e.g. whatsapp)")
+	statsCmd.Flags().StringVar(&statsSince, "since", "7d", "Count messages since this time: a duration (30d, 12h), a date (2024-01-31) or an RFC 3339 timestamp")
+	statsCmd.Flags().IntVar(&statsTop, "top", 10, "Chats and senders shown in tables and charts (0 for all)")
+	statsCmd.Flags().BoolVar(&statsHistogram, "histogram", false, "Draw activity and the busiest chats as bar charts")
+	statsCmd.Flags().IntVar(&statsConcurrency, "concurrency", 4, "Number of chats fetched at the same time")
+	registerFlagCompletion(statsCmd, "chat-id", completeChats)
+	registerFlagCompletion(statsCmd, "network", completeNetworks)
+	rootCmd.AddCommand(statsCmd)
+}
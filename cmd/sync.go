@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+	"github.com/nerveband/beeper-api-cli/internal/store"
+)
+
+var (
+	syncDB       string
+	syncChatIDs  []string
+	syncNetwork  string
+	syncPageSize int
+)
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Mirror chats and messages into a local search database",
+	Long: `Mirror chats and messages into a local SQLite database with a full-text
+index, for fast offline search with 'beeper search --local'.
+
+The database defaults to ~/.beeper-api-cli/beeper.db. Each chat records the
+newest message synced, so running sync again only fetches new messages.
+Progress is checkpointed after every page; an interrupted sync resumes where
+it stopped.`,
+	Example: `  beeper sync
+  beeper sync --network whatsapp
+  beeper sync --chat-id '!abc:beeper.local' --db ./work.db`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := openStore(syncDB)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		client := getAPIClient()
+		resolver, err := newChatResolver(client)
+		if err != nil {
+			return err
+		}
+		chatIDs, err := resolveChatIDs(resolver, syncChatIDs)
+		if err != nil {
+			return err
+		}
+
+		opts := store.SyncOptions{
+			ChatIDs:  chatIDs,
+			Network:  syncNetwork,
+			PageSize: syncPageSize,
+		}
+		if !quietMode {
+			opts.Progress = printSyncProgress
+		}
+
+		summary, err := db.Sync(client, opts)
+		if err != nil {
+			return fmt.Errorf("failed to sync: %w", err)
+		}
+
+		if format, ok := getReportFormat(cmd); ok {
+			if err := output.WriteValue(cmd.OutOrStdout(), summary, format); err != nil {
+				return err
+			}
+		} else {
+			printSyncSummary(summary)
+		}
+
+		if failed := summary.Failed(); failed > 0 {
+			return fmt.Errorf("failed to sync %d of %d chats", failed, len(summary.Chats))
+		}
+		return nil
+	},
+}
+
+// openStore opens the local database at path, or at the default location
+// next to the config file
+func openStore(path string) (*store.Store, error) {
+	if path == "" {
+		path = store.DefaultPath(config.GetConfigPath())
+	}
+	db, err := store.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open local database: %w", err)
+	}
+	return db, nil
+}
+
+// printSyncProgress reports each synced chat on stderr
+func printSyncProgress(r store.ChatResult) {
+	title := r.Title
+	if title == "" {
+		title = r.ChatID
+	}
+	if r.Error != "" {
+		fmt.Fprintf(os.Stderr, "✗ %s: %s\n", title, r.Error)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "✓ %s: %d new messages\n", title, r.NewMessages)
+}
+
+func printSyncSummary(s *store.SyncSummary) {
+	messages := 0
+	for _, c := range s.Chats {
+		messages += c.NewMessages
+	}
+	fmt.Printf("Synced %d chats: %d new messages (%d chats, %d messages stored)\n", len(s.Chats), messages, s.Stats.Chats, s.Stats.Messages)
+}
+
+func init() {
+	syncCmd.Flags().StringVar(&syncDB, "db", "", "Database file (default ~/.beeper-api-cli/beeper.db)")
+	syncCmd.Flags().StringArrayVar(&syncChatIDs, "chat-id", nil, "Sync only this chat, by ID, alias or title (repeatable)")
+	syncCmd.Flags().StringVar(&syncNetwork, "network", "", "Sync only chats on this network (e.g. whatsapp)")
+	syncCmd.Flags().IntVar(&syncPageSize, "page-size", store.DefaultPageSize, "Messages requested per page")
+	registerFlagCompletion(syncCmd, "chat-id", completeChats)
+	registerFlagCompletion(syncCmd, "network", completeNetworks)
+	rootCmd.AddCommand(syncCmd)
+}
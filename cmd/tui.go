@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/nerveband/beeper-api-cli/internal/tui"
+)
+
+var (
+	tuiInterval time.Duration
+	tuiPageSize int
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Triage chats in a full-screen terminal interface",
+	Long: `Open a full-screen interface with the chat list on the left and the
+selected chat's messages on the right. The chat list and the open chat are
+refreshed every --interval.
+
+Chat list:
+  up/down, j/k  Move                enter  Open chat
+  /             Filter by title     esc    Clear filter
+  c             Compose             s      Search messages
+  a             Archive/unarchive   r      Mark read
+  R             Refresh now         q      Quit
+
+Messages:
+  up/down, pgup/pgdn  Scroll; older messages load at the top
+  enter or c          Compose; enter sends, esc keeps the draft
+  a, r, s             Archive, mark read, search
+  esc                 Back to the chat list
+
+Pinned chats are marked *, chats with unread messages ● and their count.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if tuiInterval < time.Second {
+			return fmt.Errorf("--interval must be at least 1s")
+		}
+		if tuiPageSize < 1 {
+			return fmt.Errorf("--page-size must be positive")
+		}
+		return tui.Run(getAPIClient(), tui.Options{
+			PollInterval: tuiInterval,
+			PageSize:     tuiPageSize,
+		})
+	},
+}
+
+func init() {
+	tuiCmd.Flags().DurationVar(&tuiInterval, "interval", 5*time.Second, "How often to poll for new chats and messages")
+	tuiCmd.Flags().IntVar(&tuiPageSize, "page-size", 50, "Messages loaded per page of scrollback")
+	rootCmd.AddCommand(tuiCmd)
+}
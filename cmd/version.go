@@ -6,17 +6,38 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/nerveband/beeper-api-cli/internal/config"
+	"github.com/nerveband/beeper-api-cli/internal/output"
 )
 
+// versionReport is the structured form of 'beeper version'
+type versionReport struct {
+	Version   string `json:"version"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+	Config    string `json:"config"`
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print version information",
 	Long:  "Display the current version, build information, and platform details",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Printf("beeper-api-cli version %s\n", Version)
-		fmt.Printf("  Go version: %s\n", runtime.Version())
-		fmt.Printf("  OS/Arch:    %s/%s\n", runtime.GOOS, runtime.GOARCH)
-		fmt.Printf("  Config:     %s\n", config.GetConfigPath())
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report := versionReport{
+			Version:   Version,
+			GoVersion: runtime.Version(),
+			Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+			Config:    config.GetConfigPath(),
+		}
+
+		if format, ok := getReportFormat(cmd); ok {
+			return output.WriteValue(cmd.OutOrStdout(), report, format)
+		}
+
+		fmt.Printf("beeper-api-cli version %s\n", report.Version)
+		fmt.Printf("  Go version: %s\n", report.GoVersion)
+		fmt.Printf("  OS/Arch:    %s\n", report.Platform)
+		fmt.Printf("  Config:     %s\n", report.Config)
+		return nil
 	},
 }
 
@@ -0,0 +1,196 @@
+// Package alert runs a command for incoming messages that match a pattern,
+// at most once per message and at most once per chat per debounce window
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/watch"
+)
+
+// Matcher selects the messages that fire an alert. Our own messages never
+// match.
+type Matcher struct {
+	Text *regexp.Regexp
+	// Sender is a sender name or ID, compared case-insensitively (empty
+	// matches anyone)
+	Sender string
+}
+
+// Match reports whether msg fires an alert, and the text that matched
+func (m Matcher) Match(msg api.Message) (string, bool) {
+	if msg.IsSender {
+		return "", false
+	}
+	if m.Sender != "" && !strings.EqualFold(msg.Sender, m.Sender) && !strings.EqualFold(msg.SenderID, m.Sender) {
+		return "", false
+	}
+	loc := m.Text.FindStringIndex(msg.Text)
+	if loc == nil {
+		return "", false
+	}
+	return msg.Text[loc[0]:loc[1]], true
+}
+
+// State is what an alert keeps between runs
+type State struct {
+	watch.State
+	// Fired maps chat IDs to when an alert last fired in them
+	Fired map[string]time.Time `json:"fired,omitempty"`
+}
+
+// Runner runs the alert command for matching events
+type Runner struct {
+	Command string
+	Matcher Matcher
+	// Debounce is how long after an alert further matches in the same chat
+	// are suppressed
+	Debounce time.Duration
+	// Concurrency is how many commands may run at the same time
+	Concurrency int
+	// Timeout kills a command that runs longer (0 for no limit)
+	Timeout time.Duration
+	State   *State
+	Stdout  io.Writer
+	Stderr  io.Writer
+	// Log receives a line per alert fired or suppressed (nil for none)
+	Log io.Writer
+	// Now returns the current time (time.Now if nil)
+	Now func() time.Time
+
+	// exec runs the command for an alert; tests replace it
+	exec  func(ctx context.Context, a Alert) error
+	logMu sync.Mutex
+}
+
+// Alert is a message that fired an alert
+type Alert struct {
+	Chat    api.Chat
+	Message api.Message
+	// Match is the text the pattern matched
+	Match string
+}
+
+// Env returns the environment variables describing the alert
+func (a Alert) Env() []string {
+	return []string{
+		"BEEPER_CHAT_ID=" + a.Chat.ID,
+		"BEEPER_CHAT_TITLE=" + a.Chat.Title,
+		"BEEPER_NETWORK=" + a.Chat.Network,
+		"BEEPER_MESSAGE_ID=" + a.Message.ID,
+		"BEEPER_SENDER=" + a.Message.Sender,
+		"BEEPER_SENDER_ID=" + a.Message.SenderID,
+		"BEEPER_TEXT=" + a.Message.Text,
+		"BEEPER_TIMESTAMP=" + a.Message.Timestamp,
+		"BEEPER_MATCH=" + a.Match,
+	}
+}
+
+// Handle fires the command for every matching event, Concurrency at a time,
+// and returns once all have finished. A failing command is logged, not
+// retried: each message fires at most once.
+func (r *Runner) Handle(ctx context.Context, events []watch.Event) {
+	if r.State.Fired == nil {
+		r.State.Fired = make(map[string]time.Time)
+	}
+
+	var alerts []Alert
+	for _, ev := range events {
+		match, ok := r.Matcher.Match(ev.Message)
+		if !ok {
+			continue
+		}
+		a := Alert{Chat: ev.Chat, Message: ev.Message, Match: match}
+		now := r.now()
+		if last, ok := r.State.Fired[ev.Chat.ID]; ok && r.Debounce > 0 && now.Sub(last) < r.Debounce {
+			r.logf("Suppressed alert for message %s in %s (debounced until %s)",
+				a.Message.ID, chatName(a.Chat), last.Add(r.Debounce).Format(time.TimeOnly))
+			continue
+		}
+		r.State.Fired[ev.Chat.ID] = now
+		alerts = append(alerts, a)
+	}
+
+	run := r.exec
+	if run == nil {
+		run = r.run
+	}
+	sem := make(chan struct{}, max(r.Concurrency, 1))
+	var wg sync.WaitGroup
+	for _, a := range alerts {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := run(ctx, a); err != nil {
+				r.logf("Alert command failed for message %s in %s: %v", a.Message.ID, chatName(a.Chat), err)
+				return
+			}
+			r.logf("Alert fired for message %s in %s", a.Message.ID, chatName(a.Chat))
+		}()
+	}
+	wg.Wait()
+}
+
+// run executes the command through the shell with the message JSON on
+// stdin and the alert in the environment
+func (r *Runner) run(ctx context.Context, a Alert) error {
+	payload, err := json.Marshal(a.Message)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	c := shellCommand(ctx, r.Command)
+	c.Stdin = bytes.NewReader(append(payload, '\n'))
+	c.Stdout, c.Stderr = r.Stdout, r.Stderr
+	c.Env = append(os.Environ(), a.Env()...)
+	return c.Run()
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+func (r *Runner) logf(format string, args ...any) {
+	if r.Log == nil {
+		return
+	}
+	r.logMu.Lock()
+	defer r.logMu.Unlock()
+	fmt.Fprintf(r.Log, format+"\n", args...)
+}
+
+func (r *Runner) now() time.Time {
+	if r.Now != nil {
+		return r.Now()
+	}
+	return time.Now()
+}
+
+func chatName(chat api.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	return chat.ID
+}
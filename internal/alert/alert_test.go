@@ -0,0 +1,141 @@
+package alert
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func event(chatID, id, sender, text string) watch.Event {
+	return watch.Event{
+		Chat:    api.Chat{ID: chatID, Title: "Chat " + chatID, Network: "slack"},
+		Message: api.Message{ID: id, ChatID: chatID, Sender: sender, SenderID: "@" + strings.ToLower(sender), Text: text},
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m := Matcher{Text: regexp.MustCompile(`(?i)site (is )?down`)}
+
+	match, ok := m.Match(event("a", "1", "Ann", "Help, the SITE is down!").Message)
+	assert.True(t, ok)
+	assert.Equal(t, "SITE is down", match)
+
+	_, ok = m.Match(event("a", "2", "Ann", "all good").Message)
+	assert.False(t, ok)
+
+	own := event("a", "3", "Me", "site down").Message
+	own.IsSender = true
+	_, ok = m.Match(own)
+	assert.False(t, ok, "our own messages never match")
+
+	m.Sender = "ann"
+	_, ok = m.Match(event("a", "4", "Ann", "site down").Message)
+	assert.True(t, ok)
+	_, ok = m.Match(event("a", "5", "Bob", "site down").Message)
+	assert.False(t, ok)
+	m.Sender = "@bob"
+	_, ok = m.Match(event("a", "6", "Bob", "site down").Message)
+	assert.True(t, ok, "the sender ID matches too")
+}
+
+// recorder stands in for running the command
+type recorder struct {
+	mu    sync.Mutex
+	fired []string
+}
+
+func (r *recorder) exec(ctx context.Context, a Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fired = append(r.fired, a.Message.ID)
+	if a.Message.ID == "fail" {
+		return errors.New("exit status 1")
+	}
+	return nil
+}
+
+func TestHandle_Debounce(t *testing.T) {
+	now := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	rec := &recorder{}
+	var log strings.Builder
+	r := &Runner{
+		Matcher:  Matcher{Text: regexp.MustCompile(`down`)},
+		Debounce: time.Minute,
+		State:    &State{},
+		Log:      &log,
+		Now:      func() time.Time { return now },
+		exec:     rec.exec,
+	}
+
+	r.Handle(context.Background(), []watch.Event{
+		event("a", "1", "Ann", "site down"),
+		event("a", "2", "Ann", "still down"),
+		event("b", "3", "Bob", "down here too"),
+		event("b", "4", "Bob", "fine now"),
+	})
+	assert.ElementsMatch(t, []string{"1", "3"}, rec.fired)
+	assert.Contains(t, log.String(), "Suppressed alert for message 2 in Chat a (debounced until 09:01:00)\n")
+	assert.Contains(t, log.String(), "Alert fired for message 1 in Chat a\n")
+	assert.Equal(t, now, r.State.Fired["a"])
+
+	// Once the window has passed the chat fires again
+	now = now.Add(2 * time.Minute)
+	r.Handle(context.Background(), []watch.Event{event("a", "fail", "Ann", "down again")})
+	assert.Contains(t, rec.fired, "fail")
+	assert.Contains(t, log.String(), "Alert command failed for message fail in Chat a: exit status 1\n")
+}
+
+func TestHandle_Concurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	r := &Runner{
+		Matcher:     Matcher{Text: regexp.MustCompile(`.`)},
+		Concurrency: 2,
+		State:       &State{},
+		exec: func(ctx context.Context, a Alert) error {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			return nil
+		},
+	}
+
+	var events []watch.Event
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		events = append(events, event("chat"+id, id, "Ann", "hi"))
+	}
+	r.Handle(context.Background(), events)
+	assert.Equal(t, int32(2), peak.Load())
+	assert.Len(t, r.State.Fired, 5)
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+	var stdout strings.Builder
+	r := &Runner{Command: `printf '%s|%s|' "$BEEPER_CHAT_TITLE" "$BEEPER_MATCH"; cat`, Stdout: &stdout}
+	a := Alert{Chat: api.Chat{ID: "a", Title: "Acme"}, Message: api.Message{ID: "m1", Text: "site down"}, Match: "down"}
+
+	require.NoError(t, r.run(context.Background(), a))
+	assert.Equal(t, `Acme|down|{"id":"m1","chatID":"","senderName":"","text":"site down","timestamp":"","isSender":false}`+"\n", stdout.String())
+
+	r = &Runner{Command: "sleep 5", Timeout: 50 * time.Millisecond}
+	assert.Error(t, r.run(context.Background(), a))
+}
@@ -6,6 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -32,6 +36,11 @@ func (c *Client) SetAuthToken(token string) {
 	c.authToken = token
 }
 
+// SetTimeout sets how long a request may take, including reading the response
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.httpClient.Timeout = timeout
+}
+
 // Ping checks if the API is reachable
 func (c *Client) Ping() error {
 	resp, err := c.httpClient.Get(c.baseURL + "/health")
@@ -139,13 +148,30 @@ func (c *Client) doRequestWithOp(method, path string, body interface{}, operatio
 // Chat represents a Beeper chat/conversation
 // ChatsResponse represents the API response for listing chats
 type ChatsResponse struct {
-	Items  []Chat `json:"items"`
-	HasMore bool `json:"hasMore"`
+	Items        []Chat `json:"items"`
+	HasMore      bool   `json:"hasMore"`
+	OldestCursor string `json:"oldestCursor,omitempty"`
+	NewestCursor string `json:"newestCursor,omitempty"`
 }
 
-// ListChats retrieves all chats
+// ListChats retrieves the first page of chats
 func (c *Client) ListChats() ([]Chat, error) {
-	data, err := c.doRequestWithOp("GET", "/v1/chats", nil, "list_chats")
+	resp, err := c.ListChatsPage("")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// ListChatsPage retrieves one page of chats. An empty cursor starts at the most
+// recently active chats; pass the previous page's OldestCursor to continue.
+func (c *Client) ListChatsPage(cursor string) (*ChatsResponse, error) {
+	path := "/v1/chats"
+	if cursor != "" {
+		path += "?cursor=" + url.QueryEscape(cursor) + "&direction=before"
+	}
+
+	data, err := c.doRequestWithOp("GET", path, nil, "list_chats")
 	if err != nil {
 		return nil, err
 	}
@@ -160,12 +186,12 @@ func (c *Client) ListChats() ([]Chat, error) {
 		}
 	}
 
-	return resp.Items, nil
+	return &resp, nil
 }
 
 // GetChat retrieves a specific chat by ID
 func (c *Client) GetChat(chatID string) (*Chat, error) {
-	data, err := c.doRequestWithOp("GET", "/v1/chats/"+chatID, nil, "get_chat")
+	data, err := c.doRequestWithOp("GET", "/v1/chats/"+url.PathEscape(chatID), nil, "get_chat")
 	if err != nil {
 		return nil, err
 	}
@@ -185,13 +211,36 @@ func (c *Client) GetChat(chatID string) (*Chat, error) {
 
 // MessagesResponse represents the API response for listing messages
 type MessagesResponse struct {
-	Items []Message `json:"items"`
-	HasMore bool `json:"hasMore"`
+	Items   []Message `json:"items"`
+	HasMore bool      `json:"hasMore"`
+}
+
+// NextCursor returns the cursor for the page of older messages following this
+// one. Items are ordered newest first, so this is the last item's sort key.
+func (r *MessagesResponse) NextCursor() string {
+	if len(r.Items) == 0 {
+		return ""
+	}
+	return r.Items[len(r.Items)-1].SortKey
 }
 
 // ListMessages retrieves messages from a chat
 func (c *Client) ListMessages(chatID string, limit int) ([]Message, error) {
-	path := fmt.Sprintf("/v1/chats/%s/messages?limit=%d", chatID, limit)
+	resp, err := c.ListMessagesPage(chatID, "", limit)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// ListMessagesPage retrieves one page of messages from a chat. An empty cursor
+// starts at the newest message; pass NextCursor() to page back through history.
+func (c *Client) ListMessagesPage(chatID, cursor string, limit int) (*MessagesResponse, error) {
+	path := fmt.Sprintf("/v1/chats/%s/messages?limit=%d", url.PathEscape(chatID), limit)
+	if cursor != "" {
+		path += "&cursor=" + url.QueryEscape(cursor) + "&direction=before"
+	}
+
 	data, err := c.doRequestWithOp("GET", path, nil, "list_messages")
 	if err != nil {
 		return nil, err
@@ -207,7 +256,7 @@ func (c *Client) ListMessages(chatID string, limit int) ([]Message, error) {
 		}
 	}
 
-	return resp.Items, nil
+	return &resp, nil
 }
 
 // SendMessage sends a message to a chat and returns the message ID
@@ -216,7 +265,7 @@ func (c *Client) SendMessage(chatID, message string) (string, error) {
 		Text: message,
 	}
 
-	data, err := c.doRequestWithOp("POST", "/v1/chats/"+chatID+"/messages", req, "send_message")
+	data, err := c.doRequestWithOp("POST", "/v1/chats/"+url.PathEscape(chatID)+"/messages", req, "send_message")
 	if err != nil {
 		return "", err
 	}
@@ -234,14 +283,51 @@ func (c *Client) SendMessage(chatID, message string) (string, error) {
 	return resp.ID, nil
 }
 
+// ArchiveChat archives a chat, or moves it back to the inbox when archived is false
+func (c *Client) ArchiveChat(chatID string, archived bool) error {
+	body := map[string]bool{"archived": archived}
+	_, err := c.doRequestWithOp("POST", "/v1/chats/"+url.PathEscape(chatID)+"/archive", body, "archive_chat")
+	return err
+}
+
+// MarkChatRead marks every message in a chat as read
+func (c *Client) MarkChatRead(chatID string) error {
+	_, err := c.doRequestWithOp("POST", "/v1/chats/"+url.PathEscape(chatID)+"/read", nil, "mark_read")
+	return err
+}
+
+// AddReaction reacts to a message with an emoji or shortcode
+func (c *Client) AddReaction(chatID, messageID, reactionKey string) error {
+	body := map[string]string{"reactionKey": reactionKey}
+	_, err := c.doRequestWithOp("POST", "/v1/chats/"+url.PathEscape(chatID)+"/messages/"+url.PathEscape(messageID)+"/reactions", body, "add_reaction")
+	return err
+}
+
 // SearchResponse represents the API response for searching messages
 type SearchResponse struct {
-	Items []Message `json:"items"`
+	Items        []Message `json:"items"`
+	HasMore      bool      `json:"hasMore"`
+	OldestCursor string    `json:"oldestCursor,omitempty"`
+	NewestCursor string    `json:"newestCursor,omitempty"`
 }
 
 // SearchMessages searches for messages across all chats
 func (c *Client) SearchMessages(query string, limit int) ([]Message, error) {
-	path := fmt.Sprintf("/v1/messages/search?q=%s&limit=%d", query, limit)
+	resp, err := c.SearchMessagesPage(query, "", limit)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Items, nil
+}
+
+// SearchMessagesPage searches for messages across all chats and returns a
+// page of results with its pagination state. An empty cursor returns the
+// newest results; otherwise the page before the cursor is returned.
+func (c *Client) SearchMessagesPage(query, cursor string, limit int) (*SearchResponse, error) {
+	path := fmt.Sprintf("/v1/messages/search?q=%s&limit=%d", url.QueryEscape(query), limit)
+	if cursor != "" {
+		path += "&cursor=" + url.QueryEscape(cursor) + "&direction=before"
+	}
 	data, err := c.doRequestWithOp("GET", path, nil, "search_messages")
 	if err != nil {
 		return nil, err
@@ -257,7 +343,27 @@ func (c *Client) SearchMessages(query string, limit int) ([]Message, error) {
 		}
 	}
 
-	return resp.Items, nil
+	return &resp, nil
+}
+
+// ListAccounts retrieves all connected chat network accounts
+func (c *Client) ListAccounts() ([]Account, error) {
+	data, err := c.doRequestWithOp("GET", "/v1/accounts", nil, "list_accounts")
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []Account
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return nil, &APIError{
+			Message:    fmt.Sprintf("failed to unmarshal accounts: %v", err),
+			Category:   CategoryServer,
+			Operation:  "list_accounts",
+			Underlying: err,
+		}
+	}
+
+	return accounts, nil
 }
 
 // DiscoverAPI attempts to auto-discover the Beeper Desktop API URL
@@ -274,3 +380,80 @@ func DiscoverAPI() (string, error) {
 
 	return "", fmt.Errorf("could not auto-discover Beeper Desktop API")
 }
+
+// downloadAssetRequest asks Beeper Desktop to fetch a remote (mxc://) asset
+type downloadAssetRequest struct {
+	URL string `json:"url"`
+}
+
+// downloadAssetResponse points at the local copy of a downloaded asset
+type downloadAssetResponse struct {
+	SrcURL string `json:"srcURL"`
+	Error  string `json:"error,omitempty"`
+}
+
+// DownloadAsset opens the content of an attachment's SrcURL. Local file://
+// URLs are read directly, http(s) URLs are fetched, and anything else (such
+// as mxc:// URLs) is first downloaded by Beeper Desktop. The caller must
+// close the returned reader.
+func (c *Client) DownloadAsset(srcURL string) (io.ReadCloser, error) {
+	u, err := url.Parse(srcURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return os.Open(fileURLPath(u))
+	case "http", "https":
+		req, err := http.NewRequest("GET", srcURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		if c.authToken != "" && strings.HasPrefix(srcURL, c.baseURL) {
+			req.Header.Set("Authorization", "Bearer "+c.authToken)
+		}
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, WrapNetworkError(err, "download_asset")
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, NewAPIErrorFromStatus(resp.StatusCode, body, "download_asset")
+		}
+		return resp.Body, nil
+	}
+
+	data, err := c.doRequestWithOp("POST", "/v1/assets/download", downloadAssetRequest{URL: srcURL}, "download_asset")
+	if err != nil {
+		return nil, err
+	}
+	var resp downloadAssetResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, &APIError{
+			Message:    fmt.Sprintf("failed to unmarshal response: %v", err),
+			Category:   CategoryServer,
+			Operation:  "download_asset",
+			Underlying: err,
+		}
+	}
+	if resp.Error != "" || !strings.HasPrefix(resp.SrcURL, "file://") {
+		return nil, &APIError{
+			Message:   fmt.Sprintf("failed to download asset: %s", resp.Error),
+			Category:  CategoryServer,
+			Operation: "download_asset",
+		}
+	}
+	return c.DownloadAsset(resp.SrcURL)
+}
+
+// fileURLPath converts a file:// URL to a local path, dropping the slash
+// before Windows drive letters ("/C:/x" becomes "C:/x")
+func fileURLPath(u *url.URL) string {
+	p := u.Path
+	if len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p)
+}
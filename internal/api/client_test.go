@@ -1,7 +1,13 @@
 package api
 
 import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -176,3 +182,121 @@ func TestClient_Ping(t *testing.T) {
 	err := client.Ping()
 	assert.NoError(t, err, "Ping should succeed with valid API URL")
 }
+
+// TestClient_ListMessagesPage tests cursor handling for message pagination
+func TestClient_ListMessagesPage(t *testing.T) {
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Write([]byte(`{"items":[{"id":"m2","sortKey":"200"},{"id":"m1","sortKey":"100"}],"hasMore":true}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	page, err := client.ListMessagesPage("chat1", "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "limit=2", gotQuery)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, "100", page.NextCursor())
+
+	_, err = client.ListMessagesPage("chat1", "100", 2)
+	require.NoError(t, err)
+	assert.Equal(t, "limit=2&cursor=100&direction=before", gotQuery)
+}
+
+// TestClient_SearchMessagesPage tests query escaping and pagination state for search
+func TestClient_SearchMessagesPage(t *testing.T) {
+	var gotQuery, gotCursor string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotQuery, gotCursor = r.URL.Query().Get("q"), r.URL.Query().Get("cursor")
+		w.Header().Set("X-Beeper-Desktop-Version", "4.1.0")
+		w.Write([]byte(`{"items":[{"id":"m1"}],"hasMore":true,"oldestCursor":"c1"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	page, err := client.SearchMessagesPage("fish & chips", "", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "fish & chips", gotQuery)
+	assert.Empty(t, gotCursor)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, "c1", page.OldestCursor)
+	assert.Equal(t, "4.1.0", client.GetDesktopVersion())
+
+	_, err = client.SearchMessagesPage("fish & chips", "c1", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "c1", gotCursor)
+}
+
+// TestClient_ChatActions tests the archive and mark-read requests
+func TestClient_ChatActions(t *testing.T) {
+	var gotMethod, gotPath, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotMethod, gotPath, gotBody = r.Method, r.URL.Path, string(body)
+		w.Write([]byte(`{"success":true}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+
+	require.NoError(t, client.ArchiveChat("chat1", true))
+	assert.Equal(t, "POST", gotMethod)
+	assert.Equal(t, "/v1/chats/chat1/archive", gotPath)
+	assert.JSONEq(t, `{"archived":true}`, gotBody)
+
+	require.NoError(t, client.MarkChatRead("chat1"))
+	assert.Equal(t, "/v1/chats/chat1/read", gotPath)
+
+	require.NoError(t, client.AddReaction("chat1", "msg1", "👍"))
+	assert.Equal(t, "/v1/chats/chat1/messages/msg1/reactions", gotPath)
+	assert.JSONEq(t, `{"reactionKey":"👍"}`, gotBody)
+}
+
+// TestClient_EscapesPathSegments checks that IDs can't change the request path
+func TestClient_EscapesPathSegments(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath()+"?"+r.URL.RawQuery)
+		w.Write([]byte(`{"id":"x","items":[]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	_, err := client.GetChat("a/../accounts?x#y")
+	require.NoError(t, err)
+	_, err = client.ListMessagesPage("a/b", "", 5)
+	require.NoError(t, err)
+	require.NoError(t, client.AddReaction("a?b", "m/1", "x"))
+
+	assert.Equal(t, []string{
+		"/v1/chats/a%2F..%2Faccounts%3Fx%23y?",
+		"/v1/chats/a%2Fb/messages?limit=5",
+		"/v1/chats/a%3Fb/messages/m%2F1/reactions?",
+	}, paths)
+}
+
+// TestClient_DownloadAsset tests reading local and Desktop-downloaded assets
+func TestClient_DownloadAsset(t *testing.T) {
+	local := filepath.Join(t.TempDir(), "photo.jpg")
+	require.NoError(t, os.WriteFile(local, []byte("JPEG"), 0644))
+	fileURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(local)}).String()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/assets/download", r.URL.Path)
+		fmt.Fprintf(w, `{"srcURL":%q}`, fileURL)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	for _, src := range []string{fileURL, "mxc://beeper.com/abc"} {
+		body, err := client.DownloadAsset(src)
+		require.NoError(t, err, src)
+		data, err := io.ReadAll(body)
+		body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "JPEG", string(data))
+	}
+}
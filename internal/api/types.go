@@ -1,5 +1,10 @@
 package api
 
+import (
+	"strings"
+	"time"
+)
+
 // Chat represents a Beeper chat/conversation
 // Using a simplified structure that works with JSON unmarshaling
 type Chat struct {
@@ -19,10 +24,70 @@ type Chat struct {
 type Message struct {
 	ID        string `json:"id"`
 	ChatID    string `json:"chatID"`
+	SenderID  string `json:"senderID,omitempty"`
 	Sender    string `json:"senderName"`
 	Text      string `json:"text"`
 	Timestamp string `json:"timestamp"` // ISO 8601 timestamp string
 	IsSender  bool   `json:"isSender"`
+	SortKey   string `json:"sortKey,omitempty"` // Pagination cursor for this message
+	// ReplyTo is the ID of the message this one replies to
+	ReplyTo string `json:"linkedMessageID,omitempty"`
+
+	Attachments []Attachment `json:"attachments,omitempty"`
+	Reactions   []Reaction   `json:"reactions,omitempty"`
+
+	// Snippet is the matching excerpt of Text, set by local search
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// Time parses Timestamp. The second result is false if the timestamp is
+// missing or not ISO 8601.
+func (m Message) Time() (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, m.Timestamp)
+	return t, err == nil
+}
+
+// CompareSortKeys orders message sort keys. Numeric keys are compared by
+// value, so "99" sorts before "100"; other keys compare as strings.
+func CompareSortKeys(a, b string) int {
+	if isDigits(a) && isDigits(b) {
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Attachment represents a file attached to a message
+type Attachment struct {
+	Type     string `json:"type,omitempty"` // img, video, audio or unknown
+	SrcURL   string `json:"srcURL,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+	FileName string `json:"fileName,omitempty"`
+	FileSize int64  `json:"fileSize,omitempty"`
+}
+
+// Reaction represents a reaction added to a message
+type Reaction struct {
+	ID            string `json:"id,omitempty"`
+	ReactionKey   string `json:"reactionKey"` // Emoji or shortcode
+	ParticipantID string `json:"participantID,omitempty"`
 }
 
 // SendMessageRequest represents a message send request
@@ -34,3 +99,17 @@ type SendMessageRequest struct {
 type SendMessageResponse struct {
 	ID string `json:"id"`
 }
+
+// Account represents a chat network account connected to Beeper
+type Account struct {
+	ID      string      `json:"accountID"`
+	Network string      `json:"network"`
+	User    AccountUser `json:"user"`
+}
+
+// AccountUser represents the user identity of a connected account
+type AccountUser struct {
+	ID       string `json:"id"`
+	FullName string `json:"fullName,omitempty"`
+	Username string `json:"username,omitempty"`
+}
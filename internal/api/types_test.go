@@ -0,0 +1,27 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageTime(t *testing.T) {
+	ts, ok := Message{Timestamp: "2021-12-20T10:00:00.5Z"}.Time()
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2021, 12, 20, 10, 0, 0, 500000000, time.UTC), ts)
+
+	_, ok = Message{Timestamp: "yesterday"}.Time()
+	assert.False(t, ok)
+
+	_, ok = Message{}.Time()
+	assert.False(t, ok)
+}
+
+func TestCompareSortKeys(t *testing.T) {
+	assert.Equal(t, -1, CompareSortKeys("99", "100"))
+	assert.Equal(t, 1, CompareSortKeys("100", "099"))
+	assert.Equal(t, 0, CompareSortKeys("7", "7"))
+	assert.Equal(t, -1, CompareSortKeys("a", "b"))
+}
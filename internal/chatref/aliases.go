@@ -0,0 +1,88 @@
+// Package chatref resolves the chat references users type (aliases, exact
+// or fuzzy chat titles and raw chat IDs) to chat IDs.
+package chatref
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// AliasesFile is the alias file name in the config directory
+const AliasesFile = "aliases.yaml"
+
+// Aliases maps alias names to chat IDs
+type Aliases map[string]string
+
+// LoadAliases reads an alias file. A missing file holds no aliases.
+func LoadAliases(path string) (Aliases, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Aliases{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aliases: %w", err)
+	}
+
+	aliases := Aliases{}
+	if err := yaml.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse aliases file %s: %w", path, err)
+	}
+	normalized := make(Aliases, len(aliases))
+	for name, chatID := range aliases {
+		normalized[strings.ToLower(name)] = chatID
+	}
+	return normalized, nil
+}
+
+// Save writes the aliases to path, creating its directory if needed
+func (a Aliases) Save(path string) error {
+	data, err := yaml.Marshal(map[string]string(a))
+	if err != nil {
+		return fmt.Errorf("failed to encode aliases: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write aliases: %w", err)
+	}
+	return nil
+}
+
+// Lookup returns the chat ID for an alias, ignoring case
+func (a Aliases) Lookup(name string) (string, bool) {
+	chatID, ok := a[strings.ToLower(name)]
+	return chatID, ok
+}
+
+// Names returns the alias names in order
+func (a Aliases) Names() []string {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateAliasName checks that an alias can't be mistaken for a chat ID or
+// a flag. Names are case-insensitive.
+func ValidateAliasName(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("alias name is required")
+	case strings.HasPrefix(name, "-"), strings.HasPrefix(name, "!"):
+		return fmt.Errorf("alias name %q must not start with %q", name, name[:1])
+	case strings.ContainsFunc(name, unicode.IsSpace):
+		return fmt.Errorf("alias name %q must not contain spaces", name)
+	case strings.Contains(name, ":"):
+		return fmt.Errorf("alias name %q must not contain ':'", name)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package chatref
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAliasesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", AliasesFile)
+
+	aliases, err := LoadAliases(path)
+	require.NoError(t, err)
+	assert.Empty(t, aliases)
+
+	aliases["family"] = "!abc:beeper.local"
+	aliases["team"] = "!def:beeper.local"
+	require.NoError(t, aliases.Save(path))
+
+	loaded, err := LoadAliases(path)
+	require.NoError(t, err)
+	assert.Equal(t, aliases, loaded)
+	assert.Equal(t, []string{"family", "team"}, loaded.Names())
+}
+
+func TestLoadAliases_NormalizesCase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), AliasesFile)
+	require.NoError(t, os.WriteFile(path, []byte("Family: \"!abc:beeper.local\"\n"), 0644))
+
+	aliases, err := LoadAliases(path)
+	require.NoError(t, err)
+	chatID, ok := aliases.Lookup("FAMILY")
+	assert.True(t, ok)
+	assert.Equal(t, "!abc:beeper.local", chatID)
+}
+
+func TestValidateAliasName(t *testing.T) {
+	assert.NoError(t, ValidateAliasName("family"))
+	assert.NoError(t, ValidateAliasName("eng-team"))
+	assert.Error(t, ValidateAliasName(""))
+	assert.Error(t, ValidateAliasName("!room"))
+	assert.Error(t, ValidateAliasName("-x"))
+	assert.Error(t, ValidateAliasName("two words"))
+	assert.Error(t, ValidateAliasName("a:b"))
+}
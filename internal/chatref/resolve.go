@@ -0,0 +1,188 @@
+package chatref
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// maxCandidates caps the chats listed in an ambiguous match error
+const maxCandidates = 10
+
+// Resolver turns chat references into chat IDs
+type Resolver struct {
+	Aliases Aliases
+	// Chats lists the chats titles are matched against. It is only called
+	// for references that aren't an alias or a chat ID.
+	Chats func() ([]api.Chat, error)
+}
+
+// match tiers, best first
+const (
+	tierExact = iota
+	tierNormalized
+	tierPrefix
+	tierWordPrefix
+	tierSubstring
+	tierSubsequence
+	tierNone
+)
+
+// Resolve returns the chat ID a reference names, trying in order: an alias,
+// a chat ID, an exact title (ignoring case) and a fuzzy title match. A
+// reference that matches several chats equally well is an error listing
+// the candidates.
+func (r *Resolver) Resolve(ref string) (string, error) {
+	return r.resolve(ref, tierSubsequence)
+}
+
+// ResolveTarget resolves a chat that messages are written to. It is
+// stricter than Resolve: subsequence title matches are not accepted, since
+// a loose guess would send to the wrong chat.
+func (r *Resolver) ResolveTarget(ref string) (string, error) {
+	return r.resolve(ref, tierSubstring)
+}
+
+// resolve is Resolve, accepting title matches up to the worst tier
+func (r *Resolver) resolve(ref string, worst int) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", api.NewAPIError("chat is required", api.CategoryValidation)
+	}
+	if chatID, ok := r.Aliases.Lookup(ref); ok {
+		return chatID, nil
+	}
+	if IsChatID(ref) || r.Chats == nil {
+		return ref, nil
+	}
+
+	chats, err := r.Chats()
+	if err != nil {
+		return "", err
+	}
+
+	best := tierNone
+	var matches []api.Chat
+	for _, chat := range chats {
+		if chat.ID == ref {
+			return chat.ID, nil
+		}
+		tier := matchTier(ref, chat.Title)
+		if tier > worst {
+			tier = tierNone
+		}
+		switch {
+		case tier < best:
+			best, matches = tier, []api.Chat{chat}
+		case tier == best && tier != tierNone:
+			matches = append(matches, chat)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", &api.APIError{
+			Message:  fmt.Sprintf("no chat matches %q", ref),
+			Code:     "chat_not_found",
+			Category: api.CategoryNotFound,
+			Hint:     "Use 'beeper chats list' to see chat titles, or 'beeper alias set <name> <chat-id>' to name a chat.",
+		}
+	case 1:
+		return matches[0].ID, nil
+	}
+	return "", ambiguousError(ref, matches)
+}
+
+func ambiguousError(ref string, matches []api.Chat) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%q matches %d chats:", ref, len(matches))
+	for i, chat := range matches {
+		if i == maxCandidates {
+			fmt.Fprintf(&sb, "\n  ... and %d more", len(matches)-maxCandidates)
+			break
+		}
+		fmt.Fprintf(&sb, "\n  %s  %s", chat.ID, chat.Title)
+		if chat.Network != "" {
+			fmt.Fprintf(&sb, " (%s)", chat.Network)
+		}
+	}
+	return &api.APIError{
+		Message:  sb.String(),
+		Code:     "ambiguous_chat",
+		Category: api.CategoryValidation,
+		Hint:     "Use a longer part of the title, the chat ID, or 'beeper alias set <name> <chat-id>'.",
+	}
+}
+
+// IsChatID reports whether a reference has the shape of a Matrix room ID,
+// like "!abc123:beeper.local", and so needs no lookup
+func IsChatID(ref string) bool {
+	return strings.HasPrefix(ref, "!") && strings.Contains(ref, ":")
+}
+
+// matchTier ranks how well a reference matches a title
+func matchTier(ref, title string) int {
+	if title == "" {
+		return tierNone
+	}
+	if strings.EqualFold(ref, title) {
+		return tierExact
+	}
+
+	q, t := normalize(ref), normalize(title)
+	if q == "" || t == "" {
+		return tierNone
+	}
+	switch {
+	case q == t:
+		return tierNormalized
+	case strings.HasPrefix(t, q):
+		return tierPrefix
+	case wordPrefixes(strings.Fields(q), strings.Fields(t)):
+		return tierWordPrefix
+	case strings.Contains(t, q):
+		return tierSubstring
+	case subsequence(strings.ReplaceAll(q, " ", ""), strings.ReplaceAll(t, " ", "")):
+		return tierSubsequence
+	}
+	return tierNone
+}
+
+// normalize lowercases s and reduces punctuation and runs of spaces to
+// single spaces, so "Mom & Dad!" and "mom dad" compare equal
+func normalize(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// wordPrefixes reports whether each query word starts a distinct title
+// word, in order, as "fam grp" does for "family group chat"
+func wordPrefixes(query, title []string) bool {
+	i := 0
+	for _, word := range title {
+		if i < len(query) && strings.HasPrefix(word, query[i]) {
+			i++
+		}
+	}
+	return i == len(query)
+}
+
+// subsequence reports whether q's characters appear in t in order
+func subsequence(q, t string) bool {
+	tr := []rune(t)
+	i := 0
+	for _, r := range q {
+		for i < len(tr) && tr[i] != r {
+			i++
+		}
+		if i == len(tr) {
+			return false
+		}
+		i++
+	}
+	return true
+}
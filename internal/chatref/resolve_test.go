@@ -0,0 +1,125 @@
+package chatref
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+var testChats = []api.Chat{
+	{ID: "!family:beeper.local", Title: "Family Group", Network: "whatsapp"},
+	{ID: "!mom:beeper.local", Title: "Mom & Dad", Network: "imessage"},
+	{ID: "!eng:beeper.local", Title: "Eng Team", Network: "slack"},
+	{ID: "!eng2:beeper.local", Title: "Eng Team Alerts", Network: "slack"},
+	{ID: "!bob1:beeper.local", Title: "Bob", Network: "telegram"},
+	{ID: "!bob2:beeper.local", Title: "bob", Network: "signal"},
+	{ID: "local-id", Title: "Notes", Network: "beeper"},
+}
+
+func newTestResolver(aliases Aliases) (*Resolver, *int) {
+	calls := 0
+	return &Resolver{
+		Aliases: aliases,
+		Chats: func() ([]api.Chat, error) {
+			calls++
+			return testChats, nil
+		},
+	}, &calls
+}
+
+func TestResolve(t *testing.T) {
+	r, _ := newTestResolver(Aliases{"fam": "!family:beeper.local"})
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"fam", "!family:beeper.local"},
+		{"FAM", "!family:beeper.local"},
+		{"!unknown:beeper.local", "!unknown:beeper.local"},
+		{"local-id", "local-id"},
+		{"family group", "!family:beeper.local"},
+		{"Eng Team", "!eng:beeper.local"},
+		{"mom dad", "!mom:beeper.local"},
+		{"famil", "!family:beeper.local"},
+		{"fam grp", "!family:beeper.local"},
+		{"alerts", "!eng2:beeper.local"},
+		{"mmdd", "!mom:beeper.local"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := r.Resolve(tt.ref)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolve_SkipsLookupForAliasesAndIDs(t *testing.T) {
+	r, calls := newTestResolver(Aliases{"fam": "!family:beeper.local"})
+
+	_, err := r.Resolve("fam")
+	require.NoError(t, err)
+	_, err = r.Resolve("!abc:beeper.local")
+	require.NoError(t, err)
+	assert.Equal(t, 0, *calls)
+}
+
+func TestResolve_Ambiguous(t *testing.T) {
+	r, _ := newTestResolver(nil)
+
+	_, err := r.Resolve("bob")
+	var apiErr *api.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "ambiguous_chat", apiErr.Code)
+	assert.Equal(t, api.CategoryValidation, apiErr.Category)
+	assert.Contains(t, apiErr.Message, "!bob1:beeper.local  Bob (telegram)")
+	assert.Contains(t, apiErr.Message, "!bob2:beeper.local  bob (signal)")
+
+	// Both Eng titles start with "eng"; "Eng Team" itself resolved above
+	_, err = r.Resolve("eng")
+	require.True(t, errors.As(err, &apiErr))
+	assert.Contains(t, apiErr.Message, `"eng" matches 2 chats`)
+}
+
+func TestResolveTarget_RejectsSubsequence(t *testing.T) {
+	r, _ := newTestResolver(Aliases{"fam": "!family:beeper.local"})
+
+	for ref, want := range map[string]string{
+		"fam":                   "!family:beeper.local",
+		"!unknown:beeper.local": "!unknown:beeper.local",
+		"family group":          "!family:beeper.local",
+		"famil":                 "!family:beeper.local",
+		"alerts":                "!eng2:beeper.local",
+	} {
+		got, err := r.ResolveTarget(ref)
+		require.NoError(t, err, ref)
+		assert.Equal(t, want, got, ref)
+	}
+
+	_, err := r.ResolveTarget("mmdd")
+	var apiErr *api.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, api.CategoryNotFound, apiErr.Category)
+}
+
+func TestResolve_NotFound(t *testing.T) {
+	r, _ := newTestResolver(nil)
+
+	_, err := r.Resolve("nobody here")
+	var apiErr *api.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, api.CategoryNotFound, apiErr.Category)
+}
+
+func TestResolve_ListError(t *testing.T) {
+	r := &Resolver{Chats: func() ([]api.Chat, error) {
+		return nil, errors.New("offline")
+	}}
+	_, err := r.Resolve("family")
+	assert.EqualError(t, err, "offline")
+}
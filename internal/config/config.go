@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/spf13/viper"
 )
@@ -16,6 +17,11 @@ const (
 type Config struct {
 	APIURL       string `mapstructure:"api_url"`
 	OutputFormat string `mapstructure:"output_format"`
+	Envelope     bool   `mapstructure:"envelope"`
+
+	// envelopeSet records that Envelope was set explicitly, so it overrides
+	// the base config in Merge even when false
+	envelopeSet bool
 }
 
 // Load reads configuration from ~/.beeper-api-cli/config.yaml
@@ -55,6 +61,7 @@ func Load() (*Config, error) {
 func Save(cfg *Config) error {
 	viper.Set("api_url", cfg.APIURL)
 	viper.Set("output_format", cfg.OutputFormat)
+	viper.Set("envelope", cfg.Envelope)
 
 	if err := viper.WriteConfig(); err != nil {
 		// If config file doesn't exist yet, create it
@@ -96,6 +103,7 @@ func SaveConfig(configPath string, cfg *Config) error {
 
 	v.Set("api_url", cfg.APIURL)
 	v.Set("output_format", cfg.OutputFormat)
+	v.Set("envelope", cfg.Envelope)
 
 	// Create parent directory if it doesn't exist
 	dir := filepath.Dir(configPath)
@@ -118,23 +126,14 @@ func DefaultConfig() *Config {
 	}
 }
 
-// Validate checks if the configuration is valid
-func (c *Config) Validate() error {
+// Validate checks if the configuration is valid. Output formats are
+// registered by the output package, so validateFormat checks the format name.
+func (c *Config) Validate(validateFormat func(string) error) error {
 	if c.APIURL == "" {
 		return fmt.Errorf("api_url cannot be empty")
 	}
 
-	validFormats := map[string]bool{
-		"json":     true,
-		"text":     true,
-		"markdown": true,
-	}
-
-	if !validFormats[c.OutputFormat] {
-		return fmt.Errorf("invalid output format: %s (must be json, text, or markdown)", c.OutputFormat)
-	}
-
-	return nil
+	return validateFormat(c.OutputFormat)
 }
 
 // Merge merges override config into base config (non-empty values from override take precedence)
@@ -142,6 +141,10 @@ func (c *Config) Merge(override *Config) *Config {
 	merged := &Config{
 		APIURL:       c.APIURL,
 		OutputFormat: c.OutputFormat,
+		Envelope:     c.Envelope || override.Envelope,
+	}
+	if override.envelopeSet {
+		merged.Envelope = override.Envelope
 	}
 
 	if override.APIURL != "" {
@@ -173,6 +176,10 @@ func LoadFromEnv() *Config {
 	if format := os.Getenv("BEEPER_OUTPUT_FORMAT"); format != "" {
 		cfg.OutputFormat = format
 	}
+	if envelope, err := strconv.ParseBool(os.Getenv("BEEPER_ENVELOPE")); err == nil {
+		cfg.Envelope = envelope
+		cfg.envelopeSet = true
+	}
 
 	return cfg
 }
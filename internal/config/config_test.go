@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -93,6 +94,30 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			shouldErr: false,
 		},
+		{
+			name: "Table output format",
+			config: &Config{
+				APIURL:       "http://localhost:8080",
+				OutputFormat: "table",
+			},
+			shouldErr: false,
+		},
+		{
+			name: "CSV output format",
+			config: &Config{
+				APIURL:       "http://localhost:8080",
+				OutputFormat: "csv",
+			},
+			shouldErr: false,
+		},
+		{
+			name: "YAML output format",
+			config: &Config{
+				APIURL:       "http://localhost:8080",
+				OutputFormat: "yaml",
+			},
+			shouldErr: false,
+		},
 		{
 			name: "Invalid output format",
 			config: &Config{
@@ -113,7 +138,7 @@ func TestConfig_Validate(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := tc.config.Validate()
+			err := tc.config.Validate(validateFormat)
 			if tc.shouldErr {
 				assert.Error(t, err)
 			} else {
@@ -123,6 +148,15 @@ func TestConfig_Validate(t *testing.T) {
 	}
 }
 
+// validateFormat stands in for output.ValidateFormat
+func validateFormat(name string) error {
+	switch name {
+	case "json", "table", "csv", "yaml":
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q", name)
+}
+
 // TestGetConfigPath tests default config path resolution
 func TestGetConfigPath(t *testing.T) {
 	path := GetConfigPath()
@@ -167,6 +201,31 @@ func TestConfig_EnvOverride(t *testing.T) {
 	assert.Equal(t, "text", cfg.OutputFormat)
 }
 
+// TestConfig_Envelope tests the envelope setting from env and saved config
+func TestConfig_Envelope(t *testing.T) {
+	t.Setenv("BEEPER_ENVELOPE", "true")
+	assert.True(t, LoadFromEnv().Envelope)
+
+	t.Setenv("BEEPER_ENVELOPE", "")
+	assert.False(t, LoadFromEnv().Envelope)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := DefaultConfig()
+	cfg.Envelope = true
+	require.NoError(t, SaveConfig(configPath, cfg))
+
+	loaded, err := LoadConfig(configPath)
+	require.NoError(t, err)
+	assert.True(t, loaded.Envelope)
+	assert.True(t, DefaultConfig().Merge(loaded).Envelope)
+
+	// An explicit false in the environment turns the saved setting off
+	t.Setenv("BEEPER_ENVELOPE", "false")
+	assert.False(t, loaded.Merge(LoadFromEnv()).Envelope)
+	t.Setenv("BEEPER_ENVELOPE", "")
+	assert.True(t, loaded.Merge(LoadFromEnv()).Envelope)
+}
+
 // TestConfig_PartialSave tests saving partial configuration
 func TestConfig_PartialSave(t *testing.T) {
 	tmpDir := t.TempDir()
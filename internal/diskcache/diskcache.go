@@ -0,0 +1,103 @@
+// Package diskcache keeps short-lived API results in JSON files, so commands
+// run in quick succession (or shell completion) don't refetch them.
+package diskcache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache holds one value of type T in a file
+type Cache[T any] struct {
+	Path string
+	// Key identifies where the value came from, e.g. the API URL. A value
+	// cached under another key is never returned.
+	Key string
+	TTL time.Duration
+	// Now returns the current time (time.Now if nil)
+	Now func() time.Time
+}
+
+// entry is the on-disk form of a cached value
+type entry[T any] struct {
+	Key      string    `json:"key"`
+	StoredAt time.Time `json:"storedAt"`
+	Value    T         `json:"value"`
+}
+
+func (c *Cache[T]) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Get returns the cached value if it is fresh, otherwise fetches and caches
+// a new one. Failing to write the cache is not an error.
+func (c *Cache[T]) Get(fetch func() (T, error)) (T, error) {
+	if value, ok := c.load(false); ok {
+		return value, nil
+	}
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+	_ = c.Store(value)
+	return value, nil
+}
+
+// Stale returns the cached value however old it is, for callers that
+// prefer an outdated answer to none
+func (c *Cache[T]) Stale() (T, bool) {
+	return c.load(true)
+}
+
+func (c *Cache[T]) load(anyAge bool) (T, bool) {
+	var e entry[T]
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return e.Value, false
+	}
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != c.Key {
+		var zero T
+		return zero, false
+	}
+	if age := c.now().Sub(e.StoredAt); !anyAge && (age < 0 || age > c.TTL) {
+		var zero T
+		return zero, false
+	}
+	return e.Value, true
+}
+
+// Store writes the value through a temporary file, so readers never see a
+// partial cache
+func (c *Cache[T]) Store(value T) error {
+	data, err := json.Marshal(entry[T]{Key: c.Key, StoredAt: c.now(), Value: value})
+	if err != nil {
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+
+	dir := filepath.Dir(c.Path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".cache-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.Path); err != nil {
+		return fmt.Errorf("failed to write cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package diskcache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := &Cache[[]string]{
+		Path: filepath.Join(t.TempDir(), "cache", "items.json"),
+		Key:  "http://localhost:39867",
+		TTL:  time.Minute,
+		Now:  func() time.Time { return now },
+	}
+
+	fetches := 0
+	fetch := func() ([]string, error) {
+		fetches++
+		return []string{"a", "b"}, nil
+	}
+
+	got, err := cache.Get(fetch)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+	assert.Equal(t, 1, fetches)
+
+	now = now.Add(30 * time.Second)
+	got, err = cache.Get(fetch)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, got)
+	assert.Equal(t, 1, fetches)
+
+	now = now.Add(time.Minute)
+	_, err = cache.Get(fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 2, fetches)
+
+	cache.Key = "http://other:39867"
+	_, err = cache.Get(fetch)
+	require.NoError(t, err)
+	assert.Equal(t, 3, fetches)
+}
+
+func TestCache_Stale(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cache := &Cache[int]{
+		Path: filepath.Join(t.TempDir(), "n.json"),
+		Key:  "k",
+		TTL:  time.Second,
+		Now:  func() time.Time { return now },
+	}
+
+	_, ok := cache.Stale()
+	assert.False(t, ok)
+
+	require.NoError(t, cache.Store(42))
+	now = now.Add(time.Hour)
+
+	_, err := cache.Get(func() (int, error) { return 0, errors.New("slow") })
+	assert.EqualError(t, err, "slow")
+	n, ok := cache.Stale()
+	assert.True(t, ok)
+	assert.Equal(t, 42, n)
+}
+
+func TestCache_IgnoresCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "n.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0600))
+	cache := &Cache[int]{Path: path, TTL: time.Minute}
+
+	n, err := cache.Get(func() (int, error) { return 7, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+}
@@ -0,0 +1,512 @@
+// Package export archives chats, their complete message history and their
+// attachments to a directory, resuming where the previous run stopped.
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+)
+
+// File names within each chat directory
+const (
+	ChatFile       = "chat.json"
+	MessagesFile   = "messages.ndjson"
+	MboxFile       = "messages.mbox"
+	AttachmentsDir = output.DefaultAttachmentsDir
+)
+
+// Message file formats
+const (
+	// FormatNDJSON writes one JSON message per line to messages.ndjson
+	FormatNDJSON = "ndjson"
+	// FormatMbox writes each message as an RFC 5322 email to messages.mbox
+	FormatMbox = "mbox"
+)
+
+// DefaultPageSize is the number of messages requested per page
+const DefaultPageSize = 100
+
+const timeLayout = time.RFC3339
+
+// now returns the current time; tests replace it
+var now = time.Now
+
+// Source is the part of the API client an export reads from
+type Source interface {
+	ListChatsPage(cursor string) (*api.ChatsResponse, error)
+	GetChat(chatID string) (*api.Chat, error)
+	ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error)
+	DownloadAsset(srcURL string) (io.ReadCloser, error)
+}
+
+// Options selects what to export and where
+type Options struct {
+	// Dir is the export directory, created if needed
+	Dir string
+	// ChatIDs limits the export to these chats (empty exports every chat)
+	ChatIDs []string
+	// Network limits the export to chats on this network, case-insensitively
+	Network string
+	// Since skips messages sent before it (zero exports the full history)
+	Since time.Time
+	// PageSize is the number of messages requested per page
+	PageSize int
+	// SkipAttachments leaves attachments out of the export
+	SkipAttachments bool
+	// Format is FormatNDJSON (the default) or FormatMbox
+	Format string
+	// Progress, if set, is called after each chat is exported
+	Progress func(ChatResult)
+}
+
+// ChatResult reports the export of one chat
+type ChatResult struct {
+	ChatID      string `json:"chat_id"`
+	Title       string `json:"title"`
+	Dir         string `json:"dir"`
+	NewMessages int    `json:"new_messages"`
+	Attachments int    `json:"attachments"`
+	// AttachmentErrors counts attachments that could not be downloaded.
+	// They are recorded in the manifest and retried on the next run.
+	AttachmentErrors int    `json:"attachment_errors,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// Summary reports a complete export run
+type Summary struct {
+	Dir   string       `json:"dir"`
+	Chats []ChatResult `json:"chats"`
+}
+
+// Failed returns the number of chats that could not be exported
+func (s *Summary) Failed() int {
+	failed := 0
+	for _, c := range s.Chats {
+		if c.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Run exports the selected chats. A failure in one chat is recorded in its
+// result and the export moves on to the next chat; only problems with the
+// export directory or the chat list abort the run.
+func Run(src Source, opts Options) (*Summary, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+	if opts.Format == "" {
+		opts.Format = FormatNDJSON
+	}
+	if opts.Format != FormatNDJSON && opts.Format != FormatMbox {
+		return nil, fmt.Errorf("invalid export format: %s (available: %s, %s)", opts.Format, FormatNDJSON, FormatMbox)
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	manifest, err := LoadManifest(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	// Checkpoints are byte offsets into one message file, so a directory
+	// keeps the format it was created with
+	if len(manifest.Chats) > 0 && manifest.format() != opts.Format {
+		return nil, fmt.Errorf("export directory %s holds %s exports; use --format %s or a new directory", opts.Dir, manifest.format(), manifest.format())
+	}
+	manifest.Format = opts.Format
+
+	chats, err := selectChats(src, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &Summary{Dir: opts.Dir}
+	for _, chat := range chats {
+		result := exportChat(src, manifest, chat, opts)
+		summary.Chats = append(summary.Chats, result)
+		if opts.Progress != nil {
+			opts.Progress(result)
+		}
+	}
+	return summary, nil
+}
+
+// selectChats returns the chats matching the chat ID and network filters
+func selectChats(src Source, opts Options) ([]api.Chat, error) {
+	var chats []api.Chat
+	if len(opts.ChatIDs) > 0 {
+		for _, id := range opts.ChatIDs {
+			chat, err := src.GetChat(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get chat %s: %w", id, err)
+			}
+			chats = append(chats, *chat)
+		}
+	} else {
+		cursor := ""
+		for {
+			page, err := src.ListChatsPage(cursor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list chats: %w", err)
+			}
+			chats = append(chats, page.Items...)
+			if !page.HasMore || page.OldestCursor == "" || page.OldestCursor == cursor {
+				break
+			}
+			cursor = page.OldestCursor
+		}
+	}
+
+	if opts.Network == "" {
+		return chats, nil
+	}
+	selected := chats[:0]
+	for _, chat := range chats {
+		if strings.EqualFold(chat.Network, opts.Network) {
+			selected = append(selected, chat)
+		}
+	}
+	return selected, nil
+}
+
+var unsafeDirChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// ChatDirName returns the directory a chat is exported to, derived from its
+// ID so it never changes when the chat is renamed. A short hash of the raw
+// ID keeps IDs that sanitize alike, or differ only in case, apart.
+func ChatDirName(chatID string) string {
+	name := strings.Trim(unsafeDirChars.ReplaceAllString(chatID, "_"), "_.")
+	if name == "" {
+		name = "chat"
+	}
+	sum := sha256.Sum256([]byte(chatID))
+	return "chats/" + name + "-" + hex.EncodeToString(sum[:4])
+}
+
+// exportChat brings one chat's directory up to date
+func exportChat(src Source, manifest *Manifest, chat api.Chat, opts Options) ChatResult {
+	state, ok := manifest.Chats[chat.ID]
+	if !ok {
+		state = &ChatState{Dir: ChatDirName(chat.ID)}
+		manifest.Chats[chat.ID] = state
+	}
+	state.Title = chat.Title
+	state.Network = chat.Network
+
+	result := ChatResult{ChatID: chat.ID, Title: chat.Title, Dir: state.Dir}
+	dir := filepath.Join(opts.Dir, filepath.FromSlash(state.Dir))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		result.Error = fmt.Sprintf("failed to create chat directory: %v", err)
+		return result
+	}
+
+	data, err := json.MarshalIndent(chat, "", "  ")
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to marshal chat: %v", err)
+		return result
+	}
+	if err := writeFileAtomic(filepath.Join(dir, ChatFile), append(data, '\n')); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	exp := &chatExporter{src: src, manifest: manifest, state: state, chat: chat, dir: dir, opts: opts, result: &result}
+	if err := exp.run(); err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// chatExporter pages through one chat's messages
+type chatExporter struct {
+	src      Source
+	manifest *Manifest
+	state    *ChatState
+	chat     api.Chat
+	dir      string
+	opts     Options
+	result   *ChatResult
+}
+
+// run completes any interrupted pass, then runs passes until one starting
+// from the newest message completes, so messages that arrived while an
+// interrupted pass was being resumed are picked up too
+func (e *chatExporter) run() error {
+	if err := e.retryAttachments(); err != nil {
+		return err
+	}
+	for {
+		resumed := e.state.Pending != nil && e.state.Pending.Cursor != ""
+		if e.state.Pending == nil {
+			e.state.Pending = &PassState{After: e.state.LastSortKey}
+		}
+		if err := e.pass(); err != nil {
+			return err
+		}
+		if !resumed {
+			return nil
+		}
+	}
+}
+
+// pass pages back from the pass cursor until it reaches the last exported
+// message, the --since cut-off or the start of the history. Each page is
+// appended to messages.ndjson and checkpointed in the manifest.
+func (e *chatExporter) pass() error {
+	p := e.state.Pending
+	for {
+		page, err := e.src.ListMessagesPage(e.chat.ID, p.Cursor, e.opts.PageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+
+		if p.TopSortKey == "" && len(page.Items) > 0 {
+			top := page.Items[0]
+			p.TopSortKey, p.TopMessageID, p.TopTimestamp = top.SortKey, top.ID, top.Timestamp
+		}
+
+		fresh := e.newMessages(page.Items)
+		if err := e.appendMessages(fresh); err != nil {
+			return err
+		}
+
+		next := page.NextCursor()
+		done := len(fresh) < len(page.Items) || !page.HasMore || next == "" || next == p.Cursor
+		p.Cursor = next
+		if done {
+			e.finishPass()
+		}
+		if err := e.manifest.Save(e.opts.Dir); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// newMessages returns the leading messages of a newest-first page that are
+// newer than the pass bound and not before --since
+func (e *chatExporter) newMessages(items []api.Message) []api.Message {
+	bound := e.state.Pending.After
+	for i, msg := range items {
+		if bound != "" && api.CompareSortKeys(msg.SortKey, bound) <= 0 {
+			return items[:i]
+		}
+		if !e.opts.Since.IsZero() {
+			if t, ok := msg.Time(); ok && t.Before(e.opts.Since) {
+				return items[:i]
+			}
+		}
+	}
+	return items
+}
+
+// finishPass records the pass's newest message as the last exported one
+func (e *chatExporter) finishPass() {
+	p := e.state.Pending
+	if p.TopSortKey != "" && (e.state.LastSortKey == "" || api.CompareSortKeys(p.TopSortKey, e.state.LastSortKey) > 0) {
+		e.state.LastSortKey = p.TopSortKey
+		e.state.LastMessageID = p.TopMessageID
+		e.state.LastTimestamp = p.TopTimestamp
+	}
+	e.state.ExportedAt = now().UTC().Format(timeLayout)
+	e.state.Pending = nil
+}
+
+// appendMessages downloads the messages' attachments and appends them to
+// the chat's message file. The file is first cut back to its size at the
+// last checkpoint, dropping anything written by an interrupted run.
+func (e *chatExporter) appendMessages(messages []api.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+
+	if !e.opts.SkipAttachments {
+		for _, msg := range messages {
+			e.downloadAttachments(msg)
+		}
+	}
+
+	name := MessagesFile
+	if e.opts.Format == FormatMbox {
+		name = MboxFile
+	}
+	f, err := os.OpenFile(filepath.Join(e.dir, name), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer f.Close()
+
+	if err := f.Truncate(e.state.MessagesBytes); err != nil {
+		return fmt.Errorf("failed to truncate %s: %w", name, err)
+	}
+	if _, err := f.Seek(e.state.MessagesBytes, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek %s: %w", name, err)
+	}
+
+	var buf strings.Builder
+	if err := e.encodeMessages(&buf, messages); err != nil {
+		return err
+	}
+	n, err := io.WriteString(f, buf.String())
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	e.state.MessagesBytes += int64(n)
+	e.state.MessageCount += len(messages)
+	e.result.NewMessages += len(messages)
+	return nil
+}
+
+// encodeMessages renders messages in the export format
+func (e *chatExporter) encodeMessages(w io.Writer, messages []api.Message) error {
+	if e.opts.Format == FormatMbox {
+		enc := &mailEncoder{chat: e.chat}
+		if !e.opts.SkipAttachments {
+			enc.attachmentsDir = filepath.Join(e.dir, AttachmentsDir)
+		}
+		for _, msg := range messages {
+			if err := enc.encode(w, msg); err != nil {
+				return fmt.Errorf("failed to encode message %s: %w", msg.ID, err)
+			}
+		}
+		return nil
+	}
+
+	enc := json.NewEncoder(w)
+	for _, msg := range messages {
+		if err := enc.Encode(msg); err != nil {
+			return fmt.Errorf("failed to marshal message %s: %w", msg.ID, err)
+		}
+	}
+	return nil
+}
+
+// downloadAttachments saves a message's attachments under the chat's
+// attachments directory, named as HTML transcripts link them. Files that
+// already exist are kept, so reruns only fetch what is missing. Failed
+// downloads are recorded in the chat's state for the next run to retry.
+func (e *chatExporter) downloadAttachments(msg api.Message) {
+	for i, att := range msg.Attachments {
+		if att.SrcURL == "" {
+			continue
+		}
+		name := output.AttachmentFileName(msg, i)
+		path := filepath.Join(e.dir, AttachmentsDir, name)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := e.download(att.SrcURL, path); err != nil {
+			e.result.AttachmentErrors++
+			failed := FailedAttachment{MessageID: msg.ID, SrcURL: att.SrcURL, File: name}
+			if !slices.Contains(e.state.FailedAttachments, failed) {
+				e.state.FailedAttachments = append(e.state.FailedAttachments, failed)
+			}
+			continue
+		}
+		e.result.Attachments++
+	}
+}
+
+// retryAttachments downloads the attachments that failed in earlier runs,
+// keeping those that fail again for the next run. Messages already written
+// to an mbox file are not rewritten, so a retried attachment is only saved
+// under the attachments directory.
+func (e *chatExporter) retryAttachments() error {
+	if e.opts.SkipAttachments || len(e.state.FailedAttachments) == 0 {
+		return nil
+	}
+	var still []FailedAttachment
+	for _, failed := range e.state.FailedAttachments {
+		path := filepath.Join(e.dir, AttachmentsDir, failed.File)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		if err := e.download(failed.SrcURL, path); err != nil {
+			e.result.AttachmentErrors++
+			still = append(still, failed)
+			continue
+		}
+		e.result.Attachments++
+	}
+	e.state.FailedAttachments = still
+	return e.manifest.Save(e.opts.Dir)
+}
+
+// download copies an asset to path, via a temporary file so a partial
+// download is never mistaken for a complete one
+func (e *chatExporter) download(srcURL, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	body, err := e.src.DownloadAsset(srcURL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	tmp := path + ".part"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ParseSince parses a --since value: a duration back from now such as "30d",
+// "2w" or "12h", a date (2006-01-02, local time) or an RFC 3339 timestamp
+func ParseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+
+	if n := len(value); n > 1 {
+		if count, err := strconv.Atoi(value[:n-1]); err == nil && count >= 0 {
+			switch value[n-1] {
+			case 'd':
+				return now().AddDate(0, 0, -count), nil
+			case 'w':
+				return now().AddDate(0, 0, -7*count), nil
+			}
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value: %s (use a duration such as 30d or 12h, a date such as 2024-01-31, or an RFC 3339 timestamp)", value)
+}
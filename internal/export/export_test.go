@@ -0,0 +1,306 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource serves chats and newest-first message pages from memory
+type fakeSource struct {
+	chats    []api.Chat
+	messages map[string][]api.Message // oldest first
+	assets   map[string]string
+	// failAfter makes ListMessagesPage fail once this many pages were served
+	failAfter int
+	served    int
+}
+
+func (f *fakeSource) ListChatsPage(cursor string) (*api.ChatsResponse, error) {
+	return &api.ChatsResponse{Items: f.chats}, nil
+}
+
+func (f *fakeSource) GetChat(chatID string) (*api.Chat, error) {
+	for _, c := range f.chats {
+		if c.ID == chatID {
+			return &c, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeSource) ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error) {
+	if f.failAfter > 0 && f.served >= f.failAfter {
+		return nil, errors.New("connection reset")
+	}
+	f.served++
+
+	all := f.messages[chatID]
+	end := len(all)
+	if cursor != "" {
+		end = 0
+		for i, m := range all {
+			if m.SortKey == cursor {
+				end = i
+			}
+		}
+	}
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+	var page []api.Message
+	for i := end - 1; i >= start; i-- {
+		page = append(page, all[i])
+	}
+	return &api.MessagesResponse{Items: page, HasMore: start > 0}, nil
+}
+
+func (f *fakeSource) DownloadAsset(srcURL string) (io.ReadCloser, error) {
+	content, ok := f.assets[srcURL]
+	if !ok {
+		return nil, errors.New("asset not found")
+	}
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+// history returns n messages one minute apart with sort keys 1..n
+func history(chatID string, from, n int) []api.Message {
+	var msgs []api.Message
+	for i := from; i < from+n; i++ {
+		msgs = append(msgs, api.Message{
+			ID:        fmt.Sprintf("m%d", i),
+			ChatID:    chatID,
+			SortKey:   strconv.Itoa(i),
+			Text:      fmt.Sprintf("message %d", i),
+			Timestamp: time.Date(2024, 1, 1, 0, i, 0, 0, time.UTC).Format(time.RFC3339),
+		})
+	}
+	return msgs
+}
+
+// exportedIDs reads the message IDs of a chat's messages.ndjson
+func exportedIDs(t *testing.T, dir, chatID string) []string {
+	t.Helper()
+	f, err := os.Open(filepath.Join(dir, filepath.FromSlash(ChatDirName(chatID)), MessagesFile))
+	require.NoError(t, err)
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg api.Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		ids = append(ids, msg.ID)
+	}
+	require.NoError(t, scanner.Err())
+	return ids
+}
+
+func TestRunExportsAndResumesIncrementally(t *testing.T) {
+	dir := t.TempDir()
+	src := &fakeSource{
+		chats:    []api.Chat{{ID: "!chat:beeper", Title: "Team", Network: "slack"}},
+		messages: map[string][]api.Message{"!chat:beeper": history("!chat:beeper", 1, 5)},
+	}
+
+	summary, err := Run(src, Options{Dir: dir, PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, summary.Chats, 1)
+	assert.Equal(t, 5, summary.Chats[0].NewMessages)
+	assert.Equal(t, []string{"m5", "m4", "m3", "m2", "m1"}, exportedIDs(t, dir, "!chat:beeper"))
+	assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(ChatDirName("!chat:beeper")), ChatFile))
+
+	manifest, err := LoadManifest(dir)
+	require.NoError(t, err)
+	state := manifest.Chats["!chat:beeper"]
+	assert.Equal(t, "5", state.LastSortKey)
+	assert.Equal(t, "m5", state.LastMessageID)
+	assert.Equal(t, 5, state.MessageCount)
+	assert.Nil(t, state.Pending)
+
+	// A second run only fetches the new messages
+	src.messages["!chat:beeper"] = append(src.messages["!chat:beeper"], history("!chat:beeper", 6, 3)...)
+	summary, err = Run(src, Options{Dir: dir, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Chats[0].NewMessages)
+	assert.Equal(t, []string{"m5", "m4", "m3", "m2", "m1", "m8", "m7", "m6"}, exportedIDs(t, dir, "!chat:beeper"))
+
+	// Nothing new, nothing written
+	summary, err = Run(src, Options{Dir: dir, PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 0, summary.Chats[0].NewMessages)
+}
+
+func TestRunResumesAfterInterruption(t *testing.T) {
+	dir := t.TempDir()
+	src := &fakeSource{
+		chats:     []api.Chat{{ID: "c1", Title: "Team"}},
+		messages:  map[string][]api.Message{"c1": history("c1", 1, 6)},
+		failAfter: 2,
+	}
+
+	summary, err := Run(src, Options{Dir: dir, PageSize: 2})
+	require.NoError(t, err)
+	assert.Contains(t, summary.Chats[0].Error, "connection reset")
+	assert.Equal(t, 1, summary.Failed())
+
+	manifest, err := LoadManifest(dir)
+	require.NoError(t, err)
+	require.NotNil(t, manifest.Chats["c1"].Pending)
+	assert.Equal(t, "3", manifest.Chats["c1"].Pending.Cursor)
+
+	// Simulate a crash between writing a page and checkpointing it
+	f, err := os.OpenFile(filepath.Join(dir, filepath.FromSlash(ChatDirName("c1")), MessagesFile), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"id":"half-writ`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// New messages arrive while the export is interrupted
+	src.messages["c1"] = append(src.messages["c1"], history("c1", 7, 1)...)
+	src.failAfter = 0
+	summary, err = Run(src, Options{Dir: dir, PageSize: 2})
+	require.NoError(t, err)
+	assert.Empty(t, summary.Chats[0].Error)
+	assert.Equal(t, []string{"m6", "m5", "m4", "m3", "m2", "m1", "m7"}, exportedIDs(t, dir, "c1"))
+
+	manifest, err = LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "7", manifest.Chats["c1"].LastSortKey)
+	assert.Nil(t, manifest.Chats["c1"].Pending)
+}
+
+func TestRunFilters(t *testing.T) {
+	dir := t.TempDir()
+	src := &fakeSource{
+		chats: []api.Chat{
+			{ID: "wa", Network: "WhatsApp"},
+			{ID: "sig", Network: "signal"},
+		},
+		messages: map[string][]api.Message{
+			"wa":  history("wa", 1, 10),
+			"sig": history("sig", 1, 2),
+		},
+	}
+
+	since := time.Date(2024, 1, 1, 0, 8, 0, 0, time.UTC)
+	summary, err := Run(src, Options{Dir: dir, Network: "whatsapp", Since: since})
+	require.NoError(t, err)
+	require.Len(t, summary.Chats, 1)
+	assert.Equal(t, []string{"m10", "m9", "m8"}, exportedIDs(t, dir, "wa"))
+
+	summary, err = Run(src, Options{Dir: dir, ChatIDs: []string{"sig"}})
+	require.NoError(t, err)
+	require.Len(t, summary.Chats, 1)
+	assert.Equal(t, "sig", summary.Chats[0].ChatID)
+
+	_, err = Run(src, Options{Dir: dir, ChatIDs: []string{"missing"}})
+	assert.ErrorContains(t, err, "failed to get chat missing")
+}
+
+func TestRunDownloadsAttachments(t *testing.T) {
+	dir := t.TempDir()
+	msgs := history("c1", 1, 1)
+	msgs[0].Attachments = []api.Attachment{
+		{SrcURL: "file:///photo.jpg", FileName: "photo.jpg"},
+		{SrcURL: "mxc://gone", FileName: "gone.pdf"},
+	}
+	src := &fakeSource{
+		chats:    []api.Chat{{ID: "c1"}},
+		messages: map[string][]api.Message{"c1": msgs},
+		assets:   map[string]string{"file:///photo.jpg": "JPEG"},
+	}
+
+	summary, err := Run(src, Options{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Chats[0].Attachments)
+	assert.Equal(t, 1, summary.Chats[0].AttachmentErrors)
+
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ChatDirName("c1")), AttachmentsDir, "m1_0_photo.jpg"))
+	require.NoError(t, err)
+	assert.Equal(t, "JPEG", string(data))
+
+	_, err = Run(src, Options{Dir: dir, SkipAttachments: true})
+	require.NoError(t, err)
+}
+
+func TestRunRetriesFailedAttachments(t *testing.T) {
+	dir := t.TempDir()
+	msgs := history("c1", 1, 1)
+	msgs[0].Attachments = []api.Attachment{{SrcURL: "mxc://slow", FileName: "report.pdf"}}
+	src := &fakeSource{
+		chats:    []api.Chat{{ID: "c1"}},
+		messages: map[string][]api.Message{"c1": msgs},
+		assets:   map[string]string{},
+	}
+
+	summary, err := Run(src, Options{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Chats[0].AttachmentErrors)
+	manifest, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []FailedAttachment{{MessageID: "m1", SrcURL: "mxc://slow", File: "m1_0_report.pdf"}},
+		manifest.Chats["c1"].FailedAttachments)
+
+	// The next run has no new messages but fetches the attachment again
+	src.assets["mxc://slow"] = "PDF"
+	summary, err = Run(src, Options{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, 0, summary.Chats[0].NewMessages)
+	assert.Equal(t, 1, summary.Chats[0].Attachments)
+	assert.Equal(t, 0, summary.Chats[0].AttachmentErrors)
+
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ChatDirName("c1")), AttachmentsDir, "m1_0_report.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, "PDF", string(data))
+	manifest, err = LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Empty(t, manifest.Chats["c1"].FailedAttachments)
+}
+
+func TestParseSince(t *testing.T) {
+	ref := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	saved := now
+	now = func() time.Time { return ref }
+	defer func() { now = saved }()
+
+	tests := map[string]time.Time{
+		"":                     {},
+		"30d":                  ref.AddDate(0, 0, -30),
+		"2w":                   ref.AddDate(0, 0, -14),
+		"12h":                  ref.Add(-12 * time.Hour),
+		"2024-01-31T08:00:00Z": time.Date(2024, 1, 31, 8, 0, 0, 0, time.UTC),
+		"2024-01-31":           time.Date(2024, 1, 31, 0, 0, 0, 0, time.Local),
+	}
+	for value, want := range tests {
+		got, err := ParseSince(value)
+		require.NoError(t, err, value)
+		assert.True(t, want.Equal(got), "%s: got %s, want %s", value, got, want)
+	}
+
+	_, err := ParseSince("last tuesday")
+	assert.ErrorContains(t, err, "invalid --since value")
+}
+
+func TestChatDirName(t *testing.T) {
+	assert.Regexp(t, `^chats/abc_beeper\.local-[0-9a-f]{8}$`, ChatDirName("!abc:beeper.local"))
+	assert.Regexp(t, `^chats/chat-[0-9a-f]{8}$`, ChatDirName("!!"))
+	assert.Equal(t, ChatDirName("!abc:beeper.local"), ChatDirName("!abc:beeper.local"))
+
+	// IDs that sanitize to the same name still get their own directories
+	assert.NotEqual(t, ChatDirName("!a:b.c"), ChatDirName("!a_b.c"))
+	assert.NotEqual(t, ChatDirName("!ABC"), ChatDirName("!abc"))
+}
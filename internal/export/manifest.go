@@ -0,0 +1,139 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ManifestFile is the name of the manifest in the export directory
+const ManifestFile = "manifest.json"
+
+// manifestVersion is bumped when the manifest layout changes incompatibly
+const manifestVersion = 1
+
+// Manifest records what has been exported so far, so later runs only fetch
+// new messages and interrupted runs can resume
+type Manifest struct {
+	Version   int                   `json:"version"`
+	UpdatedAt string                `json:"updatedAt,omitempty"`
+	Format    string                `json:"format,omitempty"` // empty before mbox support, meaning ndjson
+	Chats     map[string]*ChatState `json:"chats"`
+}
+
+// format returns the manifest's message file format
+func (m *Manifest) format() string {
+	if m.Format == "" {
+		return FormatNDJSON
+	}
+	return m.Format
+}
+
+// ChatState is the export state of one chat
+type ChatState struct {
+	Title   string `json:"title"`
+	Network string `json:"network"`
+	// Dir is the chat's directory, relative to the export directory
+	Dir string `json:"dir"`
+	// LastSortKey, LastMessageID and LastTimestamp identify the newest
+	// message exported by the last completed pass
+	LastSortKey   string `json:"lastSortKey,omitempty"`
+	LastMessageID string `json:"lastMessageID,omitempty"`
+	LastTimestamp string `json:"lastTimestamp,omitempty"`
+	MessageCount  int    `json:"messageCount"`
+	// MessagesBytes is the size of the message file at the last checkpoint.
+	// Anything written after it is discarded on resume.
+	MessagesBytes int64  `json:"messagesBytes"`
+	ExportedAt    string `json:"exportedAt,omitempty"`
+	// Pending is set while a pass through the chat's history is in progress
+	Pending *PassState `json:"pending,omitempty"`
+	// FailedAttachments are attachments that could not be downloaded, retried
+	// at the start of every run until they succeed
+	FailedAttachments []FailedAttachment `json:"failedAttachments,omitempty"`
+}
+
+// FailedAttachment is an attachment whose download failed
+type FailedAttachment struct {
+	MessageID string `json:"messageID"`
+	SrcURL    string `json:"srcURL"`
+	// File is the attachment's name within the attachments directory
+	File string `json:"file"`
+}
+
+// PassState is a checkpoint within one pass, which pages from the newest
+// message back to the last exported one
+type PassState struct {
+	// After is the sort key of the newest message exported before the pass
+	After string `json:"after,omitempty"`
+	// Cursor continues the pass where it was interrupted
+	Cursor string `json:"cursor,omitempty"`
+	// Top is the newest message seen by the pass, which becomes the chat's
+	// last exported message once the pass completes
+	TopSortKey   string `json:"topSortKey,omitempty"`
+	TopMessageID string `json:"topMessageID,omitempty"`
+	TopTimestamp string `json:"topTimestamp,omitempty"`
+}
+
+// LoadManifest reads the manifest from dir. A missing manifest returns an
+// empty one.
+func LoadManifest(dir string) (*Manifest, error) {
+	m := &Manifest{Version: manifestVersion, Chats: make(map[string]*ChatState)}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if m.Version > manifestVersion {
+		return nil, fmt.Errorf("manifest version %d is newer than this CLI supports (%d); upgrade beeper", m.Version, manifestVersion)
+	}
+	if m.Chats == nil {
+		m.Chats = make(map[string]*ChatState)
+	}
+	return m, nil
+}
+
+// Save writes the manifest to dir. It is written to a temporary file and
+// renamed into place, so an interruption never leaves a truncated manifest.
+func (m *Manifest) Save(dir string) error {
+	m.Version = manifestVersion
+	m.UpdatedAt = now().UTC().Format(timeLayout)
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	return writeFileAtomic(filepath.Join(dir, ManifestFile), append(data, '\n'))
+}
+
+// writeFileAtomic replaces path with data via a temporary file and rename
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
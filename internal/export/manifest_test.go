@@ -0,0 +1,40 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Empty(t, m.Chats)
+
+	m.Chats["c1"] = &ChatState{Dir: "chats/c1", LastSortKey: "42", Pending: &PassState{Cursor: "7"}}
+	require.NoError(t, m.Save(dir))
+
+	loaded, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "42", loaded.Chats["c1"].LastSortKey)
+	assert.Equal(t, "7", loaded.Chats["c1"].Pending.Cursor)
+	assert.NotEmpty(t, loaded.UpdatedAt)
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestLoadManifestRejectsNewerVersion(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"version":99}`), 0644))
+
+	_, err := LoadManifest(dir)
+	assert.ErrorContains(t, err, "manifest version 99")
+}
@@ -0,0 +1,270 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+)
+
+// mailDomain is the domain of the synthetic addresses and Message-IDs. The
+// .invalid TLD is reserved, so they can never collide with real mail.
+const mailDomain = "beeper.invalid"
+
+// mboxFromLine matches body lines that mboxrd quotes with an extra ">"
+var mboxFromLine = regexp.MustCompile(`^>*From `)
+
+// mailEncoder renders a chat's messages as RFC 5322 emails in mboxrd format
+type mailEncoder struct {
+	chat api.Chat
+	// attachmentsDir holds the downloaded attachments embedded as MIME parts
+	attachmentsDir string
+}
+
+// encode writes one message as an mbox entry: a "From " separator line, the
+// email, and a blank line
+func (e *mailEncoder) encode(w io.Writer, msg api.Message) error {
+	date := time.Unix(0, 0).UTC()
+	if t, ok := msg.Time(); ok {
+		date = t
+	}
+
+	var mail bytes.Buffer
+	if err := e.writeMail(&mail, msg, date); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From %s %s\n", e.senderAddress(msg), date.UTC().Format(time.ANSIC))
+	for _, line := range strings.SplitAfter(strings.ReplaceAll(mail.String(), "\r\n", "\n"), "\n") {
+		if mboxFromLine.MatchString(line) {
+			buf.WriteByte('>')
+		}
+		buf.WriteString(line)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeMail writes the message's headers and MIME body. Every message of a
+// chat shares its Subject and references the chat's thread ID, so mail
+// clients group the conversation; replies also carry In-Reply-To.
+func (e *mailEncoder) writeMail(w io.Writer, msg api.Message, date time.Time) error {
+	h := &headerWriter{w: w}
+	h.set("From", formatAddress(msg.Sender, e.senderAddress(msg)))
+	h.set("To", formatAddress(e.chatTitle(), localPart(e.chat.ID)+"@"+mailDomain))
+	h.set("Subject", mime.QEncoding.Encode("utf-8", e.chatTitle()))
+	h.set("Date", date.Format(time.RFC1123Z))
+	h.set("Message-ID", e.messageID(msg.ID))
+	references := e.threadID()
+	if msg.ReplyTo != "" {
+		h.set("In-Reply-To", e.messageID(msg.ReplyTo))
+		references += " " + e.messageID(msg.ReplyTo)
+	}
+	h.set("References", references)
+	h.set("X-Beeper-Chat-ID", e.chat.ID)
+	if e.chat.Network != "" {
+		h.set("X-Beeper-Network", e.chat.Network)
+	}
+	h.set("X-Beeper-Message-ID", msg.ID)
+	h.set("MIME-Version", "1.0")
+
+	parts := e.attachmentParts(msg)
+	if len(parts) == 0 {
+		h.set("Content-Type", "text/plain; charset=utf-8")
+		h.set("Content-Transfer-Encoding", "quoted-printable")
+		if h.err != nil {
+			return h.err
+		}
+		fmt.Fprint(w, "\r\n")
+		return writeQuotedPrintable(w, msg.Text)
+	}
+
+	mw := multipart.NewWriter(w)
+	h.set("Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mw.Boundary()}))
+	if h.err != nil {
+		return h.err
+	}
+	fmt.Fprint(w, "\r\n")
+
+	text, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeQuotedPrintable(text, msg.Text); err != nil {
+		return err
+	}
+
+	for _, p := range parts {
+		part, err := mw.CreatePart(p.header)
+		if err != nil {
+			return err
+		}
+		if err := writeBase64(part, p.data); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+type mimePart struct {
+	header textproto.MIMEHeader
+	data   []byte
+}
+
+// attachmentParts reads the message's downloaded attachments. Attachments
+// that were skipped or failed to download are left out.
+func (e *mailEncoder) attachmentParts(msg api.Message) []mimePart {
+	if e.attachmentsDir == "" {
+		return nil
+	}
+	var parts []mimePart
+	for i, att := range msg.Attachments {
+		name := output.AttachmentFileName(msg, i)
+		data, err := os.ReadFile(filepath.Join(e.attachmentsDir, name))
+		if err != nil {
+			continue
+		}
+		contentType := att.MimeType
+		if contentType == "" {
+			contentType = mime.TypeByExtension(filepath.Ext(name))
+		}
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		fileName := att.FileName
+		if fileName == "" {
+			fileName = name
+		}
+		parts = append(parts, mimePart{
+			header: textproto.MIMEHeader{
+				"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": fileName})},
+				"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": fileName})},
+				"Content-Transfer-Encoding": {"base64"},
+			},
+			data: data,
+		})
+	}
+	return parts
+}
+
+func (e *mailEncoder) chatTitle() string {
+	if e.chat.Title != "" {
+		return e.chat.Title
+	}
+	return e.chat.ID
+}
+
+// senderAddress is a synthetic address for the sender: their ID when the
+// API provides one, otherwise their name
+func (e *mailEncoder) senderAddress(msg api.Message) string {
+	sender := msg.SenderID
+	if sender == "" {
+		sender = msg.Sender
+	}
+	if sender == "" {
+		sender = "unknown"
+	}
+	domain := mailDomain
+	if e.chat.Network != "" {
+		domain = localPart(strings.ToLower(e.chat.Network)) + "." + mailDomain
+	}
+	return localPart(sender) + "@" + domain
+}
+
+// messageID is the Message-ID of a message in this chat
+func (e *mailEncoder) messageID(id string) string {
+	return "<" + localPart(id) + "." + localPart(e.chat.ID) + "@" + mailDomain + ">"
+}
+
+// threadID is referenced by every message of the chat
+func (e *mailEncoder) threadID() string {
+	return "<" + localPart(e.chat.ID) + "@" + mailDomain + ">"
+}
+
+// localPart percent-encodes everything but letters, digits and a few safe
+// symbols, so any ID becomes a valid address or Message-ID local part and
+// distinct IDs stay distinct
+func localPart(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("!#$&'*+-/=?^_`{|}~", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// formatAddress renders "Display Name" <addr>, encoding non-ASCII names
+func formatAddress(name, addr string) string {
+	if name == "" {
+		return "<" + addr + ">"
+	}
+	encoded := mime.QEncoding.Encode("utf-8", name)
+	if encoded == name {
+		encoded = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name) + `"`
+	}
+	return encoded + " <" + addr + ">"
+}
+
+// headerWriter writes CRLF-terminated header fields, keeping the first error
+type headerWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (h *headerWriter) set(key, value string) {
+	if h.err != nil {
+		return
+	}
+	// Header values never span lines; stray line breaks would start a new field
+	value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+	_, h.err = fmt.Fprintf(h.w, "%s: %s\r\n", key, value)
+}
+
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, text); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64 writes data as base64 in 76-character lines
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := io.WriteString(w, encoded[:n]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
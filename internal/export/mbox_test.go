@@ -0,0 +1,135 @@
+package export
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// splitMbox splits an mboxrd file into its emails, undoing the From quoting
+func splitMbox(t *testing.T, data string) []string {
+	t.Helper()
+	var mails []string
+	var current []string
+	for _, line := range strings.Split(data, "\n") {
+		if strings.HasPrefix(line, "From ") {
+			if current != nil {
+				mails = append(mails, strings.Join(current, "\n"))
+			}
+			current = []string{}
+			continue
+		}
+		require.NotNil(t, current, "mbox must start with a From line")
+		if strings.HasPrefix(strings.TrimLeft(line, ">"), "From ") {
+			line = line[1:]
+		}
+		current = append(current, line)
+	}
+	if current != nil {
+		mails = append(mails, strings.Join(current, "\n"))
+	}
+	return mails
+}
+
+func TestRunExportsMbox(t *testing.T) {
+	dir := t.TempDir()
+	chat := api.Chat{ID: "!team:beeper", Title: "Équipe", Network: "Slack"}
+	src := &fakeSource{
+		chats: []api.Chat{chat},
+		messages: map[string][]api.Message{chat.ID: {
+			{ID: "$m1", ChatID: chat.ID, SortKey: "1", SenderID: "@alice:beeper", Sender: "Alice", Text: "From the top:\nhello", Timestamp: "2024-03-01T09:30:00Z"},
+			{ID: "$m2", ChatID: chat.ID, SortKey: "2", SenderID: "@bob:beeper", Sender: "Bob", Text: "see photo", Timestamp: "2024-03-01T09:31:00Z", ReplyTo: "$m1",
+				Attachments: []api.Attachment{{SrcURL: "mxc://photo", MimeType: "image/png", FileName: "photo.png"}}},
+		}},
+		assets: map[string]string{"mxc://photo": "PNGDATA"},
+	}
+
+	_, err := Run(src, Options{Dir: dir, Format: FormatMbox})
+	require.NoError(t, err)
+	assert.NoFileExists(t, filepath.Join(dir, filepath.FromSlash(ChatDirName(chat.ID)), MessagesFile))
+
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(ChatDirName(chat.ID)), MboxFile))
+	require.NoError(t, err)
+	mails := splitMbox(t, string(data))
+	require.Len(t, mails, 2)
+
+	// Pages are fetched newest first
+	reply, err := mail.ReadMessage(strings.NewReader(mails[0]))
+	require.NoError(t, err)
+	first, err := mail.ReadMessage(strings.NewReader(mails[1]))
+	require.NoError(t, err)
+
+	from, err := first.Header.AddressList("From")
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", from[0].Name)
+	assert.Equal(t, "%40alice%3Abeeper@slack.beeper.invalid", from[0].Address)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(first.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Équipe", subject)
+	assert.Equal(t, first.Header.Get("Subject"), reply.Header.Get("Subject"))
+
+	date, err := first.Header.Date()
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01T09:30:00Z", date.UTC().Format("2006-01-02T15:04:05Z"))
+
+	assert.Equal(t, first.Header.Get("Message-ID"), reply.Header.Get("In-Reply-To"))
+	assert.Empty(t, first.Header.Get("In-Reply-To"))
+	assert.Contains(t, reply.Header.Get("References"), first.Header.Get("Message-ID"))
+	assert.Equal(t, "!team:beeper", first.Header.Get("X-Beeper-Chat-ID"))
+
+	body, err := io.ReadAll(first.Body)
+	require.NoError(t, err)
+	// mbox stores lines with LF endings; the From line in the body was quoted
+	assert.Equal(t, "From the top:\nhello", strings.TrimRight(string(body), "\n"))
+	assert.Contains(t, string(data), "\n>From the top:\n")
+
+	mediaType, params, err := mime.ParseMediaType(reply.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/mixed", mediaType)
+	mr := multipart.NewReader(reply.Body, params["boundary"])
+
+	text, err := mr.NextPart()
+	require.NoError(t, err)
+	content, err := io.ReadAll(text)
+	require.NoError(t, err)
+	assert.Equal(t, "see photo", string(content))
+
+	att, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "photo.png", att.FileName())
+	assert.Equal(t, "image/png; name=photo.png", att.Header.Get("Content-Type"))
+	assert.Equal(t, "base64", att.Header.Get("Content-Transfer-Encoding"))
+}
+
+func TestRunKeepsDirectoryFormat(t *testing.T) {
+	dir := t.TempDir()
+	src := &fakeSource{
+		chats:    []api.Chat{{ID: "c1"}},
+		messages: map[string][]api.Message{"c1": history("c1", 1, 2)},
+	}
+
+	_, err := Run(src, Options{Dir: dir})
+	require.NoError(t, err)
+
+	_, err = Run(src, Options{Dir: dir, Format: FormatMbox})
+	assert.ErrorContains(t, err, "holds ndjson exports")
+
+	_, err = Run(src, Options{Dir: t.TempDir(), Format: "pst"})
+	assert.ErrorContains(t, err, "invalid export format: pst")
+}
+
+func TestLocalPart(t *testing.T) {
+	assert.Equal(t, "!abc%3Abeeper%2Elocal", localPart("!abc:beeper.local"))
+	assert.Equal(t, "_", localPart(""))
+	assert.NotEqual(t, localPart("a.b"), localPart("a_b"))
+}
@@ -0,0 +1,155 @@
+// Package gateway serves a curated REST API in front of Beeper Desktop, with
+// its own API keys restricted to chats, networks and read or write access.
+package gateway
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Access rights a key can hold
+const (
+	AccessRead  = "read"
+	AccessWrite = "write"
+)
+
+// keyPrefix marks gateway keys so they are recognizable in logs and configs
+const keyPrefix = "bgk_"
+
+// KeysFile is the gateway key configuration
+type KeysFile struct {
+	Keys []*Key `yaml:"keys"`
+}
+
+// Key is one API key and what it may reach
+type Key struct {
+	// Name identifies the key in the access log
+	Name string `yaml:"name"`
+	// SHA256 is the hex SHA-256 of the key. Key holds it in plain text
+	// instead; one of the two is required.
+	SHA256 string `yaml:"key_sha256,omitempty"`
+	Key    string `yaml:"key,omitempty"`
+	// Chats limits the key to these chat IDs (empty allows every chat)
+	Chats []string `yaml:"chats,omitempty"`
+	// Networks limits the key to chats on these networks (empty allows all)
+	Networks []string `yaml:"networks,omitempty"`
+	// Access lists the key's rights: read, write or both (default read)
+	Access []string `yaml:"access,omitempty"`
+	// RateLimit is the number of requests allowed per minute (0 is unlimited)
+	RateLimit int `yaml:"rate_limit,omitempty"`
+}
+
+// LoadKeys reads and validates a key file
+func LoadKeys(path string) (*KeysFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keys file: %w", err)
+	}
+
+	var kf KeysFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&kf); err != nil {
+		return nil, fmt.Errorf("failed to parse keys file: %w", err)
+	}
+	if err := kf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid keys file %s: %w", path, err)
+	}
+	return &kf, nil
+}
+
+// Validate checks every key and fills in defaults
+func (kf *KeysFile) Validate() error {
+	if len(kf.Keys) == 0 {
+		return fmt.Errorf("no keys defined")
+	}
+
+	names := make(map[string]bool)
+	hashes := make(map[string]bool)
+	for i, k := range kf.Keys {
+		if k.Name == "" {
+			return fmt.Errorf("key %d: name is required", i+1)
+		}
+		if names[k.Name] {
+			return fmt.Errorf("key %s: duplicate name", k.Name)
+		}
+		names[k.Name] = true
+
+		switch {
+		case k.Key != "" && k.SHA256 != "":
+			return fmt.Errorf("key %s: set key or key_sha256, not both", k.Name)
+		case k.Key != "":
+			k.SHA256 = HashKey(k.Key)
+			k.Key = ""
+		case k.SHA256 == "":
+			return fmt.Errorf("key %s: key or key_sha256 is required", k.Name)
+		}
+		k.SHA256 = strings.ToLower(k.SHA256)
+		if b, err := hex.DecodeString(k.SHA256); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("key %s: key_sha256 must be 64 hex characters", k.Name)
+		}
+		if hashes[k.SHA256] {
+			return fmt.Errorf("key %s: same key as another entry", k.Name)
+		}
+		hashes[k.SHA256] = true
+
+		if len(k.Access) == 0 {
+			k.Access = []string{AccessRead}
+		}
+		for _, a := range k.Access {
+			if a != AccessRead && a != AccessWrite {
+				return fmt.Errorf("key %s: invalid access %q (use read or write)", k.Name, a)
+			}
+		}
+		if k.RateLimit < 0 {
+			return fmt.Errorf("key %s: rate_limit must not be negative", k.Name)
+		}
+	}
+	return nil
+}
+
+// Can reports whether the key holds an access right
+func (k *Key) Can(access string) bool {
+	return slices.Contains(k.Access, access)
+}
+
+// AllowsChatID reports whether the key's chat list admits chatID
+func (k *Key) AllowsChatID(chatID string) bool {
+	return len(k.Chats) == 0 || slices.Contains(k.Chats, chatID)
+}
+
+// AllowsNetwork reports whether the key's network list admits network
+func (k *Key) AllowsNetwork(network string) bool {
+	if len(k.Networks) == 0 {
+		return true
+	}
+	for _, n := range k.Networks {
+		if strings.EqualFold(n, network) {
+			return true
+		}
+	}
+	return false
+}
+
+// HashKey returns the hex SHA-256 of a key, as stored in key_sha256
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateKey returns a new random key
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
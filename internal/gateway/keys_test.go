@@ -0,0 +1,84 @@
+package gateway
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeys(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadKeys(t *testing.T) {
+	hash := HashKey("secret-2")
+	path := writeKeys(t, `keys:
+  - name: reader
+    key: secret-1
+    networks: [WhatsApp]
+    rate_limit: 60
+  - name: bot
+    key_sha256: `+strings.ToUpper(hash)+`
+    chats: ["!abc:beeper.local"]
+    access: [read, write]
+`)
+
+	kf, err := LoadKeys(path)
+	require.NoError(t, err)
+	require.Len(t, kf.Keys, 2)
+
+	reader := kf.Keys[0]
+	assert.Equal(t, HashKey("secret-1"), reader.SHA256)
+	assert.Empty(t, reader.Key, "plain keys are dropped after hashing")
+	assert.Equal(t, []string{AccessRead}, reader.Access)
+	assert.True(t, reader.Can(AccessRead))
+	assert.False(t, reader.Can(AccessWrite))
+	assert.True(t, reader.AllowsNetwork("whatsapp"))
+	assert.False(t, reader.AllowsNetwork("slack"))
+	assert.True(t, reader.AllowsChatID("anything"))
+
+	bot := kf.Keys[1]
+	assert.Equal(t, hash, bot.SHA256)
+	assert.True(t, bot.Can(AccessWrite))
+	assert.True(t, bot.AllowsChatID("!abc:beeper.local"))
+	assert.False(t, bot.AllowsChatID("!other:beeper.local"))
+}
+
+func TestLoadKeysRejectsInvalid(t *testing.T) {
+	tests := map[string]string{
+		"no keys":        "keys: []\n",
+		"missing name":   "keys:\n  - key: a\n",
+		"duplicate name": "keys:\n  - name: a\n    key: a\n  - name: a\n    key: b\n",
+		"missing key":    "keys:\n  - name: a\n",
+		"both keys":      "keys:\n  - name: a\n    key: a\n    key_sha256: " + HashKey("a") + "\n",
+		"bad hash":       "keys:\n  - name: a\n    key_sha256: abc\n",
+		"same key":       "keys:\n  - name: a\n    key: x\n  - name: b\n    key: x\n",
+		"bad access":     "keys:\n  - name: a\n    key: a\n    access: [admin]\n",
+		"negative limit": "keys:\n  - name: a\n    key: a\n    rate_limit: -1\n",
+		"unknown field":  "keys:\n  - name: a\n    key: a\n    chat: x\n",
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadKeys(writeKeys(t, content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	a, err := GenerateKey()
+	require.NoError(t, err)
+	b, err := GenerateKey()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(a, keyPrefix))
+	assert.Len(t, a, len(keyPrefix)+64)
+	assert.NotEqual(t, a, b)
+}
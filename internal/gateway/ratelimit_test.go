@@ -0,0 +1,39 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newRateLimiter(func() time.Time { return now })
+
+	// A full bucket allows a burst of the per-minute limit
+	for i := 0; i < 3; i++ {
+		ok, _ := l.allow("k", 3)
+		assert.True(t, ok, "request %d", i)
+	}
+	ok, wait := l.allow("k", 3)
+	assert.False(t, ok)
+	assert.Equal(t, 20*time.Second, wait)
+
+	// Other keys have their own bucket
+	ok, _ = l.allow("other", 3)
+	assert.True(t, ok)
+
+	// One token refills every 20 seconds
+	now = now.Add(20 * time.Second)
+	ok, _ = l.allow("k", 3)
+	assert.True(t, ok)
+	ok, _ = l.allow("k", 3)
+	assert.False(t, ok)
+
+	// Zero means unlimited
+	for i := 0; i < 100; i++ {
+		ok, _ = l.allow("free", 0)
+		assert.True(t, ok)
+	}
+}
@@ -0,0 +1,476 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+)
+
+// Backend is the part of the API client the gateway proxies to
+type Backend interface {
+	ListChatsPage(cursor string) (*api.ChatsResponse, error)
+	GetChat(chatID string) (*api.Chat, error)
+	ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error)
+	SearchMessagesPage(query, cursor string, limit int) (*api.SearchResponse, error)
+	SendMessage(chatID, text string) (string, error)
+}
+
+// Result limits
+const (
+	defaultMessageLimit = 50
+	maxMessageLimit     = 200
+	defaultSearchLimit  = 20
+	maxSearchLimit      = 20
+	maxBodyBytes        = 64 << 10
+)
+
+// Options configures the gateway
+type Options struct {
+	Keys *KeysFile
+	// AccessLog receives one JSON line per request (nil disables it)
+	AccessLog io.Writer
+	// Now returns the current time; nil uses time.Now
+	Now func() time.Time
+}
+
+// Server is the gateway's HTTP handler
+type Server struct {
+	backend Backend
+	keys    map[string]*Key // by SHA-256
+	limiter *rateLimiter
+	now     func() time.Time
+	mux     *http.ServeMux
+
+	logMu     sync.Mutex
+	accessLog io.Writer
+
+	// networks caches each chat's network for scope checks
+	networksMu sync.Mutex
+	networks   map[string]string
+}
+
+// New returns a gateway serving backend to the configured keys
+func New(backend Backend, opts Options) *Server {
+	now := opts.Now
+	if now == nil {
+		now = time.Now
+	}
+	s := &Server{
+		backend:   backend,
+		keys:      make(map[string]*Key),
+		limiter:   newRateLimiter(now),
+		now:       now,
+		mux:       http.NewServeMux(),
+		accessLog: opts.AccessLog,
+		networks:  make(map[string]string),
+	}
+	if opts.Keys != nil {
+		for _, k := range opts.Keys.Keys {
+			s.keys[k.SHA256] = k
+		}
+	}
+
+	s.mux.HandleFunc("GET /v1/chats", s.requires(AccessRead, s.listChats))
+	s.mux.HandleFunc("GET /v1/chats/{chatID}", s.requires(AccessRead, s.getChat))
+	s.mux.HandleFunc("GET /v1/chats/{chatID}/messages", s.requires(AccessRead, s.listMessages))
+	s.mux.HandleFunc("POST /v1/chats/{chatID}/messages", s.requires(AccessWrite, s.sendMessage))
+	s.mux.HandleFunc("GET /v1/search", s.requires(AccessRead, s.search))
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not found")
+	})
+	return s
+}
+
+type keyContext struct{}
+
+// ServeHTTP authenticates the request, applies the key's rate limit, routes
+// it and writes the access log entry
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := s.now()
+	rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+	keyName := "-"
+	defer func() {
+		s.logRequest(r, rec, keyName, start)
+	}()
+
+	key := s.authenticate(r)
+	if key == nil {
+		rec.Header().Set("WWW-Authenticate", `Bearer realm="beeper"`)
+		writeError(rec, http.StatusUnauthorized, "missing or invalid API key")
+		return
+	}
+	keyName = key.Name
+
+	if ok, wait := s.limiter.allow(key.SHA256, key.RateLimit); !ok {
+		rec.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeError(rec, http.StatusTooManyRequests, fmt.Sprintf("rate limit of %d requests per minute exceeded", key.RateLimit))
+		return
+	}
+
+	s.mux.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), keyContext{}, key)))
+}
+
+// authenticate finds the key presented as a bearer token or X-API-Key.
+// Keys are looked up by hash, so the plain keys are never held in memory.
+func (s *Server) authenticate(r *http.Request) *Key {
+	presented := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); presented == "" && auth != "" {
+		scheme, token, ok := strings.Cut(auth, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			presented = strings.TrimSpace(token)
+		}
+	}
+	if presented == "" {
+		return nil
+	}
+	return s.keys[HashKey(presented)]
+}
+
+// requires wraps a handler that needs an access right
+func (s *Server) requires(access string, h func(http.ResponseWriter, *http.Request, *Key)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Context().Value(keyContext{}).(*Key)
+		if !key.Can(access) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("key %s lacks %s access", key.Name, access))
+			return
+		}
+		h(w, r, key)
+	}
+}
+
+// chatsPage is a page of chats; continue with nextCursor while hasMore
+type chatsPage struct {
+	Items      []api.Chat `json:"items"`
+	HasMore    bool       `json:"hasMore"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// messagesPage is a page of messages; continue with nextCursor while hasMore
+type messagesPage struct {
+	Items      []api.Message `json:"items"`
+	HasMore    bool          `json:"hasMore"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+func (s *Server) listChats(w http.ResponseWriter, r *http.Request, key *Key) {
+	resp, err := s.backend.ListChatsPage(r.URL.Query().Get("cursor"))
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	network := r.URL.Query().Get("network")
+	page := chatsPage{Items: []api.Chat{}, HasMore: resp.HasMore}
+	if resp.HasMore {
+		page.NextCursor = resp.OldestCursor
+	}
+	for _, chat := range resp.Items {
+		s.rememberNetwork(chat)
+		if key.AllowsChatID(chat.ID) && key.AllowsNetwork(chat.Network) &&
+			(network == "" || strings.EqualFold(chat.Network, network)) {
+			page.Items = append(page.Items, chat)
+		}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// chatIDParam returns the chat ID from the path. The segment arrives decoded,
+// so IDs holding /, ? or # are refused: forwarded to Desktop they would
+// reach a different path than the one the key was checked against.
+func chatIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	chatID := r.PathValue("chatID")
+	if strings.ContainsAny(chatID, "/?#") {
+		writeError(w, http.StatusBadRequest, "invalid chat ID")
+		return "", false
+	}
+	return chatID, true
+}
+
+func (s *Server) getChat(w http.ResponseWriter, r *http.Request, key *Key) {
+	chatID, ok := chatIDParam(w, r)
+	if !ok {
+		return
+	}
+	if !key.AllowsChatID(chatID) {
+		writeError(w, http.StatusNotFound, "chat not found")
+		return
+	}
+	chat, err := s.backend.GetChat(chatID)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	s.rememberNetwork(*chat)
+	if !key.AllowsNetwork(chat.Network) {
+		writeError(w, http.StatusNotFound, "chat not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, chat)
+}
+
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request, key *Key) {
+	chatID, ok := chatIDParam(w, r)
+	if !ok {
+		return
+	}
+	limit, err := parseLimit(r, defaultMessageLimit, maxMessageLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.authorizeChat(w, key, chatID) {
+		return
+	}
+
+	resp, err := s.backend.ListMessagesPage(chatID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	page := messagesPage{Items: resp.Items, HasMore: resp.HasMore}
+	if page.Items == nil {
+		page.Items = []api.Message{}
+	}
+	if resp.HasMore {
+		page.NextCursor = resp.NextCursor()
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request, key *Key) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeError(w, http.StatusBadRequest, "q is required")
+		return
+	}
+	limit, err := parseLimit(r, defaultSearchLimit, maxSearchLimit)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp, err := s.backend.SearchMessagesPage(query, "", limit)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+
+	// Search runs across every chat, so results outside the key's scope
+	// are dropped before they leave the gateway
+	page := messagesPage{Items: []api.Message{}, HasMore: resp.HasMore}
+	for _, msg := range resp.Items {
+		ok, err := s.inScope(key, msg.ChatID)
+		if err != nil {
+			writeUpstreamError(w, err)
+			return
+		}
+		if ok {
+			page.Items = append(page.Items, msg)
+		}
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request, key *Key) {
+	chatID, ok := chatIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		Text string `json:"text"`
+	}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if strings.TrimSpace(body.Text) == "" {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+	if !s.authorizeChat(w, key, chatID) {
+		return
+	}
+
+	messageID, err := s.backend.SendMessage(chatID, body.Text)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, output.SendResult{Success: true, MessageID: messageID, ChatID: chatID})
+}
+
+// authorizeChat checks a chat against the key's scope, writing a 404 (so
+// out-of-scope chats are indistinguishable from missing ones) if it fails
+func (s *Server) authorizeChat(w http.ResponseWriter, key *Key, chatID string) bool {
+	ok, err := s.inScope(key, chatID)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return false
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "chat not found")
+	}
+	return ok
+}
+
+// inScope reports whether the key may reach a chat, looking up the chat's
+// network only when the key is restricted by network
+func (s *Server) inScope(key *Key, chatID string) (bool, error) {
+	if !key.AllowsChatID(chatID) {
+		return false, nil
+	}
+	if len(key.Networks) == 0 {
+		return true, nil
+	}
+
+	s.networksMu.Lock()
+	network, ok := s.networks[chatID]
+	s.networksMu.Unlock()
+	if !ok {
+		chat, err := s.backend.GetChat(chatID)
+		if err != nil {
+			var apiErr *api.APIError
+			if errors.As(err, &apiErr) && apiErr.Category == api.CategoryNotFound {
+				return false, nil
+			}
+			return false, err
+		}
+		s.rememberNetwork(*chat)
+		network = chat.Network
+	}
+	return key.AllowsNetwork(network), nil
+}
+
+func (s *Server) rememberNetwork(chat api.Chat) {
+	s.networksMu.Lock()
+	s.networks[chat.ID] = chat.Network
+	s.networksMu.Unlock()
+}
+
+func parseLimit(r *http.Request, def, maxLimit int) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return def, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("limit must be a positive integer")
+	}
+	return min(limit, maxLimit), nil
+}
+
+// errorBody is the JSON body of every error response
+type errorBody struct {
+	Error    string `json:"error"`
+	Category string `json:"category,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorBody{Error: message})
+}
+
+// writeUpstreamError maps a Desktop API error to a gateway response. Missing
+// resources and bad input pass through; anything else is the gateway's
+// problem, not the caller's, so it becomes 502 without the Desktop's hints.
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	if rec, ok := w.(*recorder); ok {
+		rec.err = err.Error()
+	}
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		writeJSON(w, http.StatusBadGateway, errorBody{Error: "upstream request failed", Category: string(api.CategoryUnknown)})
+		return
+	}
+	switch apiErr.Category {
+	case api.CategoryNotFound:
+		writeJSON(w, http.StatusNotFound, errorBody{Error: apiErr.Message, Category: string(apiErr.Category)})
+	case api.CategoryValidation:
+		writeJSON(w, http.StatusBadRequest, errorBody{Error: apiErr.Message, Category: string(apiErr.Category)})
+	default:
+		writeJSON(w, http.StatusBadGateway, errorBody{Error: "upstream request failed", Category: string(apiErr.Category)})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// recorder captures the status and size of a response for the access log
+type recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+	// err is the upstream error behind a failed response, logged for the
+	// operator but never sent to the client
+	err string
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// accessEntry is one line of the access log. Query strings are left out
+// because search terms can be sensitive.
+type accessEntry struct {
+	Time       string `json:"time"`
+	Remote     string `json:"remote"`
+	Key        string `json:"key"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Status     int    `json:"status"`
+	Bytes      int    `json:"bytes"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+func (s *Server) logRequest(r *http.Request, rec *recorder, keyName string, start time.Time) {
+	if s.accessLog == nil {
+		return
+	}
+	remote := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	data, err := json.Marshal(accessEntry{
+		Time:       start.UTC().Format(time.RFC3339),
+		Remote:     remote,
+		Key:        keyName,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+		Status:     rec.status,
+		Bytes:      rec.bytes,
+		DurationMS: s.now().Sub(start).Milliseconds(),
+		Error:      rec.err,
+	})
+	if err != nil {
+		return
+	}
+
+	s.logMu.Lock()
+	defer s.logMu.Unlock()
+	s.accessLog.Write(append(data, '\n'))
+}
@@ -0,0 +1,264 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBackend struct {
+	chats    []api.Chat
+	messages []api.Message
+	sent     []string
+	getChats int
+	err      error
+}
+
+func (f *fakeBackend) ListChatsPage(cursor string) (*api.ChatsResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &api.ChatsResponse{Items: f.chats, HasMore: true, OldestCursor: "next"}, nil
+}
+
+func (f *fakeBackend) GetChat(chatID string) (*api.Chat, error) {
+	f.getChats++
+	for _, c := range f.chats {
+		if c.ID == chatID {
+			return &c, nil
+		}
+	}
+	return nil, &api.APIError{Message: "chat not found", Category: api.CategoryNotFound}
+}
+
+func (f *fakeBackend) ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error) {
+	var items []api.Message
+	for _, m := range f.messages {
+		if m.ChatID == chatID {
+			items = append(items, m)
+		}
+	}
+	return &api.MessagesResponse{Items: items}, nil
+}
+
+func (f *fakeBackend) SearchMessagesPage(query, cursor string, limit int) (*api.SearchResponse, error) {
+	return &api.SearchResponse{Items: f.messages}, nil
+}
+
+func (f *fakeBackend) SendMessage(chatID, text string) (string, error) {
+	f.sent = append(f.sent, chatID+": "+text)
+	return "m-new", nil
+}
+
+func newTestGateway(t *testing.T) (*Server, *fakeBackend, *bytes.Buffer) {
+	t.Helper()
+	backend := &fakeBackend{
+		chats: []api.Chat{
+			{ID: "wa1", Title: "Family", Network: "WhatsApp"},
+			{ID: "sl1", Title: "Team", Network: "Slack"},
+			{ID: "sl2", Title: "Random", Network: "Slack"},
+		},
+		messages: []api.Message{
+			{ID: "m1", ChatID: "wa1", Text: "dinner?"},
+			{ID: "m2", ChatID: "sl1", Text: "dinner plans"},
+			{ID: "m3", ChatID: "sl2", Text: "dinner gif"},
+		},
+	}
+	keys := &KeysFile{Keys: []*Key{
+		{Name: "admin", Key: "admin-key", Access: []string{AccessRead, AccessWrite}},
+		{Name: "slack-reader", Key: "slack-key", Networks: []string{"slack"}},
+		{Name: "team-bot", Key: "team-key", Chats: []string{"sl1"}, Access: []string{AccessWrite}, RateLimit: 2},
+	}}
+	require.NoError(t, keys.Validate())
+
+	var log bytes.Buffer
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	gw := New(backend, Options{Keys: keys, AccessLog: &log, Now: func() time.Time { return now }})
+	return gw, backend, &log
+}
+
+func do(gw *Server, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), v), rec.Body.String())
+}
+
+func chatIDs(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var page chatsPage
+	decode(t, rec, &page)
+	var ids []string
+	for _, c := range page.Items {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+func messageIDs(t *testing.T, rec *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var page messagesPage
+	decode(t, rec, &page)
+	var ids []string
+	for _, m := range page.Items {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func TestAuthentication(t *testing.T) {
+	gw, _, _ := newTestGateway(t)
+
+	rec := do(gw, "GET", "/v1/chats", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), "Bearer")
+
+	assert.Equal(t, http.StatusUnauthorized, do(gw, "GET", "/v1/chats", "wrong", "").Code)
+
+	req := httptest.NewRequest("GET", "/v1/chats", nil)
+	req.Header.Set("X-API-Key", "admin-key")
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestChatScopes(t *testing.T) {
+	gw, _, _ := newTestGateway(t)
+
+	assert.Equal(t, []string{"wa1", "sl1", "sl2"}, chatIDs(t, do(gw, "GET", "/v1/chats", "admin-key", "")))
+	assert.Equal(t, []string{"sl1", "sl2"}, chatIDs(t, do(gw, "GET", "/v1/chats", "slack-key", "")))
+	assert.Equal(t, []string{"wa1"}, chatIDs(t, do(gw, "GET", "/v1/chats?network=whatsapp", "admin-key", "")))
+	assert.Empty(t, chatIDs(t, do(gw, "GET", "/v1/chats?network=whatsapp", "slack-key", "")))
+
+	assert.Equal(t, http.StatusOK, do(gw, "GET", "/v1/chats/sl1", "slack-key", "").Code)
+	// Out-of-scope chats look missing
+	rec := do(gw, "GET", "/v1/chats/wa1", "slack-key", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"chat not found"}`, rec.Body.String())
+
+	assert.Equal(t, []string{"m2"}, messageIDs(t, do(gw, "GET", "/v1/chats/sl1/messages", "slack-key", "")))
+	assert.Equal(t, http.StatusNotFound, do(gw, "GET", "/v1/chats/wa1/messages", "slack-key", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(gw, "GET", "/v1/chats/sl1/messages?limit=0", "slack-key", "").Code)
+}
+
+func TestEncodedChatIDs(t *testing.T) {
+	gw, backend, _ := newTestGateway(t)
+
+	// An encoded slash would otherwise reach another Desktop path for a key
+	// scoped only by network
+	for _, path := range []string{
+		"/v1/chats/sl1%2F..%2F..%2Faccounts",
+		"/v1/chats/sl1%3Fcursor=x/messages",
+		"/v1/chats/sl1%23frag",
+	} {
+		rec := do(gw, "GET", path, "slack-key", "")
+		assert.Equal(t, http.StatusBadRequest, rec.Code, path)
+		assert.JSONEq(t, `{"error":"invalid chat ID"}`, rec.Body.String())
+	}
+	rec := do(gw, "POST", "/v1/chats/sl1%2Fx/messages", "admin-key", `{"text":"hi"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, backend.sent)
+	assert.Zero(t, backend.getChats)
+}
+
+func TestSearchFiltersResults(t *testing.T) {
+	gw, backend, _ := newTestGateway(t)
+
+	assert.Equal(t, []string{"m1", "m2", "m3"}, messageIDs(t, do(gw, "GET", "/v1/search?q=dinner", "admin-key", "")))
+	assert.Equal(t, []string{"m2", "m3"}, messageIDs(t, do(gw, "GET", "/v1/search?q=dinner", "slack-key", "")))
+
+	// Networks are cached after the first lookup
+	lookups := backend.getChats
+	do(gw, "GET", "/v1/search?q=dinner", "slack-key", "")
+	assert.Equal(t, lookups, backend.getChats)
+
+	assert.Equal(t, http.StatusBadRequest, do(gw, "GET", "/v1/search", "admin-key", "").Code)
+}
+
+func TestWriteAccess(t *testing.T) {
+	gw, backend, _ := newTestGateway(t)
+	// Rate limits are covered separately
+	gw.keys[HashKey("team-key")].RateLimit = 0
+
+	rec := do(gw, "POST", "/v1/chats/sl1/messages", "slack-key", `{"text":"hi"}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "lacks write access")
+
+	rec = do(gw, "POST", "/v1/chats/sl1/messages", "team-key", `{"text":"hi"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.JSONEq(t, `{"success":true,"message_id":"m-new","chat_id":"sl1"}`, rec.Body.String())
+	assert.Equal(t, []string{"sl1: hi"}, backend.sent)
+
+	// team-key has write but not read access
+	assert.Equal(t, http.StatusForbidden, do(gw, "GET", "/v1/chats/sl1", "team-key", "").Code)
+
+	assert.Equal(t, http.StatusNotFound, do(gw, "POST", "/v1/chats/sl2/messages", "team-key", `{"text":"hi"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(gw, "POST", "/v1/chats/sl1/messages", "admin-key", `{"text":""}`).Code)
+	assert.Equal(t, http.StatusBadRequest, do(gw, "POST", "/v1/chats/sl1/messages", "admin-key", `{"txt":"hi"}`).Code)
+}
+
+func TestRateLimitAndAccessLog(t *testing.T) {
+	gw, _, log := newTestGateway(t)
+
+	assert.Equal(t, http.StatusCreated, do(gw, "POST", "/v1/chats/sl1/messages", "team-key", `{"text":"1"}`).Code)
+	assert.Equal(t, http.StatusCreated, do(gw, "POST", "/v1/chats/sl1/messages", "team-key", `{"text":"2"}`).Code)
+	rec := do(gw, "POST", "/v1/chats/sl1/messages", "team-key", `{"text":"3"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "30", rec.Header().Get("Retry-After"))
+
+	// Unlimited keys are unaffected
+	assert.Equal(t, http.StatusOK, do(gw, "GET", "/v1/chats?q=secret", "admin-key", "").Code)
+	do(gw, "GET", "/v1/chats", "nope", "")
+
+	lines := strings.Split(strings.TrimSpace(log.String()), "\n")
+	require.Len(t, lines, 5)
+
+	var entry accessEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &entry))
+	assert.Equal(t, "team-bot", entry.Key)
+	assert.Equal(t, "POST", entry.Method)
+	assert.Equal(t, "/v1/chats/sl1/messages", entry.Path)
+	assert.Equal(t, http.StatusTooManyRequests, entry.Status)
+	assert.Equal(t, "2024-01-01T00:00:00Z", entry.Time)
+
+	assert.NotContains(t, lines[3], "secret", "query strings are not logged")
+	require.NoError(t, json.Unmarshal([]byte(lines[4]), &entry))
+	assert.Equal(t, "-", entry.Key)
+	assert.Equal(t, http.StatusUnauthorized, entry.Status)
+}
+
+func TestUpstreamErrors(t *testing.T) {
+	gw, backend, log := newTestGateway(t)
+
+	backend.err = api.WrapNetworkError(assert.AnError, "list_chats")
+	rec := do(gw, "GET", "/v1/chats", "admin-key", "")
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.JSONEq(t, `{"error":"upstream request failed","category":"network"}`, rec.Body.String())
+	// The operator sees the cause in the access log
+	assert.Contains(t, log.String(), `"error":"failed to connect to API`)
+
+	rec = do(gw, "GET", "/v1/chats/missing", "admin-key", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Anything outside the curated surface is a JSON 404
+	rec = do(gw, "GET", "/v1/accounts", "admin-key", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error":"not found"}`, rec.Body.String())
+	assert.Equal(t, http.StatusNotFound, do(gw, "DELETE", "/v1/chats/sl1", "admin-key", "").Code)
+}
@@ -0,0 +1,106 @@
+package mcp
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Schema is a JSON Schema document
+type Schema map[string]interface{}
+
+// inputSchema derives the schema of a tool's arguments from its Go type.
+// Fields without omitempty are required; a desc struct tag documents a field.
+func inputSchema(v interface{}) Schema {
+	return schemaGenerator{input: true}.schema(reflect.TypeOf(v))
+}
+
+// outputSchema derives the schema of a tool's structured result from its Go
+// type. Results come straight from the API, so no field is required and
+// slices and maps may be null.
+func outputSchema(v interface{}) Schema {
+	return schemaGenerator{}.schema(reflect.TypeOf(v))
+}
+
+type schemaGenerator struct {
+	input bool
+}
+
+func (g schemaGenerator) schema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return g.object(t)
+	case reflect.Slice, reflect.Array:
+		return Schema{"type": g.nullable("array"), "items": g.schema(t.Elem())}
+	case reflect.Map:
+		s := Schema{"type": g.nullable("object")}
+		if t.Elem().Kind() != reflect.Interface {
+			s["additionalProperties"] = g.schema(t.Elem())
+		}
+		return s
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	}
+	// interface{} and anything else accepts any value
+	return Schema{}
+}
+
+// nullable widens a type to allow null in output schemas, where Go encodes
+// nil slices and maps as null
+func (g schemaGenerator) nullable(typ string) interface{} {
+	if g.input {
+		return typ
+	}
+	return []string{typ, "null"}
+}
+
+func (g schemaGenerator) object(t reflect.Type) Schema {
+	properties := Schema{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitempty := jsonName(field)
+		if name == "-" {
+			continue
+		}
+
+		prop := g.schema(field.Type)
+		if desc := field.Tag.Get("desc"); desc != "" {
+			prop["description"] = desc
+		}
+		properties[name] = prop
+		if g.input && !omitempty {
+			required = append(required, name)
+		}
+	}
+
+	s := Schema{"type": "object", "properties": properties}
+	if g.input {
+		s["required"] = required
+		s["additionalProperties"] = false
+	}
+	return s
+}
+
+// jsonName returns the field's JSON name and whether it is omitempty
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	name, opts, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(","+opts+",", ",omitempty,")
+}
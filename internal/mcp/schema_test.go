@@ -0,0 +1,40 @@
+package mcp
+
+import (
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInputSchema(t *testing.T) {
+	s := inputSchema(listMessagesArgs{})
+
+	assert.Equal(t, "object", s["type"])
+	assert.Equal(t, []string{"chatID"}, s["required"])
+	assert.Equal(t, false, s["additionalProperties"])
+
+	props := s["properties"].(Schema)
+	assert.Equal(t, Schema{"type": "string", "description": "Chat ID from list_chats"}, props["chatID"])
+	assert.Equal(t, "integer", props["limit"].(Schema)["type"])
+}
+
+func TestOutputSchemaFollowsAPITypes(t *testing.T) {
+	s := outputSchema(messagesPage{})
+	props := s["properties"].(Schema)
+	assert.NotContains(t, s, "required")
+
+	items := props["items"].(Schema)
+	assert.Equal(t, []string{"array", "null"}, items["type"])
+
+	message := items["items"].(Schema)["properties"].(Schema)
+	assert.Equal(t, Schema{"type": "string"}, message["senderName"])
+	assert.Equal(t, Schema{"type": "boolean"}, message["isSender"])
+	assert.Contains(t, message, "linkedMessageID")
+
+	attachment := message["attachments"].(Schema)["items"].(Schema)["properties"].(Schema)
+	assert.Equal(t, Schema{"type": "integer"}, attachment["fileSize"])
+
+	chat := outputSchema(api.Chat{})["properties"].(Schema)
+	assert.Equal(t, Schema{"type": []string{"object", "null"}}, chat["participants"])
+}
@@ -0,0 +1,214 @@
+// Package mcp serves Beeper as Model Context Protocol tools over stdio, so
+// agents can call the API directly instead of shelling out to the CLI.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ProtocolVersion is the newest MCP revision the server speaks
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the MCP revisions the server accepts, newest first
+var supportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Options configures the server
+type Options struct {
+	// AllowSend exposes send_message. Tools that change state are off by
+	// default so a read-only agent can't act on the user's behalf.
+	AllowSend bool
+	// Version is reported to clients as the server version
+	Version string
+}
+
+// Server answers MCP requests read from a stream, one JSON-RPC message per
+// line as the stdio transport specifies
+type Server struct {
+	opts  Options
+	tools []*tool
+}
+
+// NewServer returns a server exposing the backend's tools
+func NewServer(backend Backend, opts Options) *Server {
+	return &Server{opts: opts, tools: newTools(backend, opts)}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// Serve answers requests from r on w until r is exhausted
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	reader := bufio.NewReader(r)
+	enc := json.NewEncoder(w)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if resp := s.handle(line); resp != nil {
+				if err := enc.Encode(resp); err != nil {
+					return fmt.Errorf("failed to write response: %w", err)
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+	}
+}
+
+// handle answers one message. Notifications get no response.
+func (s *Server) handle(line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		code := codeParseError
+		if json.Valid(line) {
+			// Valid JSON that isn't a request object, e.g. a batch array
+			code = codeInvalidRequest
+		}
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: code, Message: err.Error()}}
+	}
+
+	notification := len(req.ID) == 0
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		if notification {
+			return nil
+		}
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: &rpcError{Code: codeInvalidRequest, Message: "invalid JSON-RPC 2.0 request"}}
+	}
+
+	result, err := s.dispatch(req)
+	if notification {
+		return nil
+	}
+
+	resp := &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+	if err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: codeInternalError, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, rpcErr
+	}
+	return resp
+}
+
+func (s *Server) dispatch(req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req.Params)
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(req.Params)
+	}
+	if strings.HasPrefix(req.Method, "notifications/") {
+		// initialized, cancelled and other notifications need no action
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + req.Method}
+}
+
+// initialize agrees on a protocol version: the client's if supported,
+// otherwise the newest one the server speaks
+func (s *Server) initialize(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid initialize params: " + err.Error()}
+		}
+	}
+	version := ProtocolVersion
+	if slices.Contains(supportedVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+
+	serverVersion := s.opts.Version
+	if serverVersion == "" {
+		serverVersion = "dev"
+	}
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    "beeper",
+			"title":   "Beeper",
+			"version": serverVersion,
+		},
+		"instructions": "Read and search Beeper chats across all connected networks. Chat IDs come from list_chats; page with nextCursor while hasMore is true.",
+	}, nil
+}
+
+func (s *Server) listTools() interface{} {
+	defs := make([]toolDefinition, 0, len(s.tools))
+	for _, t := range s.tools {
+		if t.enabled {
+			defs = append(defs, t.definition)
+		}
+	}
+	return map[string]interface{}{"tools": defs}
+}
+
+func (s *Server) callTool(params json.RawMessage) (interface{}, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments,omitempty"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "invalid tools/call params: " + err.Error()}
+	}
+
+	for _, t := range s.tools {
+		if t.definition.Name != p.Name {
+			continue
+		}
+		if !t.enabled {
+			return errorResult(t.disabledError()), nil
+		}
+		return t.call(p.Arguments), nil
+	}
+	return nil, &rpcError{Code: codeInvalidParams, Message: "unknown tool: " + p.Name}
+}
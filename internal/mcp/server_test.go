@@ -0,0 +1,207 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeBackend struct {
+	chats    []api.Chat
+	messages []api.Message
+	sent     []string
+	err      error
+}
+
+func (f *fakeBackend) ListChatsPage(cursor string) (*api.ChatsResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &api.ChatsResponse{Items: f.chats, HasMore: true, OldestCursor: "c-next"}, nil
+}
+
+func (f *fakeBackend) GetChat(chatID string) (*api.Chat, error) {
+	for _, c := range f.chats {
+		if c.ID == chatID {
+			return &c, nil
+		}
+	}
+	return nil, &api.APIError{Message: "chat not found", Category: api.CategoryNotFound, Operation: "get_chat", Hint: "Use 'beeper chats list'."}
+}
+
+func (f *fakeBackend) ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error) {
+	if limit < len(f.messages) {
+		return &api.MessagesResponse{Items: f.messages[:limit], HasMore: true}, nil
+	}
+	return &api.MessagesResponse{Items: f.messages}, nil
+}
+
+func (f *fakeBackend) SearchMessagesPage(query, cursor string, limit int) (*api.SearchResponse, error) {
+	return &api.SearchResponse{Items: f.messages}, nil
+}
+
+func (f *fakeBackend) SendMessage(chatID, text string) (string, error) {
+	f.sent = append(f.sent, chatID+": "+text)
+	return "m-new", nil
+}
+
+// rpc sends requests through a server and returns the decoded responses
+func rpc(t *testing.T, s *Server, requests ...string) []map[string]interface{} {
+	t.Helper()
+	var out bytes.Buffer
+	require.NoError(t, s.Serve(strings.NewReader(strings.Join(requests, "\n")), &out))
+
+	var responses []map[string]interface{}
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var resp map[string]interface{}
+		require.NoError(t, dec.Decode(&resp))
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func call(t *testing.T, s *Server, name, args string) map[string]interface{} {
+	t.Helper()
+	responses := rpc(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+name+`","arguments":`+args+`}}`)
+	require.Len(t, responses, 1)
+	require.Nil(t, responses[0]["error"])
+	return responses[0]["result"].(map[string]interface{})
+}
+
+// errorBody decodes the JSON text of a failed tool call
+func errorBody(t *testing.T, result map[string]interface{}) toolError {
+	t.Helper()
+	require.Equal(t, true, result["isError"])
+	text := result["content"].([]interface{})[0].(map[string]interface{})["text"].(string)
+	var te toolError
+	require.NoError(t, json.Unmarshal([]byte(text), &te))
+	return te
+}
+
+func TestInitializeAndNotifications(t *testing.T) {
+	s := NewServer(&fakeBackend{}, Options{Version: "1.2.3"})
+
+	responses := rpc(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"two","method":"ping"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`,
+	)
+	require.Len(t, responses, 3)
+
+	result := responses[0]["result"].(map[string]interface{})
+	assert.Equal(t, "2025-03-26", result["protocolVersion"])
+	assert.Equal(t, "1.2.3", result["serverInfo"].(map[string]interface{})["version"])
+	assert.Contains(t, result["capabilities"], "tools")
+
+	assert.Equal(t, "two", responses[1]["id"])
+	assert.Equal(t, map[string]interface{}{}, responses[1]["result"])
+
+	assert.Equal(t, ProtocolVersion, responses[2]["result"].(map[string]interface{})["protocolVersion"])
+}
+
+func TestProtocolErrors(t *testing.T) {
+	s := NewServer(&fakeBackend{}, Options{})
+
+	responses := rpc(t, s,
+		`{not json`,
+		`[{"jsonrpc":"2.0","id":1,"method":"ping"}]`,
+		`{"jsonrpc":"2.0","id":2,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"delete_everything"}}`,
+	)
+	require.Len(t, responses, 4)
+	codes := []float64{}
+	for _, r := range responses {
+		codes = append(codes, r["error"].(map[string]interface{})["code"].(float64))
+	}
+	assert.Equal(t, []float64{codeParseError, codeInvalidRequest, codeMethodNotFound, codeInvalidParams}, codes)
+}
+
+func TestListToolsHidesSendUnlessAllowed(t *testing.T) {
+	names := func(s *Server) []string {
+		responses := rpc(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+		var out []string
+		for _, tool := range responses[0]["result"].(map[string]interface{})["tools"].([]interface{}) {
+			def := tool.(map[string]interface{})
+			out = append(out, def["name"].(string))
+			assert.Contains(t, def, "inputSchema")
+			assert.Contains(t, def, "outputSchema")
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"list_chats", "get_chat", "list_messages", "search_messages"}, names(NewServer(&fakeBackend{}, Options{})))
+	assert.Equal(t, []string{"list_chats", "get_chat", "list_messages", "search_messages", "send_message"}, names(NewServer(&fakeBackend{}, Options{AllowSend: true})))
+}
+
+func TestReadTools(t *testing.T) {
+	b := &fakeBackend{
+		chats:    []api.Chat{{ID: "c1", Title: "Team", Network: "Slack"}, {ID: "c2", Network: "WhatsApp"}},
+		messages: []api.Message{{ID: "m2", SortKey: "2", Text: "hi"}, {ID: "m1", SortKey: "1"}},
+	}
+	s := NewServer(b, Options{})
+
+	result := call(t, s, "list_chats", `{"network":"whatsapp"}`)
+	assert.Equal(t, false, result["isError"])
+	page := result["structuredContent"].(map[string]interface{})
+	assert.Len(t, page["items"], 1)
+	assert.Equal(t, "c-next", page["nextCursor"])
+
+	result = call(t, s, "get_chat", `{"chatID":"c1"}`)
+	assert.Equal(t, "Team", result["structuredContent"].(map[string]interface{})["title"])
+	text := result["content"].([]interface{})[0].(map[string]interface{})["text"].(string)
+	assert.Contains(t, text, `"title":"Team"`)
+
+	result = call(t, s, "list_messages", `{"chatID":"c1","limit":1}`)
+	page = result["structuredContent"].(map[string]interface{})
+	assert.Equal(t, true, page["hasMore"])
+	assert.Equal(t, "2", page["nextCursor"])
+
+	result = call(t, s, "search_messages", `{"query":"hi"}`)
+	assert.Len(t, result["structuredContent"].(map[string]interface{})["items"], 2)
+}
+
+func TestToolErrors(t *testing.T) {
+	b := &fakeBackend{}
+	s := NewServer(b, Options{})
+
+	te := errorBody(t, call(t, s, "get_chat", `{"chatID":"missing"}`))
+	assert.Equal(t, "chat not found", te.Error)
+	assert.Equal(t, "not_found", te.Category)
+	assert.Equal(t, "get_chat", te.Operation)
+	assert.Contains(t, te.Hint, "list_chats")
+	assert.False(t, te.Retryable)
+
+	te = errorBody(t, call(t, s, "list_messages", `{}`))
+	assert.Equal(t, "validation", te.Category)
+	assert.Equal(t, "chatID is required", te.Error)
+
+	te = errorBody(t, call(t, s, "list_chats", `{"bogus":1}`))
+	assert.Contains(t, te.Error, "unknown field")
+
+	b.err = api.WrapNetworkError(assert.AnError, "list_chats")
+	te = errorBody(t, call(t, s, "list_chats", `{}`))
+	assert.Equal(t, "network", te.Category)
+	assert.True(t, te.Retryable)
+	assert.Contains(t, te.Hint, "Beeper Desktop is running")
+}
+
+func TestSendMessageIsOptIn(t *testing.T) {
+	b := &fakeBackend{}
+
+	te := errorBody(t, call(t, NewServer(b, Options{}), "send_message", `{"chatID":"c1","text":"hi"}`))
+	assert.Equal(t, "permission", te.Category)
+	assert.Contains(t, te.Hint, "--allow-send")
+	assert.Empty(t, b.sent)
+
+	result := call(t, NewServer(b, Options{AllowSend: true}), "send_message", `{"chatID":"c1","text":"hi"}`)
+	assert.Equal(t, false, result["isError"])
+	assert.Equal(t, "m-new", result["structuredContent"].(map[string]interface{})["message_id"])
+	assert.Equal(t, []string{"c1: hi"}, b.sent)
+}
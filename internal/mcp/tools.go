@@ -0,0 +1,325 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+)
+
+// Backend is the part of the API client the tools call
+type Backend interface {
+	ListChatsPage(cursor string) (*api.ChatsResponse, error)
+	GetChat(chatID string) (*api.Chat, error)
+	ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error)
+	SearchMessagesPage(query, cursor string, limit int) (*api.SearchResponse, error)
+	SendMessage(chatID, text string) (string, error)
+}
+
+// Tool result limits
+const (
+	defaultMessageLimit = 50
+	maxMessageLimit     = 200
+	defaultSearchLimit  = 20
+	maxSearchLimit      = 20
+)
+
+type toolDefinition struct {
+	Name         string          `json:"name"`
+	Title        string          `json:"title,omitempty"`
+	Description  string          `json:"description"`
+	InputSchema  Schema          `json:"inputSchema"`
+	OutputSchema Schema          `json:"outputSchema,omitempty"`
+	Annotations  toolAnnotations `json:"annotations"`
+}
+
+type toolAnnotations struct {
+	ReadOnlyHint    bool `json:"readOnlyHint"`
+	DestructiveHint bool `json:"destructiveHint"`
+	IdempotentHint  bool `json:"idempotentHint"`
+	OpenWorldHint   bool `json:"openWorldHint"`
+}
+
+// tool is a named operation with typed arguments and result
+type tool struct {
+	definition toolDefinition
+	enabled    bool
+	// flag is the beeper mcp flag that enables a disabled tool
+	flag string
+	call func(args json.RawMessage) interface{}
+}
+
+// newTool wraps fn as a tool whose input and output schemas are derived
+// from its argument and result types
+func newTool[A, R any](def toolDefinition, fn func(A) (R, error)) *tool {
+	var args A
+	var result R
+	def.InputSchema = inputSchema(args)
+	def.OutputSchema = outputSchema(result)
+
+	return &tool{
+		definition: def,
+		enabled:    true,
+		call: func(raw json.RawMessage) interface{} {
+			var args A
+			if len(raw) > 0 && string(raw) != "null" {
+				dec := json.NewDecoder(bytes.NewReader(raw))
+				dec.DisallowUnknownFields()
+				if err := dec.Decode(&args); err != nil {
+					return errorResult(api.NewAPIError("invalid arguments: "+err.Error(), api.CategoryValidation).WithOperation(def.Name))
+				}
+			}
+			result, err := fn(args)
+			if err != nil {
+				return errorResult(err)
+			}
+			return successResult(result)
+		},
+	}
+}
+
+func (t *tool) disabledError() error {
+	return api.NewAPIError(t.definition.Name+" is disabled", api.CategoryPermission).
+		WithOperation(t.definition.Name).
+		WithHint("Ask the user to restart the server with beeper mcp " + t.flag + " to allow it.")
+}
+
+type listChatsArgs struct {
+	Cursor  string `json:"cursor,omitempty" desc:"nextCursor from a previous call, to fetch older chats"`
+	Network string `json:"network,omitempty" desc:"Only return chats on this network (e.g. whatsapp), case-insensitive"`
+}
+
+type getChatArgs struct {
+	ChatID string `json:"chatID" desc:"Chat ID from list_chats"`
+}
+
+type listMessagesArgs struct {
+	ChatID string `json:"chatID" desc:"Chat ID from list_chats"`
+	Cursor string `json:"cursor,omitempty" desc:"nextCursor from a previous call, to fetch older messages"`
+	Limit  int    `json:"limit,omitempty" desc:"Maximum messages to return, newest first (default 50, max 200)"`
+}
+
+type searchMessagesArgs struct {
+	Query string `json:"query" desc:"Text to search for across all chats"`
+	Limit int    `json:"limit,omitempty" desc:"Maximum results to return (default and max 20)"`
+}
+
+type sendMessageArgs struct {
+	ChatID string `json:"chatID" desc:"Chat ID from list_chats"`
+	Text   string `json:"text" desc:"Message text to send"`
+}
+
+// chatsPage is a page of chats; continue with nextCursor while hasMore
+type chatsPage struct {
+	Items      []api.Chat `json:"items"`
+	HasMore    bool       `json:"hasMore"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+// messagesPage is a page of messages; continue with nextCursor while hasMore
+type messagesPage struct {
+	Items      []api.Message `json:"items"`
+	HasMore    bool          `json:"hasMore"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+func newTools(b Backend, opts Options) []*tool {
+	read := toolAnnotations{ReadOnlyHint: true, IdempotentHint: true, OpenWorldHint: true}
+
+	send := newTool(toolDefinition{
+		Name:        "send_message",
+		Title:       "Send message",
+		Description: "Send a text message to a chat as the user. Only call this when the user asked for the message to be sent.",
+		Annotations: toolAnnotations{OpenWorldHint: true},
+	}, func(args sendMessageArgs) (output.SendResult, error) {
+		if err := required("send_message", "chatID", args.ChatID); err != nil {
+			return output.SendResult{}, err
+		}
+		if err := required("send_message", "text", args.Text); err != nil {
+			return output.SendResult{}, err
+		}
+		id, err := b.SendMessage(args.ChatID, args.Text)
+		if err != nil {
+			return output.SendResult{}, err
+		}
+		return output.SendResult{Success: true, MessageID: id, ChatID: args.ChatID}, nil
+	})
+	send.enabled, send.flag = opts.AllowSend, "--allow-send"
+
+	return []*tool{
+		newTool(toolDefinition{
+			Name:        "list_chats",
+			Title:       "List chats",
+			Description: "List chats across all connected networks, most recently active first. Returns one page; call again with nextCursor while hasMore is true.",
+			Annotations: read,
+		}, func(args listChatsArgs) (chatsPage, error) {
+			resp, err := b.ListChatsPage(args.Cursor)
+			if err != nil {
+				return chatsPage{}, err
+			}
+			page := chatsPage{Items: resp.Items, HasMore: resp.HasMore}
+			if resp.HasMore {
+				page.NextCursor = resp.OldestCursor
+			}
+			if args.Network != "" {
+				page.Items = filterNetwork(resp.Items, args.Network)
+			}
+			return page, nil
+		}),
+		newTool(toolDefinition{
+			Name:        "get_chat",
+			Title:       "Get chat",
+			Description: "Get one chat's details, including its participants.",
+			Annotations: read,
+		}, func(args getChatArgs) (api.Chat, error) {
+			if err := required("get_chat", "chatID", args.ChatID); err != nil {
+				return api.Chat{}, err
+			}
+			chat, err := b.GetChat(args.ChatID)
+			if err != nil {
+				return api.Chat{}, err
+			}
+			return *chat, nil
+		}),
+		newTool(toolDefinition{
+			Name:        "list_messages",
+			Title:       "List messages",
+			Description: "List a chat's messages, newest first. Call again with nextCursor while hasMore is true to read further back.",
+			Annotations: read,
+		}, func(args listMessagesArgs) (messagesPage, error) {
+			if err := required("list_messages", "chatID", args.ChatID); err != nil {
+				return messagesPage{}, err
+			}
+			limit, err := clampLimit("list_messages", args.Limit, defaultMessageLimit, maxMessageLimit)
+			if err != nil {
+				return messagesPage{}, err
+			}
+			resp, err := b.ListMessagesPage(args.ChatID, args.Cursor, limit)
+			if err != nil {
+				return messagesPage{}, err
+			}
+			page := messagesPage{Items: resp.Items, HasMore: resp.HasMore}
+			if resp.HasMore {
+				page.NextCursor = resp.NextCursor()
+			}
+			return page, nil
+		}),
+		newTool(toolDefinition{
+			Name:        "search_messages",
+			Title:       "Search messages",
+			Description: "Search message text across all chats. Results include each message's chatID.",
+			Annotations: read,
+		}, func(args searchMessagesArgs) (messagesPage, error) {
+			if err := required("search_messages", "query", args.Query); err != nil {
+				return messagesPage{}, err
+			}
+			limit, err := clampLimit("search_messages", args.Limit, defaultSearchLimit, maxSearchLimit)
+			if err != nil {
+				return messagesPage{}, err
+			}
+			resp, err := b.SearchMessagesPage(args.Query, "", limit)
+			if err != nil {
+				return messagesPage{}, err
+			}
+			return messagesPage{Items: resp.Items, HasMore: resp.HasMore}, nil
+		}),
+		send,
+	}
+}
+
+func filterNetwork(chats []api.Chat, network string) []api.Chat {
+	selected := []api.Chat{}
+	for _, chat := range chats {
+		if strings.EqualFold(chat.Network, network) {
+			selected = append(selected, chat)
+		}
+	}
+	return selected
+}
+
+func required(operation, name, value string) error {
+	if strings.TrimSpace(value) == "" {
+		return api.NewAPIError(name+" is required", api.CategoryValidation).WithOperation(operation)
+	}
+	return nil
+}
+
+// clampLimit applies the default to an unset limit and rejects negative ones.
+// Limits above the maximum are lowered rather than refused.
+func clampLimit(operation string, limit, def, maxLimit int) (int, error) {
+	switch {
+	case limit < 0:
+		return 0, api.NewAPIError("limit must not be negative", api.CategoryValidation).WithOperation(operation)
+	case limit == 0:
+		return def, nil
+	case limit > maxLimit:
+		return maxLimit, nil
+	}
+	return limit, nil
+}
+
+// toolError is the body of a failed tool call. It carries the APIError
+// fields an agent needs to decide whether to retry, fix its arguments or
+// ask the user for help.
+type toolError struct {
+	Error     string `json:"error"`
+	Code      string `json:"code,omitempty"`
+	Category  string `json:"category"`
+	Operation string `json:"operation,omitempty"`
+	Hint      string `json:"hint,omitempty"`
+	// Retryable is true for network and server errors, which may pass
+	Retryable bool `json:"retryable"`
+}
+
+// newToolError maps an error to a tool error. CLI hints that point at
+// beeper commands are replaced with ones that point at tools.
+func newToolError(err error) toolError {
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		return toolError{Error: err.Error(), Category: string(api.CategoryUnknown)}
+	}
+
+	te := toolError{
+		Error:     apiErr.Message,
+		Code:      apiErr.Code,
+		Category:  string(apiErr.Category),
+		Operation: apiErr.Operation,
+		Hint:      apiErr.Hint,
+		Retryable: apiErr.Category == api.CategoryNetwork || apiErr.Category == api.CategoryServer,
+	}
+	switch apiErr.Category {
+	case api.CategoryNotFound:
+		te.Hint = "Verify the ID is correct; list_chats returns valid chat IDs."
+	case api.CategoryValidation:
+		te.Hint = "Check the arguments against the tool's input schema."
+	}
+	return te
+}
+
+// errorResult is a tool result reporting a failure to the model, rather than
+// a protocol error, so the agent can see what went wrong and recover
+func errorResult(err error) interface{} {
+	data, _ := json.Marshal(newToolError(err))
+	return map[string]interface{}{
+		"content": []map[string]interface{}{{"type": "text", "text": string(data)}},
+		"isError": true,
+	}
+}
+
+// successResult returns the result both as structured content and, for
+// clients that predate structured content, as JSON text
+func successResult(result interface{}) interface{} {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResult(fmt.Errorf("failed to marshal result: %w", err))
+	}
+	return map[string]interface{}{
+		"content":           []map[string]interface{}{{"type": "text", "text": string(data)}},
+		"structuredContent": result,
+		"isError":           false,
+	}
+}
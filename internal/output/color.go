@@ -0,0 +1,113 @@
+package output
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Values accepted by --color
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// ANSI SGR parameters
+const (
+	ansiBold      = "1"
+	ansiDim       = "2"
+	ansiReverse   = "7"
+	ansiBoldGreen = "1;32"
+	ansiRed       = "31"
+)
+
+// senderPalette holds the colors senders are assigned from. Black, white and
+// their bright variants are left out so names stay readable on any background.
+var senderPalette = []string{"31", "32", "33", "34", "35", "36", "91", "92", "93", "94", "95", "96"}
+
+// ColorEnabled decides whether output written to f should be colored. In
+// auto mode color is used only when f is a terminal, NO_COLOR is unset and
+// TERM is not "dumb"; always and never override detection.
+func ColorEnabled(mode string, f *os.File) (bool, error) {
+	switch mode {
+	case ColorAlways:
+		return true, nil
+	case ColorNever:
+		return false, nil
+	case "", ColorAuto:
+		if os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+			return false, nil
+		}
+		return isTerminal(f), nil
+	default:
+		return false, fmt.Errorf("invalid color mode: %s (must be auto, always or never)", mode)
+	}
+}
+
+// isTerminal reports whether f is a terminal, rather than a pipe, a regular
+// file or another character device such as /dev/null
+func isTerminal(f *os.File) bool {
+	return f != nil && term.IsTerminal(int(f.Fd()))
+}
+
+// paint wraps s in the given SGR parameters
+func paint(s, code string) string {
+	if s == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// paintLines paints each line of s separately, so pagers that reset
+// attributes at line breaks keep the color
+func paintLines(s, code string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = paint(line, code)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// senderColor returns the color for a sender. It depends only on the name,
+// so a sender keeps the same color across chats and runs.
+func senderColor(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return senderPalette[h.Sum32()%uint32(len(senderPalette))]
+}
+
+// paintSender colors a sender name with its stable color
+func paintSender(name string) string {
+	return paint(name, ansiBold+";"+senderColor(name))
+}
+
+// paintUnread highlights a non-zero unread count
+func paintUnread(count string, unread int) string {
+	if unread == 0 {
+		return count
+	}
+	return paint(count, ansiBoldGreen)
+}
+
+// highlightMatches reverses the video of every case-insensitive occurrence
+// of the given terms in text
+func highlightMatches(text string, terms []string) string {
+	var quoted []string
+	for _, term := range terms {
+		if term != "" {
+			quoted = append(quoted, regexp.QuoteMeta(term))
+		}
+	}
+	if len(quoted) == 0 {
+		return text
+	}
+	re := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	return re.ReplaceAllStringFunc(text, func(match string) string {
+		return paint(match, ansiReverse)
+	})
+}
@@ -0,0 +1,101 @@
+package output
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColorEnabled(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("TERM", "xterm")
+
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	require.NoError(t, err)
+	defer f.Close()
+
+	on, err := ColorEnabled(ColorAuto, f)
+	require.NoError(t, err)
+	assert.False(t, on, "regular files are not terminals")
+
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.NoError(t, err)
+	defer null.Close()
+	on, err = ColorEnabled(ColorAuto, null)
+	require.NoError(t, err)
+	assert.False(t, on, "/dev/null is a character device, not a terminal")
+
+	on, err = ColorEnabled(ColorAlways, f)
+	require.NoError(t, err)
+	assert.True(t, on)
+
+	t.Setenv("NO_COLOR", "1")
+	on, err = ColorEnabled(ColorNever, f)
+	require.NoError(t, err)
+	assert.False(t, on)
+
+	_, err = ColorEnabled("sometimes", f)
+	assert.ErrorContains(t, err, "invalid color mode: sometimes")
+}
+
+func TestSenderColorIsStable(t *testing.T) {
+	assert.Equal(t, senderColor("Alice"), senderColor("Alice"))
+	assert.Contains(t, senderPalette, senderColor("Bob"))
+}
+
+func TestHighlightMatches(t *testing.T) {
+	assert.Equal(t, "see \x1b[7mLunch\x1b[0m at \x1b[7mnoon\x1b[0m", highlightMatches("see Lunch at noon", []string{"lunch", "noon"}))
+	assert.Equal(t, "a.b", highlightMatches("a.b", nil))
+	// Terms are matched literally
+	assert.Equal(t, "ab", highlightMatches("ab", []string{"."}))
+}
+
+func TestColorOffByDefault(t *testing.T) {
+	result := FormatMessages(testMessages, FormatText) + FormatChats(testChats, FormatTable)
+	assert.NotContains(t, result, "\x1b[")
+}
+
+func TestColoredText(t *testing.T) {
+	chats := []api.Chat{
+		{ID: "c1", Title: "Loud", UnreadCount: 3},
+		{ID: "c2", Title: "Quiet", UnreadCount: 1, IsMuted: true},
+	}
+	result := FormatChatsWithOptions(chats, FormatText, Options{Color: true})
+	assert.Contains(t, result, "Unread: \x1b[1;32m3\x1b[0m")
+	assert.Contains(t, result, "\x1b[2mTitle: Quiet\x1b[0m")
+
+	msg := api.Message{Sender: "Alice", Text: "lunch?", Timestamp: "2021-12-20T10:00:00Z"}
+	result = FormatMessagesWithOptions([]api.Message{msg}, FormatText, Options{Color: true, Highlight: []string{"lunch"}})
+	assert.Contains(t, result, paintSender("Alice")+": \x1b[7mlunch\x1b[0m?")
+}
+
+func TestColoredTableKeepsAlignment(t *testing.T) {
+	chats := []api.Chat{
+		{ID: "c1", Title: "A", Network: "whatsapp", UnreadCount: 12},
+		{ID: "chat-two", Title: "Muted", Network: "signal", IsMuted: true},
+	}
+	plain := FormatChats(chats, FormatTable)
+	colored := FormatChatsWithOptions(chats, FormatTable, Options{Color: true})
+
+	assert.Contains(t, colored, "\x1b[1;32m12\x1b[0m")
+	assert.Equal(t, plain, stripANSI(colored))
+}
+
+// stripANSI removes SGR escape sequences
+func stripANSI(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\x1b' {
+			for i < len(s) && s[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,77 @@
+package output
+
+import (
+	"encoding/csv"
+	"io"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+func init() {
+	Register(Registration{
+		Name:        FormatCSV,
+		Description: "Comma-separated values with a header row",
+		Formatter:   delimitedFormatter{comma: ','},
+	})
+	Register(Registration{
+		Name:        FormatTSV,
+		Description: "Tab-separated values with a header row",
+		Formatter:   delimitedFormatter{comma: '\t'},
+	})
+}
+
+// delimitedFormatter writes results as delimiter-separated values. Unlike
+// the table, it includes every column unless --columns narrows them.
+type delimitedFormatter struct {
+	comma rune
+}
+
+func (f delimitedFormatter) WriteChats(w io.Writer, chats []api.Chat, opts Options) error {
+	return renderDelimited(w, chats, selectColumns(chatColumns, opts.Columns, ChatColumns), f.comma)
+}
+
+func (f delimitedFormatter) WriteMessages(w io.Writer, messages []api.Message, opts Options) error {
+	return renderDelimited(w, messages, selectColumns(messageColumnsFor(opts), opts.Columns, MessageColumns), f.comma)
+}
+
+func (f delimitedFormatter) WriteAccounts(w io.Writer, accounts []api.Account, opts Options) error {
+	return renderDelimited(w, accounts, selectColumns(accountColumns, opts.Columns, AccountColumns), f.comma)
+}
+
+func (delimitedFormatter) WriteSendResult(w io.Writer, result SendResult, _ Options) error {
+	return writeSendText(w, result)
+}
+
+// WriteInbox writes one row per unread message; the summary is left out so
+// the output stays a plain table
+func (f delimitedFormatter) WriteInbox(w io.Writer, inbox Inbox, opts Options) error {
+	return renderDelimited(w, inboxRows(inbox), selectColumns(inboxColumnsFor(opts), opts.Columns, InboxColumns), f.comma)
+}
+
+// renderDelimited writes items as delimiter-separated values with a header
+// row of column names. Fields containing the delimiter, quotes or newlines
+// are quoted per RFC 4180, so multi-line message text survives intact.
+func renderDelimited[T any](w io.Writer, items []T, cols []column[T], comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+
+	record := make([]string, len(cols))
+	for i, col := range cols {
+		record[i] = col.name
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		for i, col := range cols {
+			record[i] = col.value(item)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,52 @@
+package output
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFormatChatsCSV tests CSV output includes a header and every column by default
+func TestFormatChatsCSV(t *testing.T) {
+	result := FormatChats(testChats, FormatCSV)
+
+	records, err := csv.NewReader(strings.NewReader(result)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, ChatColumns, records[0])
+	assert.Equal(t, "chat1", records[1][0])
+	assert.Equal(t, "Test Chat 1", records[1][1])
+}
+
+// TestFormatMessagesCSV_MultiLine tests RFC 4180 quoting of multi-line text
+func TestFormatMessagesCSV_MultiLine(t *testing.T) {
+	msg := api.Message{
+		ID:     "msg1",
+		Sender: "Alice",
+		Text:   "line one\nline \"two\", with comma",
+	}
+
+	result := FormatMessagesWithOptions([]api.Message{msg}, FormatCSV, Options{Columns: []string{"sender", "text"}})
+	assert.Contains(t, result, "\"line one\nline \"\"two\"\", with comma\"")
+
+	records, err := csv.NewReader(strings.NewReader(result)).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"sender", "text"}, {"Alice", msg.Text}}, records)
+}
+
+// TestFormatMessagesTSV tests tab-separated output
+func TestFormatMessagesTSV(t *testing.T) {
+	result := FormatMessagesWithOptions(testMessages, FormatTSV, Options{Columns: []string{"id", "sender"}})
+
+	assert.Equal(t, "id\tsender\nmsg1\tAlice\nmsg2\tBob\n", result)
+}
+
+// TestFormatEmptyCSV tests that empty results still produce a header row
+func TestFormatEmptyCSV(t *testing.T) {
+	result := FormatChatsWithOptions([]api.Chat{}, FormatCSV, Options{Columns: []string{"id", "title"}})
+	assert.Equal(t, "id,title\n", result)
+}
@@ -0,0 +1,50 @@
+package output
+
+import (
+	"io"
+	"time"
+)
+
+// Envelope wraps a result set with the metadata consumers need to tell
+// whether it is complete. Commands fill in everything except Items and
+// Count, which are set from the formatted results.
+type Envelope struct {
+	Items          interface{} `json:"items"`
+	HasMore        bool        `json:"hasMore"`
+	NextCursor     string      `json:"nextCursor"`
+	Count          int         `json:"count"`
+	FetchedAt      string      `json:"fetchedAt"`
+	DesktopVersion string      `json:"desktopVersion"`
+	Command        string      `json:"command"`
+}
+
+// NewEnvelope returns an envelope for a command's results, stamped with the
+// current time
+func NewEnvelope(command, desktopVersion string, hasMore bool, nextCursor string) *Envelope {
+	return &Envelope{
+		HasMore:        hasMore,
+		NextCursor:     nextCursor,
+		FetchedAt:      now().UTC().Format(time.RFC3339),
+		DesktopVersion: desktopVersion,
+		Command:        command,
+	}
+}
+
+// writeEnvelope writes items, projected to opts.Fields if given, inside a
+// copy of opts.Envelope
+func writeEnvelope[T any](w io.Writer, items []T, format Format, opts Options) error {
+	env := *opts.Envelope
+	env.Count = len(items)
+	if len(opts.Fields) > 0 {
+		projected, err := projectItems(items, opts.Fields)
+		if err != nil {
+			return err
+		}
+		env.Items = projected
+	} else if items == nil {
+		env.Items = []T{}
+	} else {
+		env.Items = items
+	}
+	return WriteValue(w, env, format)
+}
@@ -0,0 +1,53 @@
+package output
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteChatsEnvelope(t *testing.T) {
+	freezeNow(t, time.Date(2021, 12, 20, 10, 0, 0, 0, time.FixedZone("CET", 3600)))
+	opts := Options{Envelope: NewEnvelope("chats list", "4.1.0", true, "cursor-1")}
+
+	var env map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(FormatChatsWithOptions(testChats, FormatJSON, opts)), &env))
+
+	assert.Len(t, env["items"], 2)
+	assert.Equal(t, true, env["hasMore"])
+	assert.Equal(t, "cursor-1", env["nextCursor"])
+	assert.Equal(t, float64(2), env["count"])
+	assert.Equal(t, "2021-12-20T09:00:00Z", env["fetchedAt"])
+	assert.Equal(t, "4.1.0", env["desktopVersion"])
+	assert.Equal(t, "chats list", env["command"])
+}
+
+func TestEnvelopeWithFieldsAndWhere(t *testing.T) {
+	where, err := query.Parse("unreadCount > 0")
+	require.NoError(t, err)
+	opts := Options{
+		Envelope: NewEnvelope("chats list", "", false, ""),
+		Where:    where,
+		Fields:   []string{"id"},
+	}
+
+	result := FormatChatsWithOptions(testChats, FormatNDJSON, opts)
+	assert.Equal(t, 1, strings.Count(result, "\n"), "ndjson envelopes are a single line")
+	assert.Contains(t, result, `"items":[{"id":"chat1"}]`)
+	assert.Contains(t, result, `"count":1`)
+}
+
+func TestEnvelopeEmptyItems(t *testing.T) {
+	opts := Options{Envelope: NewEnvelope("accounts list", "", false, "")}
+	assert.Contains(t, FormatAccountsWithOptions(nil, FormatYAML, opts), "items: []")
+}
+
+func TestEnvelopeIgnoredByHumanFormats(t *testing.T) {
+	opts := Options{Envelope: NewEnvelope("chats list", "", true, "c")}
+	assert.Equal(t, FormatChats(testChats, FormatText), FormatChatsWithOptions(testChats, FormatText, opts))
+}
@@ -3,9 +3,13 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/query"
 )
 
 // Format defines the output format type
@@ -13,140 +17,225 @@ type Format string
 
 const (
 	FormatJSON     Format = "json"
+	FormatNDJSON   Format = "ndjson"
 	FormatText     Format = "text"
 	FormatMarkdown Format = "markdown"
+	FormatTable    Format = "table"
+	FormatCSV      Format = "csv"
+	FormatTSV      Format = "tsv"
+	FormatYAML     Format = "yaml"
+	FormatHTML     Format = "html"
+	FormatTemplate Format = "template"
+	FormatLLM      Format = "llm"
 )
 
+// Streams reports whether the format can be written incrementally, one page
+// of results at a time, without buffering the complete result set
+func (f Format) Streams() bool {
+	r, ok := lookupRegistration(f)
+	return ok && r.Streams
+}
+
+// IsStructured reports whether the format is a machine-readable data format
+// that WriteValue can produce
+func (f Format) IsStructured() bool {
+	return f == FormatJSON || f == FormatNDJSON || f == FormatYAML
+}
+
+// Formatter renders each kind of result in one output format. Formatters
+// return an error for result types they cannot represent.
+type Formatter interface {
+	WriteChats(w io.Writer, chats []api.Chat, opts Options) error
+	WriteMessages(w io.Writer, messages []api.Message, opts Options) error
+	WriteAccounts(w io.Writer, accounts []api.Account, opts Options) error
+	WriteSendResult(w io.Writer, result SendResult, opts Options) error
+	WriteInbox(w io.Writer, inbox Inbox, opts Options) error
+}
+
+// RecordFormatter is implemented by formatters that can also write projected
+// records (the result of --fields), which only carry JSON field names
+type RecordFormatter interface {
+	WriteRecords(w io.Writer, records []query.Projection, opts Options) error
+}
+
+// Options holds formatting settings that apply across result types
+type Options struct {
+	// Columns selects and orders the columns for tabular formats (table,
+	// csv, tsv). An empty list uses the defaults for the result type.
+	Columns []string
+	// Template renders results when the format is FormatTemplate
+	Template *template.Template
+	// Where filters results before formatting (nil keeps everything)
+	Where *query.Expr
+	// Fields projects results to the named JSON fields (empty keeps all)
+	Fields []string
+	// Title is the heading of documents such as HTML transcripts
+	Title string
+	// AttachmentsDir is where HTML transcripts link attachments, relative to
+	// the transcript (defaults to DefaultAttachmentsDir)
+	AttachmentsDir string
+	// Location is the time zone message timestamps are shown in (nil keeps
+	// the zone the API reported)
+	Location *time.Location
+	// TimeFormat is a --time-format value: rfc3339, short, relative or a Go
+	// layout. Empty prints timestamps as the API sent them.
+	TimeFormat string
+	// Color enables ANSI colors in text and table output
+	Color bool
+	// Highlight lists terms to highlight in message text when Color is set,
+	// such as the words of a search query
+	Highlight []string
+	// MaxTokens limits llm output to about this many tokens by leaving out
+	// the oldest messages, or the last chats and accounts (0 means no limit)
+	MaxTokens int
+	// Envelope, if set, wraps json, ndjson and yaml results in a copy of it
+	// so consumers can see whether more results are available
+	Envelope *Envelope
+}
+
+// SendResult is the result of sending a message
+type SendResult struct {
+	Success   bool   `json:"success"`
+	MessageID string `json:"message_id"`
+	ChatID    string `json:"chat_id"`
+}
+
 // FormatChats formats a list of chats according to the specified format
 func FormatChats(chats []api.Chat, format Format) string {
-	if len(chats) == 0 {
-		switch format {
-		case FormatJSON:
-			return "[]\n"
-		case FormatText, FormatMarkdown:
-			return "No chats found.\n"
-		}
-	}
+	return FormatChatsWithOptions(chats, format, Options{})
+}
 
-	switch format {
-	case FormatJSON:
-		data, err := formatChatsJSON(chats)
-		if err != nil {
-			return fmt.Sprintf("Error formatting JSON: %v\n", err)
-		}
-		return data
-	case FormatText:
-		return formatChatsText(chats)
-	case FormatMarkdown:
-		return formatChatsMarkdown(chats)
-	default:
-		// Default to JSON for unknown formats
-		data, _ := formatChatsJSON(chats)
-		return data
+// FormatChatsWithOptions formats a list of chats using the given options
+func FormatChatsWithOptions(chats []api.Chat, format Format, opts Options) string {
+	var sb strings.Builder
+	if err := WriteChats(&sb, chats, format, opts); err != nil {
+		return fmt.Sprintf("Error formatting %s: %v\n", format, err)
 	}
+	return sb.String()
 }
 
-func formatChatsJSON(chats []api.Chat) (string, error) {
-	data, err := json.MarshalIndent(chats, "", "  ")
+// WriteChats writes a list of chats to w according to the specified format
+func WriteChats(w io.Writer, chats []api.Chat, format Format, opts Options) error {
+	f, err := Lookup(format)
+	if err != nil {
+		return err
+	}
+	chats, err = filterItems(chats, opts.Where)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+		return err
+	}
+	if opts.Envelope != nil && format.IsStructured() {
+		return writeEnvelope(w, chats, format, opts)
 	}
-	return string(data), nil
+	if len(opts.Fields) > 0 {
+		return writeProjected(w, chats, f, format, opts)
+	}
+	return f.WriteChats(w, chats, opts)
+}
+
+// FormatMessages formats a list of messages according to the specified format
+func FormatMessages(messages []api.Message, format Format) string {
+	return FormatMessagesWithOptions(messages, format, Options{})
 }
 
-func formatChatsText(chats []api.Chat) string {
+// FormatMessagesWithOptions formats a list of messages using the given options
+func FormatMessagesWithOptions(messages []api.Message, format Format, opts Options) string {
 	var sb strings.Builder
-	for _, chat := range chats {
-		sb.WriteString(fmt.Sprintf("ID: %s\n", chat.ID))
-		sb.WriteString(fmt.Sprintf("Title: %s\n", chat.Title))
-		sb.WriteString(fmt.Sprintf("Type: %s\n", chat.Type))
-		sb.WriteString(fmt.Sprintf("Network: %s\n", chat.Network))
-		sb.WriteString(fmt.Sprintf("Unread: %d\n", chat.UnreadCount))
-		if chat.IsMuted {
-			sb.WriteString("Muted: Yes\n")
-		}
-		sb.WriteString("\n")
+	if err := WriteMessages(&sb, messages, format, opts); err != nil {
+		return fmt.Sprintf("Error formatting %s: %v\n", format, err)
 	}
 	return sb.String()
 }
 
-func formatChatsMarkdown(chats []api.Chat) string {
+// WriteMessages writes a list of messages to w according to the specified format
+func WriteMessages(w io.Writer, messages []api.Message, format Format, opts Options) error {
+	f, err := Lookup(format)
+	if err != nil {
+		return err
+	}
+	messages, err = filterItems(messages, opts.Where)
+	if err != nil {
+		return err
+	}
+	if opts.Envelope != nil && format.IsStructured() {
+		return writeEnvelope(w, messages, format, opts)
+	}
+	if len(opts.Fields) > 0 {
+		return writeProjected(w, messages, f, format, opts)
+	}
+	return f.WriteMessages(w, messages, opts)
+}
+
+// FormatAccounts formats a list of accounts according to the specified format
+func FormatAccounts(accounts []api.Account, format Format) string {
+	return FormatAccountsWithOptions(accounts, format, Options{})
+}
+
+// FormatAccountsWithOptions formats a list of accounts using the given options
+func FormatAccountsWithOptions(accounts []api.Account, format Format, opts Options) string {
 	var sb strings.Builder
-	sb.WriteString("# Chats\n\n")
-	for _, chat := range chats {
-		title := chat.Title
-		if title == "" {
-			title = chat.ID
-		}
-		sb.WriteString(fmt.Sprintf("## %s\n\n", title))
-		sb.WriteString(fmt.Sprintf("- **ID**: %s\n", chat.ID))
-		sb.WriteString(fmt.Sprintf("- **Type**: %s\n", chat.Type))
-		sb.WriteString(fmt.Sprintf("- **Network**: %s\n", chat.Network))
-		sb.WriteString(fmt.Sprintf("- **Unread**: %d\n\n", chat.UnreadCount))
+	if err := WriteAccounts(&sb, accounts, format, opts); err != nil {
+		return fmt.Sprintf("Error formatting %s: %v\n", format, err)
 	}
 	return sb.String()
 }
 
-// FormatMessages formats a list of messages according to the specified format
-func FormatMessages(messages []api.Message, format Format) string {
-	if len(messages) == 0 {
-		switch format {
-		case FormatJSON:
-			return "[]\n"
-		case FormatText, FormatMarkdown:
-			return "No messages found.\n"
-		}
+// WriteAccounts writes a list of accounts to w according to the specified format
+func WriteAccounts(w io.Writer, accounts []api.Account, format Format, opts Options) error {
+	f, err := Lookup(format)
+	if err != nil {
+		return err
 	}
-
-	switch format {
-	case FormatJSON:
-		data, err := formatMessagesJSON(messages)
-		if err != nil {
-			return fmt.Sprintf("Error formatting JSON: %v\n", err)
-		}
-		return data
-	case FormatText:
-		return formatMessagesText(messages)
-	case FormatMarkdown:
-		return formatMessagesMarkdown(messages)
-	default:
-		// Default to JSON
-		data, _ := formatMessagesJSON(messages)
-		return data
+	accounts, err = filterItems(accounts, opts.Where)
+	if err != nil {
+		return err
+	}
+	if opts.Envelope != nil && format.IsStructured() {
+		return writeEnvelope(w, accounts, format, opts)
 	}
+	if len(opts.Fields) > 0 {
+		return writeProjected(w, accounts, f, format, opts)
+	}
+	return f.WriteAccounts(w, accounts, opts)
 }
 
-func formatMessagesJSON(messages []api.Message) (string, error) {
-	data, err := json.MarshalIndent(messages, "", "  ")
+// WriteSendResult writes the result of a send according to the specified format
+func WriteSendResult(w io.Writer, result SendResult, format Format, opts Options) error {
+	f, err := Lookup(format)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+		return err
 	}
-	return string(data), nil
+	return f.WriteSendResult(w, result, opts)
 }
 
-func formatMessagesText(messages []api.Message) string {
-	var sb strings.Builder
-	for _, msg := range messages {
-		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n",
-			msg.Timestamp,
-			msg.Sender,
-			msg.Text,
-		))
+// WriteValue writes an arbitrary structured value, such as a report, in a
+// structured format (json, ndjson or yaml)
+func WriteValue(w io.Writer, v interface{}, format Format) error {
+	switch format {
+	case FormatYAML:
+		return writeYAML(w, v)
+	case FormatNDJSON:
+		return json.NewEncoder(w).Encode(v)
+	default:
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
 	}
-	return sb.String()
 }
 
-func formatMessagesMarkdown(messages []api.Message) string {
-	var sb strings.Builder
-	sb.WriteString("# Messages\n\n")
-	for _, msg := range messages {
-		sb.WriteString(fmt.Sprintf("**%s** - %s\n\n",
-			msg.Sender,
-			msg.Timestamp,
-		))
-		sb.WriteString(fmt.Sprintf("> %s\n\n", msg.Text))
-		sb.WriteString("---\n\n")
-	}
-	return sb.String()
+// writeEmpty writes the placeholder that human-readable formats show for an
+// empty result set
+func writeEmpty(w io.Writer, noun string) error {
+	_, err := fmt.Fprintf(w, "No %s found.\n", noun)
+	return err
 }
 
+// writeSendText writes the plain confirmation used by formats that have no
+// representation of their own for a send result
+func writeSendText(w io.Writer, result SendResult) error {
+	_, err := fmt.Fprintf(w, "Message sent successfully. ID: %s\n", result.MessageID)
+	return err
+}
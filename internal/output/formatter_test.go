@@ -1,11 +1,14 @@
 package output
 
 import (
+	"bytes"
+	"encoding/json"
 	"strings"
 	"testing"
 
 	"github.com/nerveband/beeper-api-cli/internal/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Test data
@@ -139,11 +142,44 @@ func TestFormatEmptyMessages(t *testing.T) {
 	assert.Contains(t, mdResult, "No messages found")
 }
 
+// TestFormatMessagesNDJSON tests one compact JSON object per line
+func TestFormatMessagesNDJSON(t *testing.T) {
+	result := FormatMessages(testMessages, FormatNDJSON)
+
+	lines := strings.Split(strings.TrimRight(result, "\n"), "\n")
+	require.Len(t, lines, 2)
+	for i, line := range lines {
+		var msg api.Message
+		require.NoError(t, json.Unmarshal([]byte(line), &msg))
+		assert.Equal(t, testMessages[i].ID, msg.ID)
+		assert.NotContains(t, line, "\n  ")
+	}
+
+	assert.Empty(t, FormatMessages([]api.Message{}, FormatNDJSON))
+}
+
+// TestWriteChats_Incremental tests that pages written in sequence form valid NDJSON
+func TestWriteChats_Incremental(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteChats(&buf, testChats[:1], FormatNDJSON, Options{}))
+	require.NoError(t, WriteChats(&buf, testChats[1:], FormatNDJSON, Options{}))
+
+	dec := json.NewDecoder(&buf)
+	var ids []string
+	for dec.More() {
+		var chat api.Chat
+		require.NoError(t, dec.Decode(&chat))
+		ids = append(ids, chat.ID)
+	}
+	assert.Equal(t, []string{"chat1", "chat2"}, ids)
+}
+
 // TestFormatInvalidFormat tests handling of invalid format
 func TestFormatInvalidFormat(t *testing.T) {
 	result := FormatChats(testChats, Format("invalid"))
-	// Should default to JSON
-	assert.Contains(t, result, "[")
+	// Should report the registered formats instead of guessing
+	assert.Contains(t, result, "invalid output format: invalid")
+	assert.Contains(t, result, "(available: csv, html, json")
 }
 
 // TestFormatChatName tests chat name formatting edge cases
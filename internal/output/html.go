@@ -0,0 +1,273 @@
+package output
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// DefaultAttachmentsDir is the directory, relative to an HTML transcript,
+// that attachment links point into
+const DefaultAttachmentsDir = "attachments"
+
+//go:embed templates/transcript.html.tmpl templates/inbox.html.tmpl
+var templateFS embed.FS
+
+var (
+	transcriptTemplate = template.Must(template.ParseFS(templateFS, "templates/transcript.html.tmpl"))
+	inboxTemplate      = template.Must(template.ParseFS(templateFS, "templates/inbox.html.tmpl"))
+)
+
+func init() {
+	Register(Registration{
+		Name:        FormatHTML,
+		Description: "Standalone HTML transcript (messages and inbox only)",
+		Formatter:   htmlFormatter{},
+	})
+}
+
+// htmlFormatter writes message transcripts as standalone HTML pages
+type htmlFormatter struct{}
+
+func (htmlFormatter) WriteChats(io.Writer, []api.Chat, Options) error {
+	return fmt.Errorf("html output is only supported for messages")
+}
+
+func (htmlFormatter) WriteMessages(w io.Writer, messages []api.Message, opts Options) error {
+	return writeMessagesHTML(w, messages, opts)
+}
+
+func (htmlFormatter) WriteAccounts(io.Writer, []api.Account, Options) error {
+	return fmt.Errorf("html output is only supported for messages")
+}
+
+func (htmlFormatter) WriteSendResult(w io.Writer, result SendResult, _ Options) error {
+	return writeSendText(w, result)
+}
+
+func (htmlFormatter) WriteInbox(w io.Writer, inbox Inbox, opts Options) error {
+	return writeInboxHTML(w, inbox, opts)
+}
+
+// transcriptView is the data passed to the HTML transcript template
+type transcriptView struct {
+	Title     string
+	Generated string
+	Count     int
+	Days      []transcriptDay
+}
+
+type transcriptDay struct {
+	Label  string
+	Groups []transcriptGroup
+}
+
+// transcriptGroup is a run of consecutive messages from one sender
+type transcriptGroup struct {
+	Sender   string
+	IsSender bool
+	Messages []transcriptMessage
+}
+
+type transcriptMessage struct {
+	ID          string
+	Time        string
+	Text        string
+	Attachments []transcriptAttachment
+	Reactions   []reactionCount
+}
+
+type transcriptAttachment struct {
+	Name    string
+	Path    string
+	IsImage bool
+}
+
+type reactionCount struct {
+	Key   string
+	Count int
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// AttachmentFileName returns the file name an attachment is stored under in
+// the attachments directory: the message ID, the attachment's index and its
+// original name, reduced to characters that are safe on every file system
+func AttachmentFileName(msg api.Message, index int) string {
+	name := path.Base(msg.Attachments[index].FileName)
+	if name == "" || name == "." || name == "/" {
+		name = "attachment"
+	}
+	return unsafeFileChars.ReplaceAllString(msg.ID+"_"+strconv.Itoa(index)+"_"+name, "_")
+}
+
+// writeMessagesHTML writes a standalone HTML transcript. Messages are shown
+// oldest first, grouped by day and by consecutive sender.
+func writeMessagesHTML(w io.Writer, messages []api.Message, opts Options) error {
+	dir := opts.AttachmentsDir
+	if dir == "" {
+		dir = DefaultAttachmentsDir
+	}
+
+	title := opts.Title
+	if title == "" {
+		title = "Conversation transcript"
+	}
+
+	view := transcriptView{
+		Title:     title,
+		Generated: now().Format("2006-01-02 15:04"),
+		Count:     len(messages),
+	}
+
+	for _, msg := range chronological(messages) {
+		ts, hasTime := localTime(msg, opts)
+		dayLabel := "Unknown date"
+		timeLabel := msg.Timestamp
+		if hasTime {
+			dayLabel, _ = messageDay(msg, opts)
+			timeLabel = ts.Format("15:04")
+			if opts.TimeFormat != "" {
+				timeLabel = formatTime(ts, opts.TimeFormat)
+			}
+		}
+
+		if len(view.Days) == 0 || view.Days[len(view.Days)-1].Label != dayLabel {
+			view.Days = append(view.Days, transcriptDay{Label: dayLabel})
+		}
+		day := &view.Days[len(view.Days)-1]
+
+		if len(day.Groups) == 0 || day.Groups[len(day.Groups)-1].Sender != msg.Sender ||
+			day.Groups[len(day.Groups)-1].IsSender != msg.IsSender {
+			day.Groups = append(day.Groups, transcriptGroup{Sender: msg.Sender, IsSender: msg.IsSender})
+		}
+		group := &day.Groups[len(day.Groups)-1]
+
+		entry := transcriptMessage{
+			ID:        msg.ID,
+			Time:      timeLabel,
+			Text:      msg.Text,
+			Reactions: countReactions(msg.Reactions),
+		}
+		for i, att := range msg.Attachments {
+			name := att.FileName
+			if name == "" {
+				name = "attachment"
+			}
+			entry.Attachments = append(entry.Attachments, transcriptAttachment{
+				Name:    name,
+				Path:    path.Join(dir, AttachmentFileName(msg, i)),
+				IsImage: att.Type == "img" || strings.HasPrefix(att.MimeType, "image/"),
+			})
+		}
+		group.Messages = append(group.Messages, entry)
+	}
+
+	if err := transcriptTemplate.Execute(w, view); err != nil {
+		return fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return nil
+}
+
+// inboxView is the data passed to the HTML inbox template
+type inboxView struct {
+	Summary   string
+	Generated string
+	Networks  []inboxNetworkView
+}
+
+type inboxNetworkView struct {
+	Name   string
+	Unread int
+	Chats  []inboxChatView
+}
+
+type inboxChatView struct {
+	Title    string
+	Unread   int
+	Earlier  int
+	Error    string
+	Messages []inboxMessageView
+}
+
+type inboxMessageView struct {
+	Sender string
+	Time   string
+	Text   string
+}
+
+// writeInboxHTML writes an inbox as a standalone HTML page
+func writeInboxHTML(w io.Writer, inbox Inbox, opts Options) error {
+	view := inboxView{
+		Summary:   inbox.Summary.String(),
+		Generated: now().Format("2006-01-02 15:04"),
+	}
+	for _, network := range inbox.Networks {
+		nv := inboxNetworkView{Name: networkLabel(network.Network), Unread: network.Unread}
+		for _, chat := range network.Chats {
+			cv := inboxChatView{
+				Title:   chat.title(),
+				Unread:  chat.Chat.UnreadCount,
+				Earlier: chat.earlierUnread(),
+				Error:   chat.Error,
+			}
+			for _, msg := range chat.Messages {
+				cv.Messages = append(cv.Messages, inboxMessageView{
+					Sender: msg.Sender,
+					Time:   messageTime(msg, opts),
+					Text:   msg.Text,
+				})
+			}
+			nv.Chats = append(nv.Chats, cv)
+		}
+		view.Networks = append(view.Networks, nv)
+	}
+
+	if err := inboxTemplate.Execute(w, view); err != nil {
+		return fmt.Errorf("failed to render HTML: %w", err)
+	}
+	return nil
+}
+
+// chronological returns messages sorted oldest first, by sort key where
+// timestamps tie. Messages without a parseable timestamp come first, in
+// sort key order.
+func chronological(messages []api.Message) []api.Message {
+	sorted := make([]api.Message, len(messages))
+	copy(sorted, messages)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, okI := sorted[i].Time()
+		tj, okJ := sorted[j].Time()
+		if okI != okJ {
+			return !okI
+		}
+		if okI && !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return api.CompareSortKeys(sorted[i].SortKey, sorted[j].SortKey) < 0
+	})
+	return sorted
+}
+
+// countReactions tallies reactions by key, in order of first appearance
+func countReactions(reactions []api.Reaction) []reactionCount {
+	var counts []reactionCount
+	index := make(map[string]int)
+	for _, r := range reactions {
+		if i, ok := index[r.ReactionKey]; ok {
+			counts[i].Count++
+			continue
+		}
+		index[r.ReactionKey] = len(counts)
+		counts = append(counts, reactionCount{Key: r.ReactionKey, Count: 1})
+	}
+	return counts
+}
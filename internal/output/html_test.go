@@ -0,0 +1,96 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var htmlMessages = []api.Message{
+	{ID: "m4", Sender: "Bob", Timestamp: "2021-12-21T09:00:00Z", Text: "Next day"},
+	{ID: "m3", Sender: "Me", IsSender: true, Timestamp: "2021-12-20T10:02:00Z", Text: "<b>not bold</b>",
+		Reactions: []api.Reaction{{ReactionKey: "👍"}, {ReactionKey: "👍"}, {ReactionKey: "❤️"}}},
+	{ID: "m2", Sender: "Alice", Timestamp: "2021-12-20T10:01:00Z", Text: "Second",
+		Attachments: []api.Attachment{{Type: "img", FileName: "photo 1.jpg"}, {FileName: "notes.pdf", MimeType: "application/pdf"}}},
+	{ID: "m1", Sender: "Alice", Timestamp: "2021-12-20T10:00:00Z", Text: "First"},
+}
+
+// TestFormatMessagesHTML tests the standalone HTML transcript
+func TestFormatMessagesHTML(t *testing.T) {
+	result := FormatMessagesWithOptions(htmlMessages, FormatHTML, Options{Title: "Team <chat>"})
+
+	assert.True(t, strings.HasPrefix(result, "<!DOCTYPE html>"))
+	assert.Contains(t, result, "<title>Team &lt;chat&gt;</title>")
+
+	// Oldest first, with a separator per day
+	first := strings.Index(result, "First")
+	second := strings.Index(result, "Second")
+	nextDay := strings.Index(result, "Next day")
+	require.True(t, first > 0 && second > first && nextDay > second)
+	assert.Equal(t, 2, strings.Count(result, `class="day"`))
+	assert.Contains(t, result, "Monday, December 20, 2021")
+
+	// Consecutive messages from Alice share one group
+	assert.Equal(t, 3, strings.Count(result, `class="group`))
+	assert.Contains(t, result, `class="group self"`)
+
+	// Text is escaped, reactions are counted
+	assert.Contains(t, result, "&lt;b&gt;not bold&lt;/b&gt;")
+	assert.NotContains(t, result, "<b>not bold</b>")
+	assert.Contains(t, result, "👍 2")
+
+	// Attachments are linked from the sibling directory
+	assert.Contains(t, result, `<img src="attachments/m2_0_photo_1.jpg"`)
+	assert.Contains(t, result, `<a href="attachments/m2_1_notes.pdf">notes.pdf</a>`)
+}
+
+// TestFormatMessagesHTML_AttachmentsDir tests a custom attachments directory
+func TestFormatMessagesHTML_AttachmentsDir(t *testing.T) {
+	result := FormatMessagesWithOptions(htmlMessages, FormatHTML, Options{AttachmentsDir: "files"})
+	assert.Contains(t, result, `src="files/m2_0_photo_1.jpg"`)
+}
+
+// TestFormatHTML_Unsupported tests that html is rejected for non-message results
+func TestFormatHTML_Unsupported(t *testing.T) {
+	assert.Contains(t, FormatChats(testChats, FormatHTML), "only supported for messages")
+	assert.Contains(t, FormatMessages([]api.Message{}, FormatHTML), "No messages found")
+}
+
+// TestAttachmentFileName tests that attachment names are file system safe
+func TestAttachmentFileName(t *testing.T) {
+	msg := api.Message{ID: "$abc:beeper.local", Attachments: []api.Attachment{{FileName: "../../etc/passwd"}, {}}}
+	assert.Equal(t, "_abc_beeper.local_0_passwd", AttachmentFileName(msg, 0))
+	assert.Equal(t, "_abc_beeper.local_1_attachment", AttachmentFileName(msg, 1))
+}
+
+// TestChronological tests that messages sort consistently when some
+// timestamps are missing or tie
+func TestChronological(t *testing.T) {
+	messages := []api.Message{
+		{ID: "late", SortKey: "5", Timestamp: "2021-12-20T10:05:00Z"},
+		{ID: "tie-b", SortKey: "4", Timestamp: "2021-12-20T10:01:00Z"},
+		{ID: "none-b", SortKey: "3"},
+		{ID: "tie-a", SortKey: "2", Timestamp: "2021-12-20T10:01:00Z"},
+		{ID: "none-a", SortKey: "1", Timestamp: "yesterday"},
+		{ID: "early", SortKey: "6", Timestamp: "2021-12-20T09:00:00Z"},
+	}
+	var ids []string
+	for _, msg := range chronological(messages) {
+		ids = append(ids, msg.ID)
+	}
+	assert.Equal(t, []string{"none-a", "none-b", "early", "tie-a", "tie-b", "late"}, ids)
+}
+
+// TestFormatMessagesHTML_Generated tests that the generation time comes
+// from the package clock
+func TestFormatMessagesHTML_Generated(t *testing.T) {
+	saved := now
+	now = func() time.Time { return time.Date(2024, 3, 2, 15, 4, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = saved })
+
+	assert.Contains(t, FormatMessages(htmlMessages, FormatHTML), "2024-03-02 15:04")
+}
@@ -0,0 +1,201 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// Inbox is a digest of the chats with unread messages, grouped by network
+type Inbox struct {
+	Summary  InboxSummary   `json:"summary"`
+	Networks []InboxNetwork `json:"networks"`
+}
+
+// InboxSummary totals an inbox
+type InboxSummary struct {
+	// Unread is the number of unread messages, which may exceed the
+	// messages shown when a chat has more than were fetched
+	Unread    int    `json:"unread"`
+	Chats     int    `json:"chats"`
+	Networks  int    `json:"networks"`
+	FetchedAt string `json:"fetchedAt"`
+}
+
+// InboxNetwork is one network's unread chats, most recent first
+type InboxNetwork struct {
+	Network string      `json:"network"`
+	Unread  int         `json:"unread"`
+	Chats   []InboxChat `json:"chats"`
+}
+
+// InboxChat is an unread chat with its unread messages, oldest first
+type InboxChat struct {
+	Chat     api.Chat      `json:"chat"`
+	Messages []api.Message `json:"messages"`
+	// Error is set when the chat's messages could not be fetched
+	Error string `json:"error,omitempty"`
+}
+
+// NewInbox groups chats by network. Chats are ordered by their newest
+// message, networks by their most recent chat; ties keep the given order.
+func NewInbox(chats []InboxChat) Inbox {
+	sorted := make([]InboxChat, len(chats))
+	copy(sorted, chats)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].latest(), sorted[j].latest()
+		return ti.After(tj)
+	})
+
+	inbox := Inbox{Networks: []InboxNetwork{}}
+	index := make(map[string]int)
+	for _, chat := range sorted {
+		if chat.Messages == nil {
+			chat.Messages = []api.Message{}
+		}
+		i, ok := index[chat.Chat.Network]
+		if !ok {
+			i = len(inbox.Networks)
+			index[chat.Chat.Network] = i
+			inbox.Networks = append(inbox.Networks, InboxNetwork{Network: chat.Chat.Network})
+		}
+		network := &inbox.Networks[i]
+		network.Chats = append(network.Chats, chat)
+		network.Unread += chat.Chat.UnreadCount
+		inbox.Summary.Unread += chat.Chat.UnreadCount
+	}
+	inbox.Summary.Chats = len(sorted)
+	inbox.Summary.Networks = len(inbox.Networks)
+	inbox.Summary.FetchedAt = now().UTC().Format(time.RFC3339)
+	return inbox
+}
+
+// latest returns the time of the chat's newest message, or the zero time
+func (c InboxChat) latest() time.Time {
+	var latest time.Time
+	for _, msg := range c.Messages {
+		if t, ok := msg.Time(); ok && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// title returns the chat's title, or its ID when untitled
+func (c InboxChat) title() string {
+	if c.Chat.Title != "" {
+		return c.Chat.Title
+	}
+	return c.Chat.ID
+}
+
+// earlierUnread is the number of unread messages older than those fetched,
+// or 0 when the fetch failed
+func (c InboxChat) earlierUnread() int {
+	if c.Error != "" {
+		return 0
+	}
+	return max(0, c.Chat.UnreadCount-len(c.Messages))
+}
+
+// String describes the summary, e.g. "5 unread messages in 2 chats across 1 network"
+func (s InboxSummary) String() string {
+	return fmt.Sprintf("%s in %s across %s",
+		plural(s.Unread, "unread message"), plural(s.Chats, "chat"), plural(s.Networks, "network"))
+}
+
+// WriteInbox writes an unread digest to w according to the specified format
+func WriteInbox(w io.Writer, inbox Inbox, format Format, opts Options) error {
+	f, err := Lookup(format)
+	if err != nil {
+		return err
+	}
+	return f.WriteInbox(w, inbox, opts)
+}
+
+// inboxRow is one message of an inbox, or a chat without any, for tabular
+// formats
+type inboxRow struct {
+	network string
+	chat    InboxChat
+	msg     api.Message
+}
+
+// inboxRows flattens an inbox into rows
+func inboxRows(inbox Inbox) []inboxRow {
+	var rows []inboxRow
+	for _, network := range inbox.Networks {
+		for _, chat := range network.Chats {
+			if len(chat.Messages) == 0 {
+				rows = append(rows, inboxRow{network: network.Network, chat: chat})
+			}
+			for _, msg := range chat.Messages {
+				rows = append(rows, inboxRow{network: network.Network, chat: chat, msg: msg})
+			}
+		}
+	}
+	return rows
+}
+
+var inboxColumns = []column[inboxRow]{
+	{"network", "NETWORK", 20, func(r inboxRow) string { return r.network }},
+	{"chat", "CHAT", 0, func(r inboxRow) string { return r.chat.Chat.ID }},
+	{"title", "TITLE", 30, func(r inboxRow) string { return r.chat.title() }},
+	{"unread", "UNREAD", 0, func(r inboxRow) string { return strconv.Itoa(r.chat.Chat.UnreadCount) }},
+	{"id", "ID", 0, func(r inboxRow) string { return r.msg.ID }},
+	{"time", "TIME", 0, func(r inboxRow) string { return r.msg.Timestamp }},
+	{"sender", "SENDER", 24, func(r inboxRow) string { return r.msg.Sender }},
+	{"text", "TEXT", 80, inboxRowText},
+	{"error", "ERROR", 40, func(r inboxRow) string { return r.chat.Error }},
+}
+
+// inboxRowText is the message text, or the error for a chat whose messages
+// could not be fetched
+func inboxRowText(r inboxRow) string {
+	if r.chat.Error != "" {
+		return "! " + r.chat.Error
+	}
+	return r.msg.Text
+}
+
+// InboxColumns are the column names accepted by --columns for an inbox
+var InboxColumns = columnNames(inboxColumns)
+
+var defaultInboxColumns = []string{"network", "title", "time", "sender", "text"}
+
+// inboxColumnsFor returns inboxColumns with the time column rendered in the
+// zone and format chosen by --tz and --time-format
+func inboxColumnsFor(opts Options) []column[inboxRow] {
+	cols := make([]column[inboxRow], len(inboxColumns))
+	copy(cols, inboxColumns)
+	for i := range cols {
+		if cols[i].name == "time" {
+			cols[i].value = func(r inboxRow) string {
+				if r.msg.Timestamp == "" {
+					return ""
+				}
+				return messageTime(r.msg, opts)
+			}
+		}
+	}
+	return cols
+}
+
+// networkLabel names a network, including chats the API reported none for
+func networkLabel(network string) string {
+	if network == "" {
+		return "Unknown network"
+	}
+	return network
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
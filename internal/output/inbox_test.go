@@ -0,0 +1,133 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testInbox() Inbox {
+	return NewInbox([]InboxChat{
+		{
+			Chat: api.Chat{ID: "!a:x", Title: "Alice", Network: "WhatsApp", UnreadCount: 3},
+			Messages: []api.Message{
+				{ID: "a1", Sender: "Alice", Text: "lunch?", Timestamp: "2024-03-01T12:00:00Z"},
+				{ID: "a2", Sender: "Alice", Text: "at noon", Timestamp: "2024-03-01T12:01:00Z"},
+			},
+		},
+		{
+			Chat: api.Chat{ID: "!b:x", Title: "Team", Network: "Slack", UnreadCount: 1},
+			Messages: []api.Message{
+				{ID: "b1", Sender: "Bob", Text: "deploy done", Timestamp: "2024-03-01T15:00:00Z"},
+			},
+		},
+		{
+			Chat:  api.Chat{ID: "!c:x", Title: "Carol", Network: "WhatsApp", UnreadCount: 2},
+			Error: "timed out",
+		},
+	})
+}
+
+func TestNewInbox(t *testing.T) {
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC) }
+
+	inbox := testInbox()
+
+	assert.Equal(t, InboxSummary{Unread: 6, Chats: 3, Networks: 2, FetchedAt: "2024-03-02T00:00:00Z"}, inbox.Summary)
+	require.Len(t, inbox.Networks, 2)
+	// Slack's chat is the most recent, so it comes first
+	assert.Equal(t, "Slack", inbox.Networks[0].Network)
+	assert.Equal(t, "WhatsApp", inbox.Networks[1].Network)
+	assert.Equal(t, 5, inbox.Networks[1].Unread)
+	require.Len(t, inbox.Networks[1].Chats, 2)
+	assert.Equal(t, "Alice", inbox.Networks[1].Chats[0].Chat.Title)
+	assert.NotNil(t, inbox.Networks[1].Chats[1].Messages)
+}
+
+func TestInboxSummaryString(t *testing.T) {
+	assert.Equal(t, "1 unread message in 1 chat across 1 network", InboxSummary{Unread: 1, Chats: 1, Networks: 1}.String())
+	assert.Equal(t, "6 unread messages in 3 chats across 2 networks", testInbox().Summary.String())
+}
+
+func TestWriteInbox_AllFormats(t *testing.T) {
+	tmpl, err := ParseTemplate("{{range .Networks}}{{.Network}}:{{len .Chats}} {{end}}")
+	require.NoError(t, err)
+
+	for _, name := range FormatNames() {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteInbox(&buf, testInbox(), Format(name), Options{Template: tmpl})
+			require.NoError(t, err)
+			assert.NotEmpty(t, buf.String())
+		})
+	}
+}
+
+func TestWriteInbox_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteInbox(&buf, testInbox(), FormatJSON, Options{}))
+
+	var decoded Inbox
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, 6, decoded.Summary.Unread)
+	assert.Equal(t, "timed out", decoded.Networks[1].Chats[1].Error)
+}
+
+func TestWriteInbox_NDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteInbox(&buf, testInbox(), FormatNDJSON, Options{}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], `"summary":{"unread":6`)
+	assert.Contains(t, lines[1], `"title":"Team"`)
+}
+
+func TestWriteInbox_Text(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteInbox(&buf, testInbox(), FormatText, Options{}))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "Inbox: 6 unread messages in 3 chats across 2 networks\n"))
+	assert.Contains(t, out, "== WhatsApp (2 chats, 5 unread) ==")
+	assert.Contains(t, out, "Alice (3 unread)\n  … 1 earlier unread\n  [2024-03-01T12:00:00Z] Alice: lunch?")
+	assert.Contains(t, out, "Carol (2 unread)\n  ! timed out")
+	assert.Less(t, strings.Index(out, "Slack"), strings.Index(out, "WhatsApp"))
+}
+
+func TestWriteInbox_Table(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteInbox(&buf, testInbox(), FormatTable, Options{}))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	assert.Equal(t, "6 unread messages in 3 chats across 2 networks", lines[0])
+	assert.Equal(t, []string{"NETWORK", "TITLE", "TIME", "SENDER", "TEXT"}, strings.Fields(lines[2]))
+	// One row per message, and one for the chat whose fetch failed
+	require.Len(t, lines, 7)
+	assert.Contains(t, lines[6], "! timed out")
+}
+
+func TestWriteInbox_CSV(t *testing.T) {
+	var buf bytes.Buffer
+	opts := Options{Columns: []string{"chat", "sender", "text"}}
+	require.NoError(t, WriteInbox(&buf, testInbox(), FormatCSV, opts))
+
+	assert.Equal(t, "chat,sender,text\n!b:x,Bob,deploy done\n!a:x,Alice,lunch?\n!a:x,Alice,at noon\n!c:x,,! timed out\n", buf.String())
+}
+
+func TestWriteInbox_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteInbox(&buf, NewInbox(nil), FormatText, Options{}))
+	assert.Equal(t, "No unread chats found.\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteInbox(&buf, NewInbox(nil), FormatJSON, Options{}))
+	assert.Contains(t, buf.String(), `"networks": []`)
+}
@@ -0,0 +1,114 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/query"
+)
+
+func init() {
+	Register(Registration{
+		Name:        FormatJSON,
+		Description: "Indented JSON array",
+		Formatter:   jsonFormatter{},
+	})
+	Register(Registration{
+		Name:        FormatNDJSON,
+		Description: "Newline-delimited JSON, one compact object per line",
+		Streams:     true,
+		Formatter:   ndjsonFormatter{},
+	})
+}
+
+// jsonFormatter writes results as an indented JSON array
+type jsonFormatter struct{}
+
+func (jsonFormatter) WriteChats(w io.Writer, chats []api.Chat, _ Options) error {
+	return writeJSON(w, chats)
+}
+
+func (jsonFormatter) WriteMessages(w io.Writer, messages []api.Message, _ Options) error {
+	return writeJSON(w, messages)
+}
+
+func (jsonFormatter) WriteAccounts(w io.Writer, accounts []api.Account, _ Options) error {
+	return writeJSON(w, accounts)
+}
+
+func (jsonFormatter) WriteSendResult(w io.Writer, result SendResult, _ Options) error {
+	return WriteValue(w, result, FormatJSON)
+}
+
+func (jsonFormatter) WriteInbox(w io.Writer, inbox Inbox, _ Options) error {
+	return WriteValue(w, inbox, FormatJSON)
+}
+
+func (jsonFormatter) WriteRecords(w io.Writer, records []query.Projection, _ Options) error {
+	return writeJSON(w, records)
+}
+
+// ndjsonFormatter writes results as newline-delimited JSON
+type ndjsonFormatter struct{}
+
+func (ndjsonFormatter) WriteChats(w io.Writer, chats []api.Chat, _ Options) error {
+	return writeNDJSON(w, chats)
+}
+
+func (ndjsonFormatter) WriteMessages(w io.Writer, messages []api.Message, _ Options) error {
+	return writeNDJSON(w, messages)
+}
+
+func (ndjsonFormatter) WriteAccounts(w io.Writer, accounts []api.Account, _ Options) error {
+	return writeNDJSON(w, accounts)
+}
+
+func (ndjsonFormatter) WriteSendResult(w io.Writer, result SendResult, _ Options) error {
+	return WriteValue(w, result, FormatNDJSON)
+}
+
+// WriteInbox writes the summary on the first line, then one line per chat
+func (ndjsonFormatter) WriteInbox(w io.Writer, inbox Inbox, _ Options) error {
+	if err := WriteValue(w, map[string]InboxSummary{"summary": inbox.Summary}, FormatNDJSON); err != nil {
+		return err
+	}
+	for _, network := range inbox.Networks {
+		for _, chat := range network.Chats {
+			if err := WriteValue(w, chat, FormatNDJSON); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (ndjsonFormatter) WriteRecords(w io.Writer, records []query.Projection, _ Options) error {
+	return writeNDJSON(w, records)
+}
+
+// writeJSON writes items as an indented JSON array
+func writeJSON[T any](w io.Writer, items []T) error {
+	if items == nil {
+		items = []T{}
+	}
+	data, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	data = append(data, '\n')
+	_, err = w.Write(data)
+	return err
+}
+
+// writeNDJSON writes items as newline-delimited JSON, one compact object per line
+func writeNDJSON[T any](w io.Writer, items []T) error {
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+func init() {
+	Register(Registration{
+		Name:        FormatLLM,
+		Description: "Compact transcript for LLM context (see --max-tokens)",
+		Formatter:   llmFormatter{},
+	})
+}
+
+// selfAlias is the alias of messages sent by the account owner
+const selfAlias = "me"
+
+// llmFormatter writes results in as few tokens as possible while keeping
+// them unambiguous: one line per item, short sender aliases with a legend,
+// and times that only repeat the date when the day changes
+type llmFormatter struct{}
+
+func (llmFormatter) WriteChats(w io.Writer, chats []api.Chat, opts Options) error {
+	lines := make([]string, len(chats))
+	for i, chat := range chats {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("%s | %s | %s", chat.ID, chat.Title, chat.Network))
+		if chat.UnreadCount > 0 {
+			sb.WriteString(fmt.Sprintf(" | unread %d", chat.UnreadCount))
+		}
+		if chat.IsMuted {
+			sb.WriteString(" | muted")
+		}
+		sb.WriteString("\n")
+		lines[i] = sb.String()
+	}
+	_, err := io.WriteString(w, renderList(lines, "chats", opts.MaxTokens))
+	return err
+}
+
+func (llmFormatter) WriteMessages(w io.Writer, messages []api.Message, opts Options) error {
+	messages = chronological(messages)
+	aliases := senderAliases(messages)
+
+	lines := make([]llmLine, len(messages))
+	for i, msg := range messages {
+		lines[i] = llmMessageLine(msg, aliases, opts)
+	}
+
+	kept := lines
+	if opts.MaxTokens > 0 && estimateTranscript(lines, aliases, opts, 0) > opts.MaxTokens {
+		kept = fitTokens(lines, aliases, opts)
+	}
+	_, err := io.WriteString(w, renderTranscript(kept, aliases, opts, len(lines)-len(kept)))
+	return err
+}
+
+func (llmFormatter) WriteAccounts(w io.Writer, accounts []api.Account, opts Options) error {
+	lines := make([]string, len(accounts))
+	for i, account := range accounts {
+		lines[i] = fmt.Sprintf("%s | %s | %s\n", account.ID, account.Network, accountUserName(account))
+	}
+	_, err := io.WriteString(w, renderList(lines, "accounts", opts.MaxTokens))
+	return err
+}
+
+// renderList joins lines, keeping only the leading ones that fit in about
+// maxTokens (if positive) followed by a count of those left out
+func renderList(lines []string, noun string, maxTokens int) string {
+	render := func(n int) string {
+		s := strings.Join(lines[:n], "")
+		if n < len(lines) {
+			s += fmt.Sprintf("[%d more %s omitted]\n", len(lines)-n, noun)
+		}
+		return s
+	}
+	if maxTokens <= 0 || estimateTokens(render(len(lines))) <= maxTokens {
+		return render(len(lines))
+	}
+	return render(sort.Search(len(lines), func(n int) bool {
+		return estimateTokens(render(n+1)) > maxTokens
+	}))
+}
+
+func (llmFormatter) WriteSendResult(w io.Writer, result SendResult, _ Options) error {
+	_, err := fmt.Fprintf(w, "sent %s\n", result.MessageID)
+	return err
+}
+
+// llmLine is one rendered message with the day it belongs to
+type llmLine struct {
+	day    string
+	sender string
+	text   string
+}
+
+// llmMessageLine renders a message as "15:04 alias: text", with attachments
+// in brackets and continuation lines indented
+func llmMessageLine(msg api.Message, aliases map[string]string, opts Options) llmLine {
+	line := llmLine{sender: aliasKey(msg)}
+
+	var sb strings.Builder
+	if t, ok := localTime(msg, opts); ok {
+		line.day = t.Format("2006-01-02")
+		sb.WriteString(t.Format("15:04") + " ")
+	}
+	sb.WriteString(aliases[line.sender] + ": ")
+	sb.WriteString(strings.ReplaceAll(strings.TrimSpace(msg.Text), "\n", "\n  "))
+	for _, att := range msg.Attachments {
+		kind := att.Type
+		if kind == "" {
+			kind = "file"
+		}
+		if att.FileName != "" {
+			sb.WriteString(fmt.Sprintf(" [%s: %s]", kind, att.FileName))
+		} else {
+			sb.WriteString(fmt.Sprintf(" [%s]", kind))
+		}
+	}
+	line.text = sb.String()
+	return line
+}
+
+// aliasKey identifies a message's sender for aliasing
+func aliasKey(msg api.Message) string {
+	if msg.IsSender {
+		return ""
+	}
+	return msg.Sender
+}
+
+// senderAliases assigns each sender a short alias from the initials of their
+// name, numbered on collision ("A", "A2"). Aliases are assigned in order of
+// first appearance, so the same transcript always gets the same aliases.
+// The account owner is always "me".
+func senderAliases(messages []api.Message) map[string]string {
+	aliases := map[string]string{"": selfAlias}
+	used := map[string]bool{selfAlias: true}
+	for _, msg := range messages {
+		key := aliasKey(msg)
+		if _, ok := aliases[key]; ok {
+			continue
+		}
+		base := initials(key)
+		alias := base
+		for n := 2; used[strings.ToLower(alias)]; n++ {
+			alias = base + strconv.Itoa(n)
+		}
+		aliases[key] = alias
+		used[strings.ToLower(alias)] = true
+	}
+	return aliases
+}
+
+// initials returns the upper-cased first letter of up to three words of name
+func initials(name string) string {
+	var sb strings.Builder
+	for _, word := range strings.Fields(name) {
+		r, _ := utf8.DecodeRuneInString(word)
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(unicode.ToUpper(r))
+		}
+		if sb.Len() >= 3 {
+			break
+		}
+	}
+	if sb.Len() == 0 {
+		return "U"
+	}
+	return sb.String()
+}
+
+// renderTranscript writes the header, participant legend, omission marker
+// and message lines, starting a new "@date" line whenever the day changes
+func renderTranscript(lines []llmLine, aliases map[string]string, opts Options, omitted int) string {
+	var sb strings.Builder
+	if opts.Title != "" {
+		sb.WriteString("chat: " + opts.Title + "\n")
+	}
+	if legend := participantLegend(lines, aliases); legend != "" {
+		sb.WriteString("participants: " + legend + "\n")
+	}
+	if omitted > 0 {
+		sb.WriteString(fmt.Sprintf("[%d earlier messages omitted]\n", omitted))
+	}
+
+	day := ""
+	for _, line := range lines {
+		if line.day != "" && line.day != day {
+			sb.WriteString("@" + line.day + "\n")
+			day = line.day
+		}
+		sb.WriteString(line.text + "\n")
+	}
+	return sb.String()
+}
+
+// participantLegend lists "alias=name" for every sender in lines, in order
+// of first appearance
+func participantLegend(lines []llmLine, aliases map[string]string) string {
+	var entries []string
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		if seen[line.sender] {
+			continue
+		}
+		seen[line.sender] = true
+		if line.sender == "" {
+			entries = append(entries, selfAlias+"=you")
+		} else {
+			entries = append(entries, aliases[line.sender]+"="+line.sender)
+		}
+	}
+	return strings.Join(entries, ", ")
+}
+
+// fitTokens keeps the newest lines whose transcript, including the omission
+// marker, fits in opts.MaxTokens. The estimate grows with every older line
+// kept, so the cut-off is found by binary search.
+func fitTokens(lines []llmLine, aliases map[string]string, opts Options) []llmLine {
+	start := sort.Search(len(lines), func(i int) bool {
+		return estimateTranscript(lines[i:], aliases, opts, i) <= opts.MaxTokens
+	})
+	return lines[start:]
+}
+
+// estimateTranscript estimates the tokens of the rendered transcript
+func estimateTranscript(lines []llmLine, aliases map[string]string, opts Options, omitted int) int {
+	return estimateTokens(renderTranscript(lines, aliases, opts, omitted))
+}
+
+// estimateTokens approximates a tokenizer at four characters per token,
+// which is close for English text and errs on the generous side for code
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + 3) / 4
+}
+
+// WriteInbox writes a transcript per chat under its network, with the
+// summary first. With opts.MaxTokens every chat keeps the same number of its
+// newest messages, as many as fit.
+func (llmFormatter) WriteInbox(w io.Writer, inbox Inbox, opts Options) error {
+	type transcript struct {
+		opts    Options
+		err     string
+		lines   []llmLine
+		aliases map[string]string
+		earlier int
+	}
+	var networks [][]transcript
+	longest := 0
+	for _, network := range inbox.Networks {
+		var chats []transcript
+		for _, chat := range network.Chats {
+			t := transcript{opts: opts, err: chat.Error, earlier: chat.earlierUnread()}
+			t.opts.Title = fmt.Sprintf("%s | %s | unread %d", chat.title(), chat.Chat.ID, chat.Chat.UnreadCount)
+			messages := chronological(chat.Messages)
+			t.aliases = senderAliases(messages)
+			t.lines = make([]llmLine, len(messages))
+			for i, msg := range messages {
+				t.lines[i] = llmMessageLine(msg, t.aliases, t.opts)
+			}
+			longest = max(longest, len(t.lines))
+			chats = append(chats, t)
+		}
+		networks = append(networks, chats)
+	}
+
+	// render keeps the newest perChat messages of every chat
+	render := func(perChat int) string {
+		var sb strings.Builder
+		sb.WriteString("inbox: " + inbox.Summary.String() + "\n")
+		for i, network := range inbox.Networks {
+			sb.WriteString(fmt.Sprintf("\n# %s\n", networkLabel(network.Network)))
+			for _, t := range networks[i] {
+				if t.err != "" {
+					sb.WriteString(fmt.Sprintf("chat: %s\n[error: %s]\n", t.opts.Title, t.err))
+					continue
+				}
+				drop := max(0, len(t.lines)-perChat)
+				sb.WriteString(renderTranscript(t.lines[drop:], t.aliases, t.opts, t.earlier+drop))
+			}
+		}
+		return sb.String()
+	}
+
+	out := render(longest)
+	if opts.MaxTokens > 0 && estimateTokens(out) > opts.MaxTokens {
+		out = render(sort.Search(longest, func(n int) bool {
+			return estimateTokens(render(n+1)) > opts.MaxTokens
+		}))
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
@@ -0,0 +1,121 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var llmMessages = []api.Message{
+	{Sender: "Bob Jones", Text: "Tomorrow works", Timestamp: "2021-12-21T09:00:00Z"},
+	{Sender: "Me", IsSender: true, Text: "Lunch?\nAt noon", Timestamp: "2021-12-20T10:02:00Z",
+		Attachments: []api.Attachment{{Type: "img", FileName: "menu.png"}}},
+	{Sender: "Alice Smith", Text: "Hi", Timestamp: "2021-12-20T10:00:00Z"},
+}
+
+func TestFormatMessagesLLM(t *testing.T) {
+	result := FormatMessagesWithOptions(llmMessages, FormatLLM, Options{Title: "Team"})
+	assert.Equal(t, `chat: Team
+participants: AS=Alice Smith, me=you, BJ=Bob Jones
+@2021-12-20
+10:00 AS: Hi
+10:02 me: Lunch?
+  At noon [img: menu.png]
+@2021-12-21
+09:00 BJ: Tomorrow works
+`, result)
+}
+
+func TestSenderAliasesAreUniqueAndStable(t *testing.T) {
+	messages := []api.Message{
+		{Sender: "Anna"}, {Sender: "Alex"}, {Sender: "anna b"}, {Sender: "Anna"}, {Sender: "Me", IsSender: true},
+	}
+	aliases := senderAliases(messages)
+	assert.Equal(t, "A", aliases["Anna"])
+	assert.Equal(t, "A2", aliases["Alex"])
+	assert.Equal(t, "AB", aliases["anna b"])
+	assert.Equal(t, "me", aliases[""])
+	assert.Equal(t, aliases, senderAliases(messages))
+}
+
+func TestLLMMaxTokens(t *testing.T) {
+	var messages []api.Message
+	for i := 0; i < 50; i++ {
+		messages = append(messages, api.Message{
+			Sender:    "Alice",
+			Text:      fmt.Sprintf("message number %d with some padding text", i),
+			Timestamp: fmt.Sprintf("2021-12-20T10:%02d:00Z", i),
+		})
+	}
+
+	result := FormatMessagesWithOptions(messages, FormatLLM, Options{MaxTokens: 100})
+	assert.LessOrEqual(t, estimateTokens(result), 100)
+	assert.Contains(t, result, "earlier messages omitted]")
+	assert.Contains(t, result, "message number 49 ")
+	assert.NotContains(t, result, "message number 0 ")
+
+	// The marker counts exactly the messages left out
+	kept := strings.Count(result, "message number")
+	assert.Contains(t, result, fmt.Sprintf("[%d earlier messages omitted]", 50-kept))
+
+	// A budget that fits everything changes nothing
+	assert.Equal(t, FormatMessages(messages, FormatLLM),
+		FormatMessagesWithOptions(messages, FormatLLM, Options{MaxTokens: 100000}))
+}
+
+func TestFormatChatsLLM(t *testing.T) {
+	chats := []api.Chat{{ID: "c1", Title: "Team", Network: "slack", UnreadCount: 2, IsMuted: true}}
+	assert.Equal(t, "c1 | Team | slack | unread 2 | muted\n", FormatChats(chats, FormatLLM))
+}
+
+func TestFormatChatsLLM_MaxTokens(t *testing.T) {
+	var chats []api.Chat
+	for i := 0; i < 30; i++ {
+		chats = append(chats, api.Chat{ID: fmt.Sprintf("chat%02d", i), Title: "Some chat title", Network: "slack"})
+	}
+	result := FormatChatsWithOptions(chats, FormatLLM, Options{MaxTokens: 50})
+	assert.LessOrEqual(t, estimateTokens(result), 50)
+	assert.Contains(t, result, "chat00 |")
+	kept := strings.Count(result, " | slack")
+	assert.Contains(t, result, fmt.Sprintf("[%d more chats omitted]", 30-kept))
+}
+
+func TestWriteInboxLLM_MaxTokens(t *testing.T) {
+	chat := func(id string) InboxChat {
+		c := InboxChat{Chat: api.Chat{ID: id, Title: id, Network: "slack", UnreadCount: 25}}
+		// Newest first, as the API returns them
+		for i := 19; i >= 0; i-- {
+			c.Messages = append(c.Messages, api.Message{
+				Sender:    "Alice",
+				Text:      fmt.Sprintf("%s message %d with some padding", id, i),
+				Timestamp: fmt.Sprintf("2024-03-01T10:%02d:00Z", i),
+			})
+		}
+		return c
+	}
+	inbox := NewInbox([]InboxChat{chat("a"), chat("b")})
+
+	var full bytes.Buffer
+	require.NoError(t, WriteInbox(&full, inbox, FormatLLM, Options{}))
+	assert.Less(t, strings.Index(full.String(), "a message 0 "), strings.Index(full.String(), "a message 19 "), "oldest first")
+	assert.Contains(t, full.String(), "[5 earlier messages omitted]")
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteInbox(&buf, inbox, FormatLLM, Options{MaxTokens: 200}))
+	result := buf.String()
+	assert.LessOrEqual(t, estimateTokens(result), 200)
+	assert.Contains(t, result, "a message 19 ")
+	assert.Contains(t, result, "b message 19 ")
+	assert.NotContains(t, result, "message 0 ")
+
+	// Both chats keep the same number of messages, and the markers count
+	// the unread messages left out
+	kept := strings.Count(result, "a message")
+	assert.Equal(t, kept, strings.Count(result, "b message"))
+	assert.Equal(t, 2, strings.Count(result, fmt.Sprintf("[%d earlier messages omitted]", 25-kept)))
+}
@@ -0,0 +1,116 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+func init() {
+	Register(Registration{
+		Name:        FormatMarkdown,
+		Description: "Markdown with headings per item",
+		Formatter:   markdownFormatter{},
+	})
+}
+
+// markdownFormatter writes results as Markdown documents
+type markdownFormatter struct{}
+
+func (markdownFormatter) WriteChats(w io.Writer, chats []api.Chat, _ Options) error {
+	if len(chats) == 0 {
+		return writeEmpty(w, "chats")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Chats\n\n")
+	for _, chat := range chats {
+		title := chat.Title
+		if title == "" {
+			title = chat.ID
+		}
+		sb.WriteString(fmt.Sprintf("## %s\n\n", title))
+		sb.WriteString(fmt.Sprintf("- **ID**: %s\n", chat.ID))
+		sb.WriteString(fmt.Sprintf("- **Type**: %s\n", chat.Type))
+		sb.WriteString(fmt.Sprintf("- **Network**: %s\n", chat.Network))
+		sb.WriteString(fmt.Sprintf("- **Unread**: %d\n\n", chat.UnreadCount))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (markdownFormatter) WriteMessages(w io.Writer, messages []api.Message, opts Options) error {
+	if len(messages) == 0 {
+		return writeEmpty(w, "messages")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Messages\n\n")
+	var lastDay string
+	for _, msg := range messages {
+		if day, ok := messageDay(msg, opts); ok && day != lastDay {
+			sb.WriteString(fmt.Sprintf("## %s\n\n", day))
+			lastDay = day
+		}
+		sb.WriteString(fmt.Sprintf("**%s** - %s\n\n",
+			msg.Sender,
+			messageTime(msg, opts),
+		))
+		sb.WriteString(fmt.Sprintf("> %s\n\n", msg.Text))
+		sb.WriteString("---\n\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (markdownFormatter) WriteAccounts(w io.Writer, accounts []api.Account, _ Options) error {
+	if len(accounts) == 0 {
+		return writeEmpty(w, "accounts")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Accounts\n\n")
+	for _, account := range accounts {
+		sb.WriteString(fmt.Sprintf("## %s\n\n", account.Network))
+		sb.WriteString(fmt.Sprintf("- **ID**: %s\n", account.ID))
+		sb.WriteString(fmt.Sprintf("- **User**: %s\n\n", accountUserName(account)))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (markdownFormatter) WriteSendResult(w io.Writer, result SendResult, _ Options) error {
+	_, err := fmt.Fprintf(w, "**Message sent successfully**\n\nID: `%s`\n", result.MessageID)
+	return err
+}
+
+func (markdownFormatter) WriteInbox(w io.Writer, inbox Inbox, opts Options) error {
+	if len(inbox.Networks) == 0 {
+		return writeEmpty(w, "unread chats")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Inbox\n\n")
+	sb.WriteString(inbox.Summary.String() + "\n\n")
+	for _, network := range inbox.Networks {
+		sb.WriteString(fmt.Sprintf("## %s\n\n", networkLabel(network.Network)))
+		for _, chat := range network.Chats {
+			sb.WriteString(fmt.Sprintf("### %s (%d unread)\n\n", chat.title(), chat.Chat.UnreadCount))
+			if chat.Error != "" {
+				sb.WriteString(fmt.Sprintf("*Failed to fetch messages: %s*\n\n", chat.Error))
+				continue
+			}
+			if earlier := chat.earlierUnread(); earlier > 0 {
+				sb.WriteString(fmt.Sprintf("*%d earlier unread*\n\n", earlier))
+			}
+			for _, msg := range chat.Messages {
+				sb.WriteString(fmt.Sprintf("**%s** - %s\n\n", msg.Sender, messageTime(msg, opts)))
+				sb.WriteString(fmt.Sprintf("> %s\n\n", strings.ReplaceAll(msg.Text, "\n", "\n> ")))
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
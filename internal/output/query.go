@@ -0,0 +1,60 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/nerveband/beeper-api-cli/internal/query"
+)
+
+// filterItems keeps the items whose JSON form matches where
+func filterItems[T any](items []T, where *query.Expr) ([]T, error) {
+	if where == nil {
+		return items, nil
+	}
+
+	kept := make([]T, 0, len(items))
+	for _, item := range items {
+		record, err := query.ToRecord(item)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := where.Match(record)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			kept = append(kept, item)
+		}
+	}
+	return kept, nil
+}
+
+// writeProjected writes items reduced to opts.Fields. Projections only carry
+// JSON field names, so they are limited to formatters that implement
+// RecordFormatter: JSON-shaped formats (including YAML) and templates.
+func writeProjected[T any](w io.Writer, items []T, f Formatter, format Format, opts Options) error {
+	rf, ok := f.(RecordFormatter)
+	if !ok {
+		return fmt.Errorf("--fields is only supported with json, ndjson, yaml and template output (use --columns for %s)", format)
+	}
+
+	projected, err := projectItems(items, opts.Fields)
+	if err != nil {
+		return err
+	}
+	return rf.WriteRecords(w, projected, opts)
+}
+
+// projectItems reduces each item's JSON form to fields
+func projectItems[T any](items []T, fields []string) ([]query.Projection, error) {
+	projected := make([]query.Projection, len(items))
+	for i, item := range items {
+		record, err := query.ToRecord(item)
+		if err != nil {
+			return nil, err
+		}
+		projected[i] = query.Project(record, fields)
+	}
+	return projected, nil
+}
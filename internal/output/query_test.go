@@ -0,0 +1,38 @@
+package output
+
+import (
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/query"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFormatChats_Where tests filtering results before formatting
+func TestFormatChats_Where(t *testing.T) {
+	where, err := query.Parse("unreadCount > 0")
+	require.NoError(t, err)
+
+	result := FormatChatsWithOptions(testChats, FormatText, Options{Where: where})
+	assert.Contains(t, result, "chat1")
+	assert.NotContains(t, result, "chat2")
+
+	none, err := query.Parse(`title == "nothing"`)
+	require.NoError(t, err)
+	assert.Contains(t, FormatChatsWithOptions(testChats, FormatText, Options{Where: none}), "No chats found")
+}
+
+// TestFormatMessages_Fields tests projecting results to selected fields
+func TestFormatMessages_Fields(t *testing.T) {
+	opts := Options{Fields: []string{"senderName", "text"}}
+
+	assert.Equal(t,
+		"{\"senderName\":\"Alice\",\"text\":\"Hello, world!\"}\n{\"senderName\":\"Bob\",\"text\":\"How are you?\"}\n",
+		FormatMessagesWithOptions(testMessages, FormatNDJSON, opts))
+
+	result := FormatMessagesWithOptions(testMessages, FormatJSON, opts)
+	assert.Contains(t, result, `"senderName": "Alice"`)
+	assert.NotContains(t, result, "msg1")
+
+	assert.Contains(t, FormatMessagesWithOptions(testMessages, FormatTable, opts), "--fields is only supported")
+}
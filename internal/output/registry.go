@@ -0,0 +1,75 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Registration describes a registered output format
+type Registration struct {
+	Name        Format
+	Description string
+	// Streams is true if writing successive pages to the same writer yields
+	// one valid document, so large results need not be buffered
+	Streams   bool
+	Formatter Formatter
+}
+
+var registry = make(map[Format]Registration)
+
+// Register makes a formatter available under its name. Formats are
+// registered from init functions, so registering a name twice panics.
+func Register(r Registration) {
+	if _, exists := registry[r.Name]; exists {
+		panic(fmt.Sprintf("output: format %q registered twice", r.Name))
+	}
+	registry[r.Name] = r
+}
+
+// Lookup returns the formatter registered for format
+func Lookup(format Format) (Formatter, error) {
+	r, ok := lookupRegistration(format)
+	if !ok {
+		return nil, unknownFormatError(string(format))
+	}
+	return r.Formatter, nil
+}
+
+func lookupRegistration(format Format) (Registration, bool) {
+	r, ok := registry[format]
+	return r, ok
+}
+
+// Formats returns every registered format, sorted by name
+func Formats() []Registration {
+	formats := make([]Registration, 0, len(registry))
+	for _, r := range registry {
+		formats = append(formats, r)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i].Name < formats[j].Name })
+	return formats
+}
+
+// FormatNames returns the names of every registered format, sorted
+func FormatNames() []string {
+	formats := Formats()
+	names := make([]string, len(formats))
+	for i, r := range formats {
+		names[i] = string(r.Name)
+	}
+	return names
+}
+
+// ValidateFormat returns an error listing the registered formats if name is
+// not one of them
+func ValidateFormat(name string) error {
+	if _, ok := lookupRegistration(Format(name)); !ok {
+		return unknownFormatError(name)
+	}
+	return nil
+}
+
+func unknownFormatError(name string) error {
+	return fmt.Errorf("invalid output format: %s (available: %s)", name, strings.Join(FormatNames(), ", "))
+}
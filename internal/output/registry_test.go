@@ -0,0 +1,62 @@
+package output
+
+import (
+	"io"
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatNames(t *testing.T) {
+	names := FormatNames()
+	for _, want := range []string{"csv", "html", "json", "markdown", "ndjson", "table", "template", "text", "tsv", "yaml"} {
+		assert.Contains(t, names, want)
+	}
+	assert.IsIncreasing(t, names)
+}
+
+func TestValidateFormat(t *testing.T) {
+	assert.NoError(t, ValidateFormat("table"))
+
+	err := ValidateFormat("xml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid output format: xml")
+	assert.Contains(t, err.Error(), "csv, html, json")
+}
+
+func TestFormatStreams(t *testing.T) {
+	assert.True(t, FormatNDJSON.Streams())
+	assert.False(t, FormatJSON.Streams())
+	assert.False(t, Format("xml").Streams())
+}
+
+// idsFormatter is a minimal Formatter that writes one chat ID per line
+type idsFormatter struct{ textFormatter }
+
+func (idsFormatter) WriteChats(w io.Writer, chats []api.Chat, _ Options) error {
+	for _, chat := range chats {
+		if _, err := io.WriteString(w, chat.ID+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	const name = Format("ids-test")
+	Register(Registration{Name: name, Description: "Chat IDs", Formatter: idsFormatter{}})
+	defer delete(registry, name)
+
+	assert.NoError(t, ValidateFormat(string(name)))
+	assert.Equal(t, "chat1\nchat2\n", FormatChats(testChats, name))
+
+	// Projections need a RecordFormatter
+	result := FormatChatsWithOptions(testChats, name, Options{Fields: []string{"id"}})
+	assert.Contains(t, result, "--fields is only supported")
+
+	assert.Panics(t, func() {
+		Register(Registration{Name: name, Formatter: idsFormatter{}})
+	})
+}
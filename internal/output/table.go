@@ -0,0 +1,317 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+func init() {
+	Register(Registration{
+		Name:        FormatTable,
+		Description: "Aligned columns (see --columns)",
+		Formatter:   tableFormatter{},
+	})
+}
+
+// tableFormatter writes results as aligned columns
+type tableFormatter struct{}
+
+func (tableFormatter) WriteChats(w io.Writer, chats []api.Chat, opts Options) error {
+	if len(chats) == 0 {
+		return writeEmpty(w, "chats")
+	}
+	return renderTable(w, chats, selectColumns(chatColumns, opts.Columns, defaultChatColumns), chatStyle(opts))
+}
+
+func (tableFormatter) WriteMessages(w io.Writer, messages []api.Message, opts Options) error {
+	if len(messages) == 0 {
+		return writeEmpty(w, "messages")
+	}
+	return renderTable(w, messages, selectColumns(messageColumnsFor(opts), opts.Columns, defaultMessageColumns), messageStyle(opts))
+}
+
+func (tableFormatter) WriteAccounts(w io.Writer, accounts []api.Account, opts Options) error {
+	if len(accounts) == 0 {
+		return writeEmpty(w, "accounts")
+	}
+	return renderTable(w, accounts, selectColumns(accountColumns, opts.Columns, defaultAccountColumns), nil)
+}
+
+func (tableFormatter) WriteSendResult(w io.Writer, result SendResult, _ Options) error {
+	return writeSendText(w, result)
+}
+
+// WriteInbox writes the summary above one row per unread message
+func (tableFormatter) WriteInbox(w io.Writer, inbox Inbox, opts Options) error {
+	if len(inbox.Networks) == 0 {
+		return writeEmpty(w, "unread chats")
+	}
+	if _, err := fmt.Fprintf(w, "%s\n\n", inbox.Summary); err != nil {
+		return err
+	}
+	return renderTable(w, inboxRows(inbox), selectColumns(inboxColumnsFor(opts), opts.Columns, defaultInboxColumns), inboxStyle(opts))
+}
+
+// column describes a selectable column for tabular output
+type column[T any] struct {
+	name     string
+	header   string
+	maxWidth int // 0 means never truncate
+	value    func(T) string
+}
+
+var chatColumns = []column[api.Chat]{
+	{"id", "ID", 0, func(c api.Chat) string { return c.ID }},
+	{"title", "TITLE", 40, func(c api.Chat) string { return c.Title }},
+	{"type", "TYPE", 0, func(c api.Chat) string { return c.Type }},
+	{"network", "NETWORK", 20, func(c api.Chat) string { return c.Network }},
+	{"unread", "UNREAD", 0, func(c api.Chat) string { return strconv.Itoa(c.UnreadCount) }},
+	{"muted", "MUTED", 0, func(c api.Chat) string { return yesNo(c.IsMuted) }},
+	{"archived", "ARCHIVED", 0, func(c api.Chat) string { return yesNo(c.IsArchived) }},
+	{"pinned", "PINNED", 0, func(c api.Chat) string { return yesNo(c.IsPinned) }},
+}
+
+var messageColumns = []column[api.Message]{
+	{"id", "ID", 0, func(m api.Message) string { return m.ID }},
+	{"chat", "CHAT", 0, func(m api.Message) string { return m.ChatID }},
+	{"time", "TIME", 0, func(m api.Message) string { return m.Timestamp }},
+	{"sender", "SENDER", 24, func(m api.Message) string { return m.Sender }},
+	{"text", "TEXT", 80, func(m api.Message) string { return m.Text }},
+	{"is_sender", "IS_SENDER", 0, func(m api.Message) string { return yesNo(m.IsSender) }},
+	{"snippet", "SNIPPET", 80, func(m api.Message) string { return m.Snippet }},
+}
+
+var accountColumns = []column[api.Account]{
+	{"id", "ID", 0, func(a api.Account) string { return a.ID }},
+	{"network", "NETWORK", 20, func(a api.Account) string { return a.Network }},
+	{"user", "USER", 32, func(a api.Account) string { return accountUserName(a) }},
+	{"username", "USERNAME", 32, func(a api.Account) string { return a.User.Username }},
+}
+
+// messageColumnsFor returns messageColumns with the time column rendered in
+// the zone and format chosen by --tz and --time-format
+func messageColumnsFor(opts Options) []column[api.Message] {
+	cols := make([]column[api.Message], len(messageColumns))
+	copy(cols, messageColumns)
+	for i := range cols {
+		if cols[i].name == "time" {
+			cols[i].value = func(m api.Message) string { return messageTime(m, opts) }
+		}
+	}
+	return cols
+}
+
+// Column names accepted by --columns for each result type
+var (
+	ChatColumns    = columnNames(chatColumns)
+	MessageColumns = columnNames(messageColumns)
+	AccountColumns = columnNames(accountColumns)
+)
+
+// Default columns used when --columns is not given
+var (
+	defaultChatColumns    = []string{"id", "title", "network", "unread"}
+	defaultMessageColumns = []string{"time", "sender", "text"}
+	defaultAccountColumns = []string{"id", "network", "user"}
+)
+
+// ParseColumns splits a comma-separated column list and validates each name
+// against the available columns. An empty spec returns nil (use defaults).
+func ParseColumns(spec string, available []string) ([]string, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+
+	valid := make(map[string]bool, len(available))
+	for _, name := range available {
+		valid[name] = true
+	}
+
+	var columns []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !valid[name] {
+			return nil, fmt.Errorf("unknown column: %s (available: %s)", name, strings.Join(available, ", "))
+		}
+		columns = append(columns, name)
+	}
+
+	return columns, nil
+}
+
+func columnNames[T any](cols []column[T]) []string {
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = col.name
+	}
+	return names
+}
+
+// selectColumns returns the column definitions matching names, in order.
+// Unknown names are skipped; an empty selection falls back to defaults.
+func selectColumns[T any](cols []column[T], names, defaults []string) []column[T] {
+	if len(names) == 0 {
+		names = defaults
+	}
+
+	byName := make(map[string]column[T], len(cols))
+	for _, col := range cols {
+		byName[col.name] = col
+	}
+
+	selected := make([]column[T], 0, len(names))
+	for _, name := range names {
+		if col, ok := byName[name]; ok {
+			selected = append(selected, col)
+		}
+	}
+	return selected
+}
+
+// cellStyle decorates a table cell, for example with color, after it has been
+// measured. It receives the item, the column name and the plain cell text.
+type cellStyle[T any] func(item T, column, cell string) string
+
+// chatStyle dims muted chats and highlights unread counts
+func chatStyle(opts Options) cellStyle[api.Chat] {
+	if !opts.Color {
+		return nil
+	}
+	return func(c api.Chat, column, cell string) string {
+		switch {
+		case c.IsMuted:
+			return paint(cell, ansiDim)
+		case column == "unread":
+			return paintUnread(cell, c.UnreadCount)
+		}
+		return cell
+	}
+}
+
+// messageStyle colors senders, dims timestamps and highlights search matches
+func messageStyle(opts Options) cellStyle[api.Message] {
+	if !opts.Color {
+		return nil
+	}
+	return func(m api.Message, column, cell string) string {
+		switch column {
+		case "sender":
+			return paintSender(cell)
+		case "time":
+			return paint(cell, ansiDim)
+		case "text", "snippet":
+			return highlightMatches(cell, opts.Highlight)
+		}
+		return cell
+	}
+}
+
+// inboxStyle colors senders and dims timestamps like messageStyle, and
+// flags chats whose messages could not be fetched
+func inboxStyle(opts Options) cellStyle[inboxRow] {
+	if !opts.Color {
+		return nil
+	}
+	return func(r inboxRow, column, cell string) string {
+		switch column {
+		case "sender":
+			return paintSender(cell)
+		case "time":
+			return paint(cell, ansiDim)
+		case "error":
+			return paint(cell, ansiRed)
+		}
+		return cell
+	}
+}
+
+// renderTable writes items as aligned columns with a header row. Column widths
+// depend on every row, so the whole result set is measured before writing.
+// A non-nil style decorates cells and makes the header bold; padding is
+// computed from the plain text so escape codes never break alignment.
+func renderTable[T any](w io.Writer, items []T, cols []column[T], style cellStyle[T]) error {
+	rows := make([][]string, 0, len(items)+1)
+
+	header := make([]string, len(cols))
+	for i, col := range cols {
+		header[i] = col.header
+	}
+	rows = append(rows, header)
+
+	for _, item := range items {
+		row := make([]string, len(cols))
+		for i, col := range cols {
+			row[i] = tableCell(col.value(item), col.maxWidth)
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(cols))
+	for _, row := range rows {
+		for i, cell := range row {
+			if width := runewidth.StringWidth(cell); width > widths[i] {
+				widths[i] = width
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for r, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			styled := cell
+			if style != nil && r == 0 {
+				styled = paint(cell, ansiBold)
+			} else if style != nil {
+				styled = style(items[r-1], cols[i].name, cell)
+			}
+			line.WriteString(styled)
+			if i < len(row)-1 {
+				line.WriteString(strings.Repeat(" ", widths[i]-runewidth.StringWidth(cell)))
+			}
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " "))
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// tableCell flattens whitespace and truncates a value to the given display width
+func tableCell(value string, maxWidth int) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if maxWidth > 0 && runewidth.StringWidth(value) > maxWidth {
+		value = runewidth.Truncate(value, maxWidth, "…")
+	}
+	return value
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// accountUserName returns the best available display name for an account's user
+func accountUserName(a api.Account) string {
+	switch {
+	case a.User.FullName != "":
+		return a.User.FullName
+	case a.User.Username != "":
+		return a.User.Username
+	default:
+		return a.User.ID
+	}
+}
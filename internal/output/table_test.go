@@ -0,0 +1,95 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFormatChatsTable tests default table columns for chats
+func TestFormatChatsTable(t *testing.T) {
+	result := FormatChats(testChats, FormatTable)
+
+	lines := strings.Split(strings.TrimRight(result, "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"ID", "TITLE", "NETWORK", "UNREAD"}, strings.Fields(lines[0]))
+	assert.Contains(t, lines[1], "Test Chat 1")
+	assert.True(t, strings.HasSuffix(lines[1], "5"))
+}
+
+// TestFormatChatsTable_Columns tests column selection and ordering
+func TestFormatChatsTable_Columns(t *testing.T) {
+	result := FormatChatsWithOptions(testChats, FormatTable, Options{Columns: []string{"unread", "id"}})
+
+	lines := strings.Split(strings.TrimRight(result, "\n"), "\n")
+	assert.Equal(t, []string{"UNREAD", "ID"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"5", "chat1"}, strings.Fields(lines[1]))
+}
+
+// TestFormatTable_Alignment tests that columns align for wide characters
+func TestFormatTable_Alignment(t *testing.T) {
+	chats := []api.Chat{
+		{ID: "a", Title: "日本語のチャット", Network: "line"},
+		{ID: "b", Title: "plain", Network: "slack"},
+	}
+
+	result := FormatChatsWithOptions(chats, FormatTable, Options{Columns: []string{"title", "network"}})
+	lines := strings.Split(strings.TrimRight(result, "\n"), "\n")
+
+	col := runewidth.StringWidth(lines[1]) - runewidth.StringWidth("line")
+	assert.Equal(t, col, runewidth.StringWidth(lines[2])-runewidth.StringWidth("slack"))
+	assert.Equal(t, col, strings.Index(lines[0], "NETWORK"))
+}
+
+// TestFormatTable_Truncation tests truncation of long and multi-line values
+func TestFormatTable_Truncation(t *testing.T) {
+	msg := api.Message{
+		Sender:    "Alice",
+		Timestamp: "2021-12-20T00:00:00Z",
+		Text:      "first line\nsecond line " + strings.Repeat("界", 100),
+	}
+
+	result := FormatMessages([]api.Message{msg}, FormatTable)
+	lines := strings.Split(strings.TrimRight(result, "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], "first line second line")
+	assert.True(t, strings.HasSuffix(lines[1], "…"))
+}
+
+// TestFormatEmptyTable tests table output with no results
+func TestFormatEmptyTable(t *testing.T) {
+	assert.Contains(t, FormatChats([]api.Chat{}, FormatTable), "No chats found")
+	assert.Contains(t, FormatMessages([]api.Message{}, FormatTable), "No messages found")
+	assert.Contains(t, FormatAccounts([]api.Account{}, FormatTable), "No accounts found")
+}
+
+// TestFormatAccountsTable tests table output for accounts
+func TestFormatAccountsTable(t *testing.T) {
+	accounts := []api.Account{
+		{ID: "acc1", Network: "whatsapp", User: api.AccountUser{ID: "u1", FullName: "Alice Smith"}},
+		{ID: "acc2", Network: "telegram", User: api.AccountUser{ID: "u2", Username: "bob"}},
+	}
+
+	result := FormatAccounts(accounts, FormatTable)
+	assert.Contains(t, result, "Alice Smith")
+	assert.Contains(t, result, "bob")
+	assert.Contains(t, result, "telegram")
+}
+
+// TestParseColumns tests column list parsing and validation
+func TestParseColumns(t *testing.T) {
+	columns, err := ParseColumns(" id, Title ,unread", ChatColumns)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "title", "unread"}, columns)
+
+	columns, err = ParseColumns("", ChatColumns)
+	require.NoError(t, err)
+	assert.Nil(t, columns)
+
+	_, err = ParseColumns("id,bogus", ChatColumns)
+	assert.ErrorContains(t, err, "unknown column: bogus")
+}
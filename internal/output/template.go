@@ -0,0 +1,221 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/query"
+)
+
+func init() {
+	Register(Registration{
+		Name:        FormatTemplate,
+		Description: "Go text/template from --template or --template-file",
+		Formatter:   templateFormatter{},
+	})
+}
+
+// templateFormatter executes the user's template with the same data the JSON
+// formatter would marshal
+type templateFormatter struct{}
+
+func (templateFormatter) WriteChats(w io.Writer, chats []api.Chat, opts Options) error {
+	return writeTemplate(w, opts.Template, chats)
+}
+
+func (templateFormatter) WriteMessages(w io.Writer, messages []api.Message, opts Options) error {
+	return writeTemplate(w, opts.Template, messages)
+}
+
+func (templateFormatter) WriteAccounts(w io.Writer, accounts []api.Account, opts Options) error {
+	return writeTemplate(w, opts.Template, accounts)
+}
+
+func (templateFormatter) WriteSendResult(w io.Writer, result SendResult, opts Options) error {
+	return writeTemplate(w, opts.Template, result)
+}
+
+func (templateFormatter) WriteInbox(w io.Writer, inbox Inbox, opts Options) error {
+	return writeTemplate(w, opts.Template, inbox)
+}
+
+func (templateFormatter) WriteRecords(w io.Writer, records []query.Projection, opts Options) error {
+	values := make([]map[string]interface{}, len(records))
+	for i, p := range records {
+		values[i] = p.Values
+	}
+	return writeTemplate(w, opts.Template, values)
+}
+
+// templateFuncs are the helper functions available to --template output
+var templateFuncs = template.FuncMap{
+	"timefmt":         timeFormat,
+	"truncate":        truncate,
+	"join":            join,
+	"json":            jsonEscape,
+	"participants":    participantNames,
+	"participantName": participantName,
+}
+
+// ParseTemplate parses a --template string with the output helper functions
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// ParseTemplateFile reads and parses a --template-file
+func ParseTemplateFile(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template file: %w", err)
+	}
+	return ParseTemplate(string(data))
+}
+
+// writeTemplate executes tmpl with data, which is the same value the JSON
+// formatter would marshal (a slice of chats or messages, or a send result)
+func writeTemplate(w io.Writer, tmpl *template.Template, data interface{}) error {
+	if tmpl == nil {
+		return fmt.Errorf("no template given (use --template or --template-file)")
+	}
+	return tmpl.Execute(w, data)
+}
+
+// timeFormat formats an ISO 8601 timestamp or time.Time using a Go layout or
+// one of the names rfc3339, date, time, datetime or kitchen. Unparseable
+// timestamps are returned unchanged.
+func timeFormat(layout string, value interface{}) string {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return v
+		}
+		t = parsed
+	default:
+		return fmt.Sprint(value)
+	}
+
+	switch layout {
+	case "rfc3339":
+		layout = time.RFC3339
+	case "date":
+		layout = time.DateOnly
+	case "time":
+		layout = "15:04"
+	case "datetime":
+		layout = "2006-01-02 15:04"
+	case "kitchen":
+		layout = time.Kitchen
+	}
+	return t.Format(layout)
+}
+
+// truncate shortens s to at most width display columns, ending with "…"
+func truncate(width int, s string) string {
+	if runewidth.StringWidth(s) <= width {
+		return s
+	}
+	return runewidth.Truncate(s, width, "…")
+}
+
+// join joins a list of values with sep
+func join(sep string, values interface{}) string {
+	switch v := values.(type) {
+	case []string:
+		return strings.Join(v, sep)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, sep)
+	default:
+		return fmt.Sprint(values)
+	}
+}
+
+// jsonEscape returns value encoded as JSON, e.g. a quoted and escaped string
+func jsonEscape(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// participantList returns the participant objects of a chat. The API nests
+// them as {"items": [...]}; flat maps keyed by name are treated as names.
+func participantList(chat api.Chat) []map[string]interface{} {
+	items, ok := chat.Participants["items"].([]interface{})
+	if !ok {
+		names := make([]string, 0, len(chat.Participants))
+		for name := range chat.Participants {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		list := make([]map[string]interface{}, len(names))
+		for i, name := range names {
+			list[i] = map[string]interface{}{"id": name, "fullName": name}
+		}
+		return list
+	}
+
+	list := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if p, ok := item.(map[string]interface{}); ok {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// displayName returns the best available name for a participant object
+func displayName(p map[string]interface{}) string {
+	for _, key := range []string{"fullName", "username", "id"} {
+		if name, ok := p[key].(string); ok && name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// participantNames returns the display names of a chat's participants
+func participantNames(chat api.Chat) []string {
+	list := participantList(chat)
+	names := make([]string, 0, len(list))
+	for _, p := range list {
+		if name := displayName(p); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// participantName looks up a participant's display name by user ID, falling
+// back to the ID itself when the chat does not list that participant
+func participantName(chat api.Chat, userID string) string {
+	for _, p := range participantList(chat) {
+		if id, _ := p["id"].(string); id == userID {
+			if name := displayName(p); name != "" {
+				return name
+			}
+		}
+	}
+	return userID
+}
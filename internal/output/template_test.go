@@ -0,0 +1,89 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFormatChatsTemplate tests rendering chats through a template
+func TestFormatChatsTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{range .}}{{.Title}}={{.UnreadCount}};{{end}}`)
+	require.NoError(t, err)
+
+	result := FormatChatsWithOptions(testChats, FormatTemplate, Options{Template: tmpl})
+	assert.Equal(t, "Test Chat 1=5;Test Chat 2=0;", result)
+}
+
+// TestFormatMessagesTemplate_Helpers tests the template helper functions
+func TestFormatMessagesTemplate_Helpers(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{range .}}{{timefmt "15:04" .Timestamp}} {{.Sender}}: {{truncate 8 .Text | json}}
+{{end}}`)
+	require.NoError(t, err)
+
+	result := FormatMessagesWithOptions(testMessages, FormatTemplate, Options{Template: tmpl})
+	assert.Equal(t, "00:00 Alice: \"Hello, …\"\n00:01 Bob: \"How are…\"\n", result)
+}
+
+// TestWriteSendResultTemplate tests rendering a send result through a template
+func TestWriteSendResultTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`sent {{.MessageID}} to {{.ChatID}}`)
+	require.NoError(t, err)
+
+	result := SendResult{Success: true, MessageID: "msg1", ChatID: "chat1"}
+	var sb strings.Builder
+	require.NoError(t, WriteSendResult(&sb, result, FormatTemplate, Options{Template: tmpl}))
+	assert.Equal(t, "sent msg1 to chat1", sb.String())
+}
+
+// TestParseTemplateFile tests loading a template from disk
+func TestParseTemplateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chats.tmpl")
+	require.NoError(t, os.WriteFile(path, []byte(`{{len .}} chats`), 0644))
+
+	tmpl, err := ParseTemplateFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "2 chats", FormatChatsWithOptions(testChats, FormatTemplate, Options{Template: tmpl}))
+
+	_, err = ParseTemplateFile(filepath.Join(t.TempDir(), "missing.tmpl"))
+	assert.Error(t, err)
+
+	_, err = ParseTemplate(`{{.Title`)
+	assert.ErrorContains(t, err, "invalid template")
+}
+
+// TestParticipantHelpers tests participant name lookup for both participant shapes
+func TestParticipantHelpers(t *testing.T) {
+	chat := api.Chat{
+		ID: "chat1",
+		Participants: map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"id": "@alice:beeper.com", "fullName": "Alice Smith"},
+				map[string]interface{}{"id": "@bob:beeper.com", "username": "bob"},
+			},
+		},
+	}
+
+	assert.Equal(t, []string{"Alice Smith", "bob"}, participantNames(chat))
+	assert.Equal(t, "Alice Smith", participantName(chat, "@alice:beeper.com"))
+	assert.Equal(t, "@carol:beeper.com", participantName(chat, "@carol:beeper.com"))
+
+	assert.Equal(t, []string{"Alice", "Bob"}, participantNames(testChats[0]))
+
+	tmpl, err := ParseTemplate(`{{range .}}{{participants . | join ", "}}{{end}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Smith, bob", FormatChatsWithOptions([]api.Chat{chat}, FormatTemplate, Options{Template: tmpl}))
+}
+
+// TestTimeFormat tests named and custom layouts and unparseable input
+func TestTimeFormat(t *testing.T) {
+	assert.Equal(t, "2021-12-20", timeFormat("date", "2021-12-20T00:01:40Z"))
+	assert.Equal(t, "2021-12-20 00:01", timeFormat("datetime", "2021-12-20T00:01:40Z"))
+	assert.Equal(t, "Dec 20", timeFormat("Jan 2", "2021-12-20T00:01:40Z"))
+	assert.Equal(t, "not a time", timeFormat("date", "not a time"))
+}
@@ -0,0 +1,154 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+func init() {
+	Register(Registration{
+		Name:        FormatText,
+		Description: "Plain human-readable text",
+		Formatter:   textFormatter{},
+	})
+}
+
+// textFormatter writes results as plain human-readable text
+type textFormatter struct{}
+
+func (textFormatter) WriteChats(w io.Writer, chats []api.Chat, opts Options) error {
+	if len(chats) == 0 {
+		return writeEmpty(w, "chats")
+	}
+
+	var sb strings.Builder
+	for _, chat := range chats {
+		unread := strconv.Itoa(chat.UnreadCount)
+		if opts.Color && !chat.IsMuted {
+			unread = paintUnread(unread, chat.UnreadCount)
+		}
+
+		var block strings.Builder
+		block.WriteString(fmt.Sprintf("ID: %s\n", chat.ID))
+		block.WriteString(fmt.Sprintf("Title: %s\n", chat.Title))
+		block.WriteString(fmt.Sprintf("Type: %s\n", chat.Type))
+		block.WriteString(fmt.Sprintf("Network: %s\n", chat.Network))
+		block.WriteString(fmt.Sprintf("Unread: %s\n", unread))
+		if chat.IsMuted {
+			block.WriteString("Muted: Yes\n")
+		}
+
+		if opts.Color && chat.IsMuted {
+			sb.WriteString(paintLines(strings.TrimSuffix(block.String(), "\n"), ansiDim) + "\n")
+		} else {
+			sb.WriteString(block.String())
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (textFormatter) WriteMessages(w io.Writer, messages []api.Message, opts Options) error {
+	if len(messages) == 0 {
+		return writeEmpty(w, "messages")
+	}
+
+	var sb strings.Builder
+	var lastDay string
+	for _, msg := range messages {
+		if day, ok := messageDay(msg, opts); ok && day != lastDay {
+			if lastDay != "" {
+				sb.WriteString("\n")
+			}
+			header := fmt.Sprintf("--- %s ---", day)
+			if opts.Color {
+				header = paint(header, ansiBold)
+			}
+			sb.WriteString(header + "\n")
+			lastDay = day
+		}
+
+		stamp := "[" + messageTime(msg, opts) + "]"
+		sender, text := msg.Sender, msg.Text
+		if opts.Color {
+			stamp = paint(stamp, ansiDim)
+			sender = paintSender(sender)
+			text = highlightMatches(text, opts.Highlight)
+		}
+		sb.WriteString(fmt.Sprintf("%s %s: %s\n", stamp, sender, text))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (textFormatter) WriteAccounts(w io.Writer, accounts []api.Account, _ Options) error {
+	if len(accounts) == 0 {
+		return writeEmpty(w, "accounts")
+	}
+
+	var sb strings.Builder
+	for _, account := range accounts {
+		sb.WriteString(fmt.Sprintf("ID: %s\n", account.ID))
+		sb.WriteString(fmt.Sprintf("Network: %s\n", account.Network))
+		sb.WriteString(fmt.Sprintf("User: %s\n", accountUserName(account)))
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func (textFormatter) WriteSendResult(w io.Writer, result SendResult, _ Options) error {
+	return writeSendText(w, result)
+}
+
+// WriteInbox writes the summary, then a heading per network and per chat
+// above that chat's unread messages
+func (textFormatter) WriteInbox(w io.Writer, inbox Inbox, opts Options) error {
+	if len(inbox.Networks) == 0 {
+		return writeEmpty(w, "unread chats")
+	}
+
+	bold := func(s string) string {
+		if opts.Color {
+			return paint(s, ansiBold)
+		}
+		return s
+	}
+
+	var sb strings.Builder
+	sb.WriteString(bold("Inbox: "+inbox.Summary.String()) + "\n")
+	for _, network := range inbox.Networks {
+		sb.WriteString(fmt.Sprintf("\n%s\n", bold(fmt.Sprintf("== %s (%s, %d unread) ==",
+			networkLabel(network.Network), plural(len(network.Chats), "chat"), network.Unread))))
+		for _, chat := range network.Chats {
+			sb.WriteString(fmt.Sprintf("\n%s (%d unread)\n", bold(chat.title()), chat.Chat.UnreadCount))
+			if chat.Error != "" {
+				line := "  ! " + chat.Error
+				if opts.Color {
+					line = paint(line, ansiRed)
+				}
+				sb.WriteString(line + "\n")
+				continue
+			}
+			if earlier := chat.earlierUnread(); earlier > 0 {
+				sb.WriteString(fmt.Sprintf("  … %d earlier unread\n", earlier))
+			}
+			for _, msg := range chat.Messages {
+				stamp := "[" + messageTime(msg, opts) + "]"
+				sender, text := msg.Sender, strings.ReplaceAll(msg.Text, "\n", "\n    ")
+				if opts.Color {
+					stamp = paint(stamp, ansiDim)
+					sender = paintSender(sender)
+				}
+				sb.WriteString(fmt.Sprintf("  %s %s: %s\n", stamp, sender, text))
+			}
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,145 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// Named --time-format values; anything else is used as a Go time layout
+const (
+	TimeFormatRFC3339  = "rfc3339"
+	TimeFormatShort    = "short"
+	TimeFormatRelative = "relative"
+)
+
+// dayLayout labels the day headers of transcripts
+const dayLayout = "Monday, January 2, 2006"
+
+// now returns the current time, which relative timestamps are measured from
+var now = time.Now
+
+// ParseLocation resolves a --tz value: an IANA zone name such as
+// "America/New_York", "UTC", or "local" for the system zone. An empty name
+// returns nil, which keeps timestamps in the zone the API reported.
+func ParseLocation(name string) (*time.Location, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return nil, nil
+	case "local":
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone: %s (use a zone name such as America/New_York, UTC or local)", name)
+	}
+	return loc, nil
+}
+
+// ValidateTimeFormat checks a --time-format value. Custom layouts must
+// contain at least one element of Go's reference time.
+func ValidateTimeFormat(spec string) error {
+	switch spec {
+	case "", TimeFormatRFC3339, TimeFormatShort, TimeFormatRelative:
+		return nil
+	}
+	probe := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if probe.Format(spec) == spec {
+		return fmt.Errorf("invalid time format: %s (use rfc3339, short, relative or a Go layout such as \"Jan 2 15:04\")", spec)
+	}
+	return nil
+}
+
+// localTime parses a message's timestamp and moves it into opts.Location
+func localTime(msg api.Message, opts Options) (time.Time, bool) {
+	t, ok := msg.Time()
+	if !ok {
+		return time.Time{}, false
+	}
+	if opts.Location != nil {
+		t = t.In(opts.Location)
+	}
+	return t, true
+}
+
+// messageTime renders a message's timestamp using opts.TimeFormat in
+// opts.Location. With neither set, or if the timestamp cannot be parsed, the
+// timestamp is returned exactly as the API sent it.
+func messageTime(msg api.Message, opts Options) string {
+	if opts.TimeFormat == "" && opts.Location == nil {
+		return msg.Timestamp
+	}
+	t, ok := localTime(msg, opts)
+	if !ok {
+		return msg.Timestamp
+	}
+	return formatTime(t, opts.TimeFormat)
+}
+
+// formatTime formats t according to a --time-format value
+func formatTime(t time.Time, spec string) string {
+	switch spec {
+	case "", TimeFormatRFC3339:
+		return t.Format(time.RFC3339)
+	case TimeFormatShort:
+		return t.Format("2006-01-02 15:04")
+	case TimeFormatRelative:
+		return relativeTime(t, now())
+	default:
+		return t.Format(spec)
+	}
+}
+
+// relativeTime describes t relative to ref ("just now", "5m ago", "in 2h").
+// Times more than a week away fall back to the date.
+func relativeTime(t, ref time.Time) string {
+	d := ref.Sub(t)
+	suffix, prefix := " ago", ""
+	if d < 0 {
+		d = -d
+		suffix, prefix = "", "in "
+	}
+
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%s%dm%s", prefix, int(d/time.Minute), suffix)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%s%dh%s", prefix, int(d/time.Hour), suffix)
+	case d < 7*24*time.Hour:
+		return fmt.Sprintf("%s%dd%s", prefix, int(d/(24*time.Hour)), suffix)
+	case t.Year() == ref.Year():
+		return t.Format("Jan 2")
+	default:
+		return t.Format("Jan 2, 2006")
+	}
+}
+
+// messageDay returns the day header a message belongs under, in
+// opts.Location. Relative output labels the current and previous day
+// "Today" and "Yesterday". ok is false if the timestamp cannot be parsed.
+func messageDay(msg api.Message, opts Options) (label string, ok bool) {
+	t, ok := localTime(msg, opts)
+	if !ok {
+		return "", false
+	}
+	if opts.TimeFormat == TimeFormatRelative {
+		today := now().In(t.Location())
+		switch {
+		case sameDay(t, today):
+			return "Today", true
+		case sameDay(t, today.AddDate(0, 0, -1)):
+			return "Yesterday", true
+		}
+	}
+	return t.Format(dayLayout), true
+}
+
+func sameDay(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
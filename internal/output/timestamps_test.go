@@ -0,0 +1,114 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// freezeNow pins the clock used for relative timestamps
+func freezeNow(t *testing.T, at time.Time) {
+	t.Helper()
+	saved := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = saved })
+}
+
+func TestParseLocation(t *testing.T) {
+	loc, err := ParseLocation("")
+	require.NoError(t, err)
+	assert.Nil(t, loc)
+
+	loc, err = ParseLocation("local")
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, loc)
+
+	loc, err = ParseLocation("America/New_York")
+	require.NoError(t, err)
+	assert.Equal(t, "America/New_York", loc.String())
+
+	_, err = ParseLocation("Mars/Olympus")
+	assert.ErrorContains(t, err, "invalid time zone: Mars/Olympus")
+}
+
+func TestValidateTimeFormat(t *testing.T) {
+	for _, spec := range []string{"", "rfc3339", "short", "relative", "Jan 2 15:04", "15:04"} {
+		assert.NoError(t, ValidateTimeFormat(spec), spec)
+	}
+	assert.ErrorContains(t, ValidateTimeFormat("fancy"), "invalid time format: fancy")
+}
+
+func TestMessageTime(t *testing.T) {
+	msg := api.Message{Timestamp: "2021-12-20T15:30:00Z"}
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	assert.Equal(t, "2021-12-20T15:30:00Z", messageTime(msg, Options{}))
+	assert.Equal(t, "2021-12-20T10:30:00-05:00", messageTime(msg, Options{Location: ny}))
+	assert.Equal(t, "2021-12-20 10:30", messageTime(msg, Options{Location: ny, TimeFormat: "short"}))
+	assert.Equal(t, "Dec 20 3:30PM", messageTime(msg, Options{TimeFormat: "Jan 2 3:04PM"}))
+
+	// Unparseable timestamps are passed through
+	assert.Equal(t, "soon", messageTime(api.Message{Timestamp: "soon"}, Options{Location: ny}))
+}
+
+func TestRelativeTime(t *testing.T) {
+	ref := time.Date(2021, 12, 20, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{ref.Add(-30 * time.Second), "just now"},
+		{ref.Add(-5 * time.Minute), "5m ago"},
+		{ref.Add(-3 * time.Hour), "3h ago"},
+		{ref.Add(-50 * time.Hour), "2d ago"},
+		{ref.Add(2 * time.Hour), "in 2h"},
+		{ref.AddDate(0, 0, -10), "Dec 10"},
+		{ref.AddDate(-1, 0, 0), "Dec 20, 2020"},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, relativeTime(tc.at, ref))
+	}
+}
+
+func TestMessagesDayHeaders(t *testing.T) {
+	messages := []api.Message{
+		{Sender: "Alice", Text: "late", Timestamp: "2021-12-21T02:00:00Z"},
+		{Sender: "Bob", Text: "early", Timestamp: "2021-12-20T15:00:00Z"},
+	}
+
+	text := FormatMessages(messages, FormatText)
+	assert.Contains(t, text, "--- Tuesday, December 21, 2021 ---\n[2021-12-21T02:00:00Z] Alice: late\n\n--- Monday, December 20, 2021 ---\n")
+
+	// In New York both messages fall on the same day
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	md := FormatMessagesWithOptions(messages, FormatMarkdown, Options{Location: ny, TimeFormat: "15:04"})
+	assert.Equal(t, 1, strings.Count(md, "## "))
+	assert.Contains(t, md, "## Monday, December 20, 2021\n\n**Alice** - 21:00")
+}
+
+func TestMessagesRelativeDays(t *testing.T) {
+	freezeNow(t, time.Date(2021, 12, 21, 9, 0, 0, 0, time.UTC))
+	messages := []api.Message{
+		{Sender: "Alice", Text: "hi", Timestamp: "2021-12-21T08:55:00Z"},
+		{Sender: "Bob", Text: "yo", Timestamp: "2021-12-20T09:00:00Z"},
+	}
+
+	result := FormatMessagesWithOptions(messages, FormatText, Options{TimeFormat: TimeFormatRelative})
+	assert.Contains(t, result, "--- Today ---\n[5m ago] Alice: hi")
+	assert.Contains(t, result, "--- Yesterday ---\n[1d ago] Bob: yo")
+}
+
+func TestTableTimeColumn(t *testing.T) {
+	msg := api.Message{Sender: "Alice", Text: "hi", Timestamp: "2021-12-20T15:30:00Z"}
+	result := FormatMessagesWithOptions([]api.Message{msg}, FormatCSV, Options{
+		Columns:    []string{"time"},
+		TimeFormat: TimeFormatShort,
+	})
+	assert.Equal(t, "time\n2021-12-20 15:30\n", result)
+}
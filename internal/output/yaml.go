@@ -0,0 +1,82 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/query"
+	"go.yaml.in/yaml/v3"
+)
+
+func init() {
+	Register(Registration{
+		Name:        FormatYAML,
+		Description: "YAML documents with JSON field names",
+		Formatter:   yamlFormatter{},
+	})
+}
+
+// yamlFormatter writes results as YAML
+type yamlFormatter struct{}
+
+func (yamlFormatter) WriteChats(w io.Writer, chats []api.Chat, _ Options) error {
+	return writeYAML(w, chats)
+}
+
+func (yamlFormatter) WriteMessages(w io.Writer, messages []api.Message, _ Options) error {
+	return writeYAML(w, messages)
+}
+
+func (yamlFormatter) WriteAccounts(w io.Writer, accounts []api.Account, _ Options) error {
+	return writeYAML(w, accounts)
+}
+
+func (yamlFormatter) WriteSendResult(w io.Writer, result SendResult, _ Options) error {
+	return writeYAML(w, result)
+}
+
+func (yamlFormatter) WriteInbox(w io.Writer, inbox Inbox, _ Options) error {
+	return writeYAML(w, inbox)
+}
+
+func (yamlFormatter) WriteRecords(w io.Writer, records []query.Projection, _ Options) error {
+	return writeYAML(w, records)
+}
+
+// writeYAML writes v as a YAML document. The value is converted through its
+// JSON form so keys match the JSON output exactly and keep their order;
+// multi-line strings are emitted as literal block scalars.
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return enc.Close()
+}
+
+// blockStyle resets the flow and quoting styles inherited from JSON so the
+// encoder picks idiomatic YAML, and marks multi-line strings as literals
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" && strings.Contains(n.Value, "\n") {
+		n.Style = yaml.LiteralStyle
+	}
+	for _, child := range n.Content {
+		blockStyle(child)
+	}
+}
@@ -0,0 +1,49 @@
+package output
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.yaml.in/yaml/v3"
+)
+
+// TestFormatMessagesYAML tests YAML output uses the JSON field names
+func TestFormatMessagesYAML(t *testing.T) {
+	result := FormatMessages(testMessages, FormatYAML)
+
+	assert.True(t, strings.HasPrefix(result, "- id: msg1\n"))
+	assert.Contains(t, result, "  senderName: Alice\n")
+	assert.Contains(t, result, "  timestamp: \"2021-12-20T00:00:00Z\"\n")
+
+	var decoded []api.Message
+	require.NoError(t, yaml.Unmarshal([]byte(result), &decoded))
+	require.Len(t, decoded, 2)
+}
+
+// TestFormatMessagesYAML_BlockScalar tests multi-line text becomes a literal block
+func TestFormatMessagesYAML_BlockScalar(t *testing.T) {
+	msg := api.Message{ID: "msg1", Sender: "Alice", Text: "first line\nsecond line"}
+
+	result := FormatMessagesWithOptions([]api.Message{msg}, FormatYAML, Options{Fields: []string{"text"}})
+	assert.Equal(t, "- text: |-\n    first line\n    second line\n", result)
+}
+
+// TestFormatChatsYAML_Empty tests YAML output for an empty result set
+func TestFormatChatsYAML_Empty(t *testing.T) {
+	assert.Equal(t, "[]\n", FormatChats([]api.Chat{}, FormatYAML))
+}
+
+// TestWriteValueYAML tests structured output of arbitrary values
+func TestWriteValueYAML(t *testing.T) {
+	var sb strings.Builder
+	value := map[string]interface{}{"version": "1.0.0", "platform": "linux/amd64"}
+	require.NoError(t, WriteValue(&sb, value, FormatYAML))
+	assert.Equal(t, "platform: linux/amd64\nversion: 1.0.0\n", sb.String())
+
+	sb.Reset()
+	require.NoError(t, WriteSendResult(&sb, SendResult{Success: true, MessageID: "m1", ChatID: "c1"}, FormatYAML, Options{}))
+	assert.Equal(t, "success: true\nmessage_id: m1\nchat_id: c1\n", sb.String())
+}
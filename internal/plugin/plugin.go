@@ -0,0 +1,219 @@
+// Package plugin finds and runs external beeper-<name> executables on PATH,
+// so "beeper foo" can dispatch to "beeper-foo" the way git and kubectl do.
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Prefix is the file name prefix that marks an executable as a plugin
+const Prefix = "beeper-"
+
+// Plugin is a plugin executable found on PATH
+type Plugin struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// ShadowedBy is the builtin command or earlier plugin path that runs
+	// instead of this one, if any
+	ShadowedBy string `json:"shadowedBy,omitempty"`
+}
+
+// Find returns the plugin for the longest leading run of args that names
+// one, so "beeper foo bar" tries beeper-foo-bar before beeper-foo. It also
+// returns how many args the name used; the rest belong to the plugin.
+func Find(args []string, lookPath func(string) (string, error)) (string, int, bool) {
+	for n := len(args); n > 0; n-- {
+		if !validName(args[:n]) {
+			continue
+		}
+		path, err := lookPath(Prefix + strings.Join(args[:n], "-"))
+		if err == nil {
+			return path, n, true
+		}
+	}
+	return "", 0, false
+}
+
+// validName rejects args that can't be part of a plugin file name
+func validName(parts []string) bool {
+	for _, p := range parts {
+		if p == "" || strings.HasPrefix(p, "-") || strings.ContainsAny(p, `/\`) {
+			return false
+		}
+	}
+	return true
+}
+
+// List returns the plugins in the directories of a PATH value, in lookup
+// order. isBuiltin reports whether a name is a builtin command, which
+// always runs instead of a plugin of the same name.
+func List(pathEnv string, isBuiltin func(string) bool) []Plugin {
+	var plugins []Plugin
+	seen := make(map[string]string)
+	visited := make(map[string]bool)
+
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if dir == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		if visited[dir] {
+			continue
+		}
+		visited[dir] = true
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+
+			p := Plugin{Name: name, Path: path}
+			first := strings.SplitN(name, "-", 2)[0]
+			switch {
+			case isBuiltin != nil && isBuiltin(first):
+				p.ShadowedBy = "builtin command " + first
+			case seen[name] != "":
+				p.ShadowedBy = seen[name]
+			default:
+				seen[name] = path
+			}
+			plugins = append(plugins, p)
+		}
+	}
+	return plugins
+}
+
+// pluginName returns the command name a plugin file provides, without the
+// prefix and any Windows executable extension
+func pluginName(file string) (string, bool) {
+	if !strings.HasPrefix(file, Prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(file, Prefix)
+	if runtime.GOOS == "windows" {
+		ext := filepath.Ext(name)
+		if ext == "" || !isWindowsExecExt(ext) {
+			return "", false
+		}
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name, name != ""
+}
+
+func isWindowsExecExt(ext string) bool {
+	pathext := os.Getenv("PATHEXT")
+	if pathext == "" {
+		pathext = ".com;.exe;.bat;.cmd"
+	}
+	for _, e := range filepath.SplitList(pathext) {
+		if strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return info.Mode()&0111 != 0
+}
+
+// Env is the context a plugin receives in BEEPER_* environment variables,
+// resolved from the CLI's config file, environment and global flags
+type Env struct {
+	APIURL       string
+	Token        string
+	OutputFormat string
+	Envelope     bool
+	// ConfigPath is the config file the values were resolved from
+	ConfigPath string
+	// CLIPath is the beeper executable, for plugins that call back into it
+	CLIPath string
+	Version string
+}
+
+// Environ returns base with the plugin variables set, replacing any
+// existing values
+func (e Env) Environ(base []string) []string {
+	vars := map[string]string{
+		"BEEPER_API_URL":       e.APIURL,
+		"BEEPER_TOKEN":         e.Token,
+		"BEEPER_OUTPUT_FORMAT": e.OutputFormat,
+		"BEEPER_ENVELOPE":      strconv.FormatBool(e.Envelope),
+		"BEEPER_CONFIG":        e.ConfigPath,
+		"BEEPER_CLI_PATH":      e.CLIPath,
+		"BEEPER_CLI_VERSION":   e.Version,
+	}
+
+	env := make([]string, 0, len(base)+len(vars))
+	for _, kv := range base {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := vars[key]; !ok {
+			env = append(env, kv)
+		}
+	}
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if vars[k] != "" {
+			env = append(env, k+"="+vars[k])
+		}
+	}
+	return env
+}
+
+// Run executes a plugin with the terminal's stdio and returns its exit
+// code. Interrupts are left to the plugin, which shares the terminal's
+// process group; the CLI waits for it to exit rather than dying first.
+func Run(path string, args, env []string) (int, error) {
+	c := exec.Command(path, args...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	c.Env = env
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
+
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if code := exitErr.ExitCode(); code >= 0 {
+			return code, nil
+		}
+		// Killed by a signal
+		return 1, nil
+	}
+	if err != nil {
+		return 1, fmt.Errorf("failed to run plugin %s: %w", filepath.Base(path), err)
+	}
+	return 0, nil
+}
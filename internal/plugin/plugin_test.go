@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePlugin(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nexit 0\n"), 0755))
+	return path
+}
+
+func TestFind_LongestMatch(t *testing.T) {
+	available := map[string]bool{"beeper-foo": true, "beeper-foo-bar": true}
+	lookPath := func(name string) (string, error) {
+		if available[name] {
+			return "/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	path, n, ok := Find([]string{"foo", "bar", "baz"}, lookPath)
+	require.True(t, ok)
+	assert.Equal(t, "/bin/beeper-foo-bar", path)
+	assert.Equal(t, 2, n)
+
+	path, n, ok = Find([]string{"foo", "qux"}, lookPath)
+	require.True(t, ok)
+	assert.Equal(t, "/bin/beeper-foo", path)
+	assert.Equal(t, 1, n)
+
+	_, _, ok = Find([]string{"nope"}, lookPath)
+	assert.False(t, ok)
+}
+
+func TestFind_RejectsPaths(t *testing.T) {
+	lookPath := func(name string) (string, error) {
+		return "/bin/" + name, nil
+	}
+	_, _, ok := Find([]string{"../evil"}, lookPath)
+	assert.False(t, ok)
+}
+
+func TestList(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses executable bits")
+	}
+	first, second := t.TempDir(), t.TempDir()
+	writePlugin(t, first, "beeper-foo")
+	writePlugin(t, first, "beeper-chats-extra")
+	shadowed := writePlugin(t, second, "beeper-foo")
+	require.NoError(t, os.WriteFile(filepath.Join(second, "beeper-notexec"), nil, 0644))
+	writePlugin(t, second, "other-tool")
+
+	isBuiltin := func(name string) bool { return name == "chats" }
+	plugins := List(strings.Join([]string{first, second, first}, string(os.PathListSeparator)), isBuiltin)
+
+	require.Len(t, plugins, 3)
+	assert.Equal(t, "chats-extra", plugins[0].Name)
+	assert.Equal(t, "builtin command chats", plugins[0].ShadowedBy)
+	assert.Equal(t, "foo", plugins[1].Name)
+	assert.Empty(t, plugins[1].ShadowedBy)
+	assert.Equal(t, shadowed, plugins[2].Path)
+	assert.Equal(t, plugins[1].Path, plugins[2].ShadowedBy)
+}
+
+func TestEnviron(t *testing.T) {
+	env := Env{APIURL: "http://localhost:1234", OutputFormat: "text", ConfigPath: "/tmp/config.yaml", Version: "1.0.0"}
+	got := env.Environ([]string{"HOME=/home/u", "BEEPER_API_URL=http://old", "BEEPER_TOKEN=stale"})
+
+	assert.Contains(t, got, "HOME=/home/u")
+	assert.Contains(t, got, "BEEPER_API_URL=http://localhost:1234")
+	assert.Contains(t, got, "BEEPER_OUTPUT_FORMAT=text")
+	assert.Contains(t, got, "BEEPER_ENVELOPE=false")
+	assert.Contains(t, got, "BEEPER_CONFIG=/tmp/config.yaml")
+	assert.Contains(t, got, "BEEPER_CLI_VERSION=1.0.0")
+	assert.NotContains(t, got, "BEEPER_API_URL=http://old")
+	for _, kv := range got {
+		assert.False(t, strings.HasPrefix(kv, "BEEPER_TOKEN="), "unset token should not be passed: %s", kv)
+	}
+}
+
+func TestRun_ExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "beeper-fail")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\nexit 3\n"), 0755))
+
+	code, err := Run(path, nil, os.Environ())
+	require.NoError(t, err)
+	assert.Equal(t, 3, code)
+}
@@ -0,0 +1,86 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testRecord = map[string]interface{}{
+	"id":          "chat1",
+	"title":       "Family Group",
+	"network":     "whatsapp",
+	"unreadCount": float64(3),
+	"isMuted":     false,
+	"user":        map[string]interface{}{"fullName": "Alice"},
+}
+
+// TestExpr_Match tests expression evaluation against a record
+func TestExpr_Match(t *testing.T) {
+	testCases := []struct {
+		expr     string
+		expected bool
+	}{
+		{`unreadCount > 0 && network == "whatsapp"`, true},
+		{`unreadCount > 5 || network == 'telegram'`, false},
+		{`unreadCount >= 3 && unreadCount <= 3`, true},
+		{`!isMuted`, true},
+		{`isMuted`, false},
+		{`title =~ "(?i)family"`, true},
+		{`title != "Family Group"`, false},
+		{`user.fullName == "Alice"`, true},
+		{`missing == null`, true},
+		{`missing > 0`, false},
+		{`(network == "slack" || network == "whatsapp") && !(unreadCount < 1)`, true},
+		{`unreadCount == "3"`, false},
+		{`unreadCount > -1`, true},
+		{`id =~ "^chat\d+$"`, true},
+		{`id =~ "^chat\\d+$"`, true},
+		{`title =~ "Family\sGroup"`, true},
+		{`title == 'Family\'s'`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := Parse(tc.expr)
+			require.NoError(t, err)
+
+			ok, err := expr.Match(testRecord)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, ok)
+		})
+	}
+}
+
+// TestTokenize_Escapes tests that only quotes and backslashes are escaped
+func TestTokenize_Escapes(t *testing.T) {
+	for source, want := range map[string]string{
+		`"\d+"`:        `\d+`,
+		`"\\d+"`:       `\d+`,
+		`"say \"hi\""`: `say "hi"`,
+		`'it\'s'`:      `it's`,
+		`'a\"b'`:       `a\"b`,
+	} {
+		tokens, err := tokenize(source)
+		require.NoError(t, err, source)
+		assert.Equal(t, token{tokenString, want}, tokens[0], source)
+	}
+}
+
+// TestParse_Errors tests that malformed expressions are rejected
+func TestParse_Errors(t *testing.T) {
+	for _, source := range []string{
+		``,
+		`unreadCount >`,
+		`(network == "x"`,
+		`title == "unterminated`,
+		`title =~ 5`,
+		`title =~ "("`,
+		`a == b c`,
+		`a # b`,
+	} {
+		_, err := Parse(source)
+		assert.Error(t, err, source)
+	}
+}
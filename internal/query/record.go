@@ -0,0 +1,87 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ToRecord converts a value to its JSON object form, so that fields are
+// addressed by the same names the JSON output uses
+func ToRecord(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("result is not an object: %w", err)
+	}
+	return record, nil
+}
+
+// Lookup returns the value at a dotted field path, or nil if it is missing
+func Lookup(record map[string]interface{}, path []string) interface{} {
+	var current interface{} = record
+	for _, key := range path {
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = obj[key]
+	}
+	return current
+}
+
+// ParseFields splits a comma-separated --fields list
+func ParseFields(spec string) []string {
+	var fields []string
+	for _, field := range strings.Split(spec, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// Projection is a record reduced to selected fields. It marshals to a JSON
+// object with keys in the order the fields were requested.
+type Projection struct {
+	Fields []string
+	Values map[string]interface{}
+}
+
+// Project selects fields from a record. Missing fields are included as null.
+func Project(record map[string]interface{}, fields []string) Projection {
+	values := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		values[field] = Lookup(record, strings.Split(field, "."))
+	}
+	return Projection{Fields: fields, Values: values}
+}
+
+// MarshalJSON implements json.Marshaler, preserving field order
+func (p Projection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range p.Fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.Values[field])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,39 @@
+package query
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestToRecord tests conversion of a struct to its JSON field names
+func TestToRecord(t *testing.T) {
+	type item struct {
+		ID    string `json:"id"`
+		Count int    `json:"unreadCount"`
+	}
+
+	record, err := ToRecord(item{ID: "x", Count: 2})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"id": "x", "unreadCount": float64(2)}, record)
+
+	_, err = ToRecord([]int{1})
+	assert.Error(t, err)
+}
+
+// TestProject tests that projections keep requested fields in order
+func TestProject(t *testing.T) {
+	p := Project(testRecord, []string{"unreadCount", "id", "user.fullName", "missing"})
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	assert.Equal(t, `{"unreadCount":3,"id":"chat1","user.fullName":"Alice","missing":null}`, string(data))
+}
+
+// TestParseFields tests splitting of the --fields flag
+func TestParseFields(t *testing.T) {
+	assert.Equal(t, []string{"id", "title"}, ParseFields(" id, ,title "))
+	assert.Nil(t, ParseFields(""))
+}
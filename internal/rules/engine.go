@@ -0,0 +1,255 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/watch"
+)
+
+// Statuses in the log
+const (
+	StatusOK          = "ok"
+	StatusError       = "error"
+	StatusDryRun      = "dry_run"
+	StatusRateLimited = "rate_limited"
+)
+
+// Client is the part of the API client rules act through
+type Client interface {
+	SendMessage(chatID, text string) (string, error)
+	AddReaction(chatID, messageID, reactionKey string) error
+	MarkChatRead(chatID string) error
+	ArchiveChat(chatID string, archived bool) error
+}
+
+// State is what the rules keep between runs
+type State struct {
+	watch.State
+	// Fired maps rate limit keys to when the rule acted within its window
+	Fired map[string][]time.Time `json:"fired,omitempty"`
+}
+
+// Engine applies rules to new messages
+type Engine struct {
+	Rules  []*Rule
+	Client Client
+	State  *State
+	// DryRun logs what the actions would do without doing it. Rate limits
+	// are checked against State but not recorded in it.
+	DryRun bool
+	// Location is where hours and days are evaluated (local time if nil)
+	Location *time.Location
+	// HTTPClient sends webhooks (a client with a 10s timeout if nil)
+	HTTPClient *http.Client
+	// Log receives a JSON line per action or skipped match (nil for none)
+	Log io.Writer
+	// Now returns the current time (time.Now if nil)
+	Now func() time.Time
+
+	// sent holds the IDs of messages the rules sent, which are never acted
+	// on even if the network doesn't flag them as ours
+	sent map[string]bool
+}
+
+// logEntry is one line of the log
+type logEntry struct {
+	Time      string `json:"time"`
+	Rule      string `json:"rule"`
+	ChatID    string `json:"chat_id"`
+	MessageID string `json:"message_id"`
+	Action    string `json:"action,omitempty"`
+	Status    string `json:"status"`
+	// Detail is the text sent, the reaction, or the forward or webhook target
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Handle runs the actions of every rule matching each event, in rule order
+func (e *Engine) Handle(events []watch.Event) {
+	if e.State.Fired == nil {
+		e.State.Fired = make(map[string][]time.Time)
+	}
+	if e.sent == nil {
+		e.sent = make(map[string]bool)
+	}
+	loc := e.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	for _, ev := range events {
+		if e.sent[ev.Message.ID] {
+			continue
+		}
+		for _, r := range e.Rules {
+			now := e.now()
+			if !r.Matches(ev.Chat, ev.Message, now, loc) {
+				continue
+			}
+			if !e.allow(r, ev, now) {
+				e.log(logEntry{Rule: r.Name, ChatID: ev.Chat.ID, MessageID: ev.Message.ID, Status: StatusRateLimited})
+				continue
+			}
+			data := TemplateData{Rule: r.Name, Chat: ev.Chat, Message: ev.Message}
+			for i := range r.Actions {
+				e.run(&r.Actions[i], data)
+			}
+		}
+	}
+}
+
+// allow records that the rule acts now, unless its rate limit is used up.
+// In a dry run the limit is only checked.
+func (e *Engine) allow(r *Rule, ev watch.Event, now time.Time) bool {
+	rl := r.RateLimit
+	if rl == nil {
+		return true
+	}
+	key := r.Name
+	switch rl.Scope {
+	case ScopeChat:
+		key += "|" + ev.Chat.ID
+	case ScopeSender:
+		sender := ev.Message.SenderID
+		if sender == "" {
+			sender = ev.Message.Sender
+		}
+		key += "|" + sender
+	}
+
+	var recent []time.Time
+	for _, t := range e.State.Fired[key] {
+		if now.Sub(t) < rl.window {
+			recent = append(recent, t)
+		}
+	}
+	if e.DryRun {
+		return len(recent) < rl.Max
+	}
+	if len(recent) >= rl.Max {
+		e.State.Fired[key] = recent
+		return false
+	}
+	e.State.Fired[key] = append(recent, now)
+	return true
+}
+
+// run performs one action and logs the outcome
+func (e *Engine) run(a *Action, data TemplateData) {
+	entry := logEntry{Rule: data.Rule, ChatID: data.Chat.ID, MessageID: data.Message.ID, Action: a.Name()}
+
+	var text string
+	if a.tmpl != nil {
+		var sb strings.Builder
+		if err := a.tmpl.Execute(&sb, data); err != nil {
+			entry.Status, entry.Error = StatusError, fmt.Sprintf("failed to render template: %v", err)
+			e.log(entry)
+			return
+		}
+		text = sb.String()
+	}
+
+	switch {
+	case a.Reply != "":
+		entry.Detail = text
+	case a.React != "":
+		entry.Detail = a.React
+	case a.Forward != "":
+		entry.Detail = a.Forward
+	case a.Webhook != "":
+		entry.Detail = a.Webhook
+	}
+	if e.DryRun {
+		entry.Status = StatusDryRun
+		e.log(entry)
+		return
+	}
+
+	var err error
+	switch {
+	case a.Reply != "":
+		err = e.send(data.Chat.ID, text)
+	case a.React != "":
+		err = e.Client.AddReaction(data.Chat.ID, data.Message.ID, a.React)
+	case a.MarkRead:
+		err = e.Client.MarkChatRead(data.Chat.ID)
+	case a.Archive:
+		err = e.Client.ArchiveChat(data.Chat.ID, true)
+	case a.Forward != "":
+		err = e.send(a.Forward, text)
+	case a.Webhook != "":
+		err = e.postWebhook(a.Webhook, data)
+	}
+
+	entry.Status = StatusOK
+	if err != nil {
+		entry.Status, entry.Error = StatusError, err.Error()
+	}
+	e.log(entry)
+}
+
+// send sends a message and remembers it, so the rules never answer it
+func (e *Engine) send(chatID, text string) error {
+	id, err := e.Client.SendMessage(chatID, text)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		e.sent[id] = true
+	}
+	return nil
+}
+
+// webhookPayload is the body POSTed to webhooks
+type webhookPayload struct {
+	Rule    string      `json:"rule"`
+	Chat    api.Chat    `json:"chat"`
+	Message api.Message `json:"message"`
+}
+
+func (e *Engine) postWebhook(url string, data TemplateData) error {
+	body, err := json.Marshal(webhookPayload{Rule: data.Rule, Chat: data.Chat, Message: data.Message})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook: %w", err)
+	}
+	client := e.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to call webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (e *Engine) log(entry logEntry) {
+	if e.Log == nil {
+		return
+	}
+	entry.Time = e.now().UTC().Format(time.RFC3339)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	e.Log.Write(append(data, '\n'))
+}
+
+func (e *Engine) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,198 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClient records the actions taken
+type fakeClient struct {
+	calls []string
+	sent  int
+}
+
+func (c *fakeClient) SendMessage(chatID, text string) (string, error) {
+	c.calls = append(c.calls, "send "+chatID+": "+text)
+	c.sent++
+	return fmt.Sprintf("sent%d", c.sent), nil
+}
+
+func (c *fakeClient) AddReaction(chatID, messageID, reactionKey string) error {
+	c.calls = append(c.calls, "react "+messageID+" "+reactionKey)
+	return nil
+}
+
+func (c *fakeClient) MarkChatRead(chatID string) error {
+	c.calls = append(c.calls, "read "+chatID)
+	return errors.New("not found")
+}
+
+func (c *fakeClient) ArchiveChat(chatID string, archived bool) error {
+	c.calls = append(c.calls, "archive "+chatID)
+	return nil
+}
+
+func newEngine(t *testing.T, content string) (*Engine, *fakeClient, *strings.Builder) {
+	f, err := Load(writeRules(t, content))
+	require.NoError(t, err)
+	client := &fakeClient{}
+	var log strings.Builder
+	now := time.Date(2024, 3, 4, 20, 0, 0, 0, time.UTC)
+	return &Engine{
+		Rules:    f.Rules,
+		Client:   client,
+		State:    &State{},
+		Location: time.UTC,
+		Log:      &log,
+		Now:      func() time.Time { return now },
+	}, client, &log
+}
+
+func event(chatID, id, sender, text string) watch.Event {
+	return watch.Event{
+		Chat:    api.Chat{ID: chatID, Title: "Chat " + chatID, Network: "slack"},
+		Message: api.Message{ID: id, ChatID: chatID, Sender: sender, SenderID: "@" + sender, Text: text},
+	}
+}
+
+func logLines(t *testing.T, log *strings.Builder) []map[string]string {
+	var lines []map[string]string
+	for _, line := range strings.Split(strings.TrimSpace(log.String()), "\n") {
+		var entry map[string]string
+		require.NoError(t, json.Unmarshal([]byte(line), &entry), line)
+		lines = append(lines, entry)
+	}
+	return lines
+}
+
+func TestHandle(t *testing.T) {
+	e, client, log := newEngine(t, `rules:
+  - name: ooo
+    actions:
+      - reply: "Hi {{.Message.Sender}}, back Monday."
+      - react: "👋"
+      - mark_read: true
+      - archive: true
+      - forward: "!me"
+        forward_text: "[{{.Rule}}] {{.Chat.Title}}: {{.Message.Text}}"
+`)
+
+	own := event("!a", "m0", "Me", "hello")
+	own.Message.IsSender = true
+	e.Handle([]watch.Event{own, event("!a", "m1", "Ann", "anyone there?")})
+
+	assert.Equal(t, []string{
+		"send !a: Hi Ann, back Monday.",
+		"react m1 👋",
+		"read !a",
+		"archive !a",
+		"send !me: [ooo] Chat !a: anyone there?",
+	}, client.calls)
+
+	lines := logLines(t, log)
+	require.Len(t, lines, 5)
+	assert.Equal(t, map[string]string{
+		"time": "2024-03-04T20:00:00Z", "rule": "ooo", "chat_id": "!a", "message_id": "m1",
+		"action": "reply", "status": "ok", "detail": "Hi Ann, back Monday.",
+	}, lines[0])
+	assert.Equal(t, "error", lines[2]["status"])
+	assert.Equal(t, "not found", lines[2]["error"])
+
+	// Our replies never trigger the rules, even if not flagged as ours
+	client.calls = nil
+	e.Handle([]watch.Event{event("!a", "sent1", "Me", "Hi Ann, back Monday.")})
+	assert.Empty(t, client.calls)
+}
+
+func TestHandle_DryRun(t *testing.T) {
+	e, client, log := newEngine(t, `rules:
+  - name: ooo
+    actions:
+      - reply: "Away, {{.Message.Sender}}"
+      - archive: true
+`)
+	e.DryRun = true
+	e.Handle([]watch.Event{event("!a", "m1", "Ann", "hi")})
+
+	assert.Empty(t, client.calls)
+	lines := logLines(t, log)
+	require.Len(t, lines, 2)
+	assert.Equal(t, "dry_run", lines[0]["status"])
+	assert.Equal(t, "Away, Ann", lines[0]["detail"])
+	assert.Equal(t, "archive", lines[1]["action"])
+}
+
+func TestHandle_RateLimit(t *testing.T) {
+	e, client, log := newEngine(t, `rules:
+  - name: ooo
+    actions: [{archive: true}]
+    rate_limit: {max: 1, per: 1h, scope: chat}
+`)
+	now := time.Date(2024, 3, 4, 20, 0, 0, 0, time.UTC)
+	e.Now = func() time.Time { return now }
+
+	e.Handle([]watch.Event{
+		event("!a", "m1", "Ann", "hi"),
+		event("!a", "m2", "Ann", "hello?"),
+		event("!b", "m3", "Bob", "hi"),
+	})
+	assert.Equal(t, []string{"archive !a", "archive !b"}, client.calls)
+	assert.Equal(t, "rate_limited", logLines(t, log)[1]["status"])
+
+	// The window slides: an hour later the chat may be answered again
+	now = now.Add(time.Hour)
+	e.Handle([]watch.Event{event("!a", "m4", "Ann", "still there?")})
+	assert.Equal(t, []string{"archive !a", "archive !b", "archive !a"}, client.calls)
+	assert.Len(t, e.State.Fired["ooo|!a"], 1)
+}
+
+func TestHandle_DryRunLeavesRateLimits(t *testing.T) {
+	e, _, log := newEngine(t, `rules:
+  - name: ooo
+    actions: [{archive: true}]
+    rate_limit: {max: 1, per: 1h, scope: chat}
+`)
+	e.DryRun = true
+	e.Handle([]watch.Event{event("!a", "m1", "Ann", "hi")})
+	e.Handle([]watch.Event{event("!a", "m2", "Ann", "hello?")})
+
+	assert.Empty(t, e.State.Fired)
+	for _, line := range logLines(t, log) {
+		assert.Equal(t, "dry_run", line["status"])
+	}
+}
+
+func TestHandle_Webhook(t *testing.T) {
+	var got webhookPayload
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &got))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	e, _, log := newEngine(t, "rules:\n  - name: hook\n    actions: [{webhook: '"+server.URL+"'}]\n")
+	e.Handle([]watch.Event{event("!a", "m1", "Ann", "site down")})
+	assert.Equal(t, "hook", got.Rule)
+	assert.Equal(t, "!a", got.Chat.ID)
+	assert.Equal(t, "site down", got.Message.Text)
+	assert.Equal(t, "ok", logLines(t, log)[0]["status"])
+
+	status = http.StatusBadGateway
+	e.Handle([]watch.Event{event("!a", "m2", "Ann", "still down")})
+	assert.Equal(t, "webhook returned 502 Bad Gateway", logLines(t, log)[1]["error"])
+}
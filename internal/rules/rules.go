@@ -0,0 +1,335 @@
+// Package rules reacts to incoming messages: each rule matches on chat,
+// network, sender, text and time of day, and runs actions such as replying,
+// reacting, archiving, forwarding or calling a webhook.
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/nerveband/beeper-api-cli/internal/output"
+	"go.yaml.in/yaml/v3"
+)
+
+// Rate limit scopes: a rule's limit counts its actions overall, per chat or
+// per sender
+const (
+	ScopeRule   = "rule"
+	ScopeChat   = "chat"
+	ScopeSender = "sender"
+)
+
+// File is a rules file
+type File struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Rule runs its actions on every incoming message it matches. Empty
+// conditions match anything; our own messages never match.
+type Rule struct {
+	// Name identifies the rule in logs and rate limits
+	Name string `yaml:"name"`
+	// Chats limits the rule to these chats, by ID (the command also accepts
+	// aliases and titles)
+	Chats []string `yaml:"chats,omitempty"`
+	// Networks limits the rule to chats on these networks
+	Networks []string `yaml:"networks,omitempty"`
+	// Senders limits the rule to these sender names or IDs
+	Senders []string `yaml:"senders,omitempty"`
+	// Text is a regular expression the message text must match
+	Text string `yaml:"text,omitempty"`
+	// Hours is a time-of-day window such as "18:00-09:00", which may wrap
+	// past midnight
+	Hours string `yaml:"hours,omitempty"`
+	// Days limits the rule to these weekdays (mon, tue, ...)
+	Days      []string   `yaml:"days,omitempty"`
+	Actions   []Action   `yaml:"actions"`
+	RateLimit *RateLimit `yaml:"rate_limit,omitempty"`
+
+	text     *regexp.Regexp
+	from, to int // Hours as minutes after midnight
+	days     []time.Weekday
+}
+
+// Action is one thing a rule does; exactly one field is set
+type Action struct {
+	// Reply sends a message to the chat, a template over TemplateData
+	Reply string `yaml:"reply,omitempty"`
+	// React reacts to the message with an emoji or shortcode
+	React string `yaml:"react,omitempty"`
+	// MarkRead marks the chat read
+	MarkRead bool `yaml:"mark_read,omitempty"`
+	// Archive archives the chat
+	Archive bool `yaml:"archive,omitempty"`
+	// Forward sends the message to another chat, by ID (the command also
+	// accepts aliases and titles)
+	Forward string `yaml:"forward,omitempty"`
+	// ForwardText is the template of forwarded messages (default
+	// DefaultForwardText)
+	ForwardText string `yaml:"forward_text,omitempty"`
+	// Webhook POSTs the rule, chat and message as JSON to this URL
+	Webhook string `yaml:"webhook,omitempty"`
+
+	tmpl *template.Template
+}
+
+// RateLimit caps how often a rule acts
+type RateLimit struct {
+	// Max is the number of times the rule may act within Per
+	Max int    `yaml:"max"`
+	Per string `yaml:"per"`
+	// Scope is what the limit counts separately: rule (default), chat or
+	// sender
+	Scope string `yaml:"scope,omitempty"`
+
+	window time.Duration
+}
+
+// DefaultForwardText is the template of forwarded messages
+const DefaultForwardText = "{{.Message.Sender}} in {{.Chat.Title}}: {{.Message.Text}}"
+
+// TemplateData is what reply and forward templates are executed with
+type TemplateData struct {
+	Rule    string
+	Chat    api.Chat
+	Message api.Message
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Load reads and validates a rules file
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var f File
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// Validate checks every rule and compiles its patterns and templates
+func (f *File) Validate() error {
+	if len(f.Rules) == 0 {
+		return fmt.Errorf("no rules defined")
+	}
+
+	names := make(map[string]bool)
+	for i, r := range f.Rules {
+		if r.Name == "" {
+			return fmt.Errorf("rule %d: name is required", i+1)
+		}
+		if names[r.Name] {
+			return fmt.Errorf("rule %s: duplicate name", r.Name)
+		}
+		names[r.Name] = true
+		if err := r.validate(); err != nil {
+			return fmt.Errorf("rule %s: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) validate() error {
+	if r.Text != "" {
+		text, err := regexp.Compile(r.Text)
+		if err != nil {
+			return fmt.Errorf("invalid text pattern: %w", err)
+		}
+		r.text = text
+	}
+
+	if r.Hours != "" {
+		from, to, ok := strings.Cut(r.Hours, "-")
+		var err error
+		if ok {
+			if r.from, err = parseClock(from); err == nil {
+				r.to, err = parseClock(to)
+			}
+		}
+		if !ok || err != nil || r.from == r.to {
+			return fmt.Errorf("invalid hours %q (use HH:MM-HH:MM, e.g. 18:00-09:00)", r.Hours)
+		}
+	}
+
+	r.days = nil
+	for _, d := range r.Days {
+		day, ok := weekdays[strings.ToLower(d[:min(3, len(d))])]
+		if !ok {
+			return fmt.Errorf("invalid day %q (use mon, tue, ...)", d)
+		}
+		r.days = append(r.days, day)
+	}
+
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("at least one action is required")
+	}
+	for i := range r.Actions {
+		if err := r.Actions[i].validate(); err != nil {
+			return fmt.Errorf("action %d: %w", i+1, err)
+		}
+	}
+
+	if rl := r.RateLimit; rl != nil {
+		if rl.Max < 1 {
+			return fmt.Errorf("rate_limit: max must be positive")
+		}
+		window, err := time.ParseDuration(rl.Per)
+		if err != nil || window <= 0 {
+			return fmt.Errorf("rate_limit: invalid per %q (use a duration such as 1h or 24h)", rl.Per)
+		}
+		rl.window = window
+		if rl.Scope == "" {
+			rl.Scope = ScopeRule
+		}
+		if rl.Scope != ScopeRule && rl.Scope != ScopeChat && rl.Scope != ScopeSender {
+			return fmt.Errorf("rate_limit: invalid scope %q (use rule, chat or sender)", rl.Scope)
+		}
+	}
+	return nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (a *Action) validate() error {
+	set := 0
+	for _, ok := range []bool{a.Reply != "", a.React != "", a.MarkRead, a.Archive, a.Forward != "", a.Webhook != ""} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("set exactly one of reply, react, mark_read, archive, forward or webhook")
+	}
+	if a.ForwardText != "" && a.Forward == "" {
+		return fmt.Errorf("forward_text needs forward")
+	}
+
+	var err error
+	switch {
+	case a.Reply != "":
+		a.tmpl, err = output.ParseTemplate(a.Reply)
+	case a.Forward != "":
+		text := a.ForwardText
+		if text == "" {
+			text = DefaultForwardText
+		}
+		a.tmpl, err = output.ParseTemplate(text)
+	case a.Webhook != "":
+		u, perr := url.Parse(a.Webhook)
+		if perr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			err = fmt.Errorf("invalid webhook URL %q", a.Webhook)
+		}
+	}
+	return err
+}
+
+// Name returns the action's name as it appears in logs
+func (a *Action) Name() string {
+	switch {
+	case a.Reply != "":
+		return "reply"
+	case a.React != "":
+		return "react"
+	case a.MarkRead:
+		return "mark_read"
+	case a.Archive:
+		return "archive"
+	case a.Forward != "":
+		return "forward"
+	default:
+		return "webhook"
+	}
+}
+
+// Matches reports whether the rule applies to a message in chat. Hours and
+// days are checked against the message time (now if it has none) in loc.
+func (r *Rule) Matches(chat api.Chat, msg api.Message, now time.Time, loc *time.Location) bool {
+	if msg.IsSender {
+		return false
+	}
+	if len(r.Chats) > 0 && !slices.Contains(r.Chats, chat.ID) {
+		return false
+	}
+	if len(r.Networks) > 0 && !containsFold(r.Networks, chat.Network) {
+		return false
+	}
+	if len(r.Senders) > 0 && !containsFold(r.Senders, msg.Sender) && !containsFold(r.Senders, msg.SenderID) {
+		return false
+	}
+	if r.text != nil && !r.text.MatchString(msg.Text) {
+		return false
+	}
+
+	at := now
+	if t, ok := msg.Time(); ok {
+		at = t
+	}
+	at = at.In(loc)
+	if len(r.days) > 0 && !slices.Contains(r.days, at.Weekday()) {
+		return false
+	}
+	if r.Hours != "" {
+		minute := at.Hour()*60 + at.Minute()
+		if r.from < r.to {
+			return minute >= r.from && minute < r.to
+		}
+		return minute >= r.from || minute < r.to
+	}
+	return true
+}
+
+// ChatIDs returns every chat the rules are limited to, or nil if any rule
+// applies to all chats
+func (f *File) ChatIDs() []string {
+	var ids []string
+	for _, r := range f.Rules {
+		if len(r.Chats) == 0 {
+			return nil
+		}
+		for _, id := range r.Chats {
+			if !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func containsFold(values []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRules = `rules:
+  - name: out-of-office
+    networks: [slack]
+    hours: "18:00-09:00"
+    actions:
+      - reply: "Hi {{.Message.Sender}}, I'm away until 9am."
+      - mark_read: true
+    rate_limit:
+      max: 1
+      per: 24h
+      scope: chat
+  - name: page
+    chats: ["!ops:beeper.local"]
+    text: '(?i)site down'
+    actions:
+      - webhook: https://hooks.example.com/page
+`
+
+func writeRules(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	f, err := Load(writeRules(t, testRules))
+	require.NoError(t, err)
+	require.Len(t, f.Rules, 2)
+
+	ooo := f.Rules[0]
+	assert.Equal(t, 18*60, ooo.from)
+	assert.Equal(t, 9*60, ooo.to)
+	assert.Equal(t, 24*time.Hour, ooo.RateLimit.window)
+	assert.Equal(t, ScopeChat, ooo.RateLimit.Scope)
+	assert.Equal(t, "reply", ooo.Actions[0].Name())
+	assert.Equal(t, "mark_read", ooo.Actions[1].Name())
+	assert.Equal(t, "webhook", f.Rules[1].Actions[0].Name())
+
+	// Any rule without chats means every chat is watched
+	assert.Nil(t, f.ChatIDs())
+	f.Rules[0].Chats = []string{"!a", "!ops:beeper.local"}
+	assert.Equal(t, []string{"!a", "!ops:beeper.local"}, f.ChatIDs())
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"empty", "rules: []\n", "no rules defined"},
+		{"unknown field", "rules:\n  - name: a\n    colour: red\n", "field colour not found"},
+		{"no name", "rules:\n  - actions: [{archive: true}]\n", "rule 1: name is required"},
+		{"duplicate", "rules:\n  - name: a\n    actions: [{archive: true}]\n  - name: a\n    actions: [{archive: true}]\n", "rule a: duplicate name"},
+		{"no actions", "rules:\n  - name: a\n", "at least one action"},
+		{"two in one action", "rules:\n  - name: a\n    actions: [{archive: true, react: x}]\n", "action 1: set exactly one"},
+		{"bad pattern", "rules:\n  - name: a\n    text: '('\n    actions: [{archive: true}]\n", "invalid text pattern"},
+		{"bad hours", "rules:\n  - name: a\n    hours: 9-5\n    actions: [{archive: true}]\n", "invalid hours"},
+		{"bad day", "rules:\n  - name: a\n    days: [funday]\n    actions: [{archive: true}]\n", "invalid day"},
+		{"bad template", "rules:\n  - name: a\n    actions: [{reply: '{{.Oops'}]\n", "invalid template"},
+		{"bad webhook", "rules:\n  - name: a\n    actions: [{webhook: 'ftp://x'}]\n", "invalid webhook URL"},
+		{"bad rate limit", "rules:\n  - name: a\n    actions: [{archive: true}]\n    rate_limit: {max: 1, per: daily}\n", "rate_limit: invalid per"},
+		{"bad scope", "rules:\n  - name: a\n    actions: [{archive: true}]\n    rate_limit: {max: 1, per: 1h, scope: team}\n", "rate_limit: invalid scope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeRules(t, tt.content))
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	f, err := Load(writeRules(t, testRules))
+	require.NoError(t, err)
+	ooo, page := f.Rules[0], f.Rules[1]
+
+	slack := api.Chat{ID: "!acme", Network: "Slack"}
+	ops := api.Chat{ID: "!ops:beeper.local", Network: "matrix"}
+	at := func(ts string) api.Message {
+		return api.Message{ID: "m", Sender: "Ann", Text: "The site down again?", Timestamp: ts}
+	}
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+
+	assert.True(t, ooo.Matches(slack, at("2024-03-04T20:00:00Z"), now, time.UTC))
+	assert.True(t, ooo.Matches(slack, at("2024-03-05T08:59:00Z"), now, time.UTC), "the window wraps past midnight")
+	assert.False(t, ooo.Matches(slack, at("2024-03-05T09:00:00Z"), now, time.UTC))
+	assert.False(t, ooo.Matches(ops, at("2024-03-04T20:00:00Z"), now, time.UTC), "wrong network")
+	// 12:00 UTC is 21:00 in Tokyo
+	assert.True(t, ooo.Matches(slack, at("2024-03-04T12:00:00Z"), now, time.FixedZone("JST", 9*60*60)))
+	// Without a timestamp the time of the check counts
+	assert.False(t, ooo.Matches(slack, at(""), now, time.UTC))
+
+	assert.True(t, page.Matches(ops, at(""), now, time.UTC))
+	assert.False(t, page.Matches(slack, at(""), now, time.UTC), "wrong chat")
+	own := at("")
+	own.IsSender = true
+	assert.False(t, page.Matches(ops, own, now, time.UTC), "our own messages never match")
+
+	page.Senders = []string{"@bob:beeper.com"}
+	msg := at("")
+	assert.False(t, page.Matches(ops, msg, now, time.UTC))
+	msg.SenderID = "@BOB:beeper.com"
+	assert.True(t, page.Matches(ops, msg, now, time.UTC))
+
+	page.days = []time.Weekday{time.Saturday, time.Sunday}
+	assert.False(t, page.Matches(ops, msg, now, time.UTC), "March 4 2024 is a Monday")
+}
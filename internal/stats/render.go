@@ -0,0 +1,213 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// histogramWidth is the length of the longest bar
+const histogramWidth = 40
+
+// weekdays labels Report.ByWeekday
+var weekdays = [7]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+
+// WriteTable writes the report as a summary followed by tables of the top
+// chats and senders and of activity by hour and weekday. top limits the chat
+// and sender tables (0 shows every row).
+func WriteTable(w io.Writer, r Report, top int) error {
+	var sb strings.Builder
+	writeSummary(&sb, r)
+
+	sb.WriteString("\n")
+	chats := [][]string{{"CHAT", "NETWORK", "MESSAGES", "SENT", "RECEIVED", "YOUR REPLY", "THEIR REPLY"}}
+	for _, c := range limit(r.Chats, top) {
+		chats = append(chats, []string{
+			runewidth.Truncate(chatLabel(c), 40, "…"), c.Network, strconv.Itoa(c.Messages), strconv.Itoa(c.Sent), strconv.Itoa(c.Received),
+			formatResponse(c.ResponseTimes.Ours), formatResponse(c.ResponseTimes.Theirs),
+		})
+	}
+	writeTable(&sb, chats)
+
+	sb.WriteString("\n")
+	senders := [][]string{{"SENDER", "MESSAGES"}}
+	for _, s := range limit(r.Senders, top) {
+		senders = append(senders, []string{s.Name, strconv.Itoa(s.Messages)})
+	}
+	writeTable(&sb, senders)
+
+	sb.WriteString("\n")
+	hours := [][]string{{"HOUR", "MESSAGES"}}
+	for h, n := range r.ByHour {
+		hours = append(hours, []string{fmt.Sprintf("%02d", h), strconv.Itoa(n)})
+	}
+	writeTable(&sb, hours)
+
+	sb.WriteString("\n")
+	days := [][]string{{"WEEKDAY", "MESSAGES"}}
+	for d, n := range r.ByWeekday {
+		days = append(days, []string{weekdays[d], strconv.Itoa(n)})
+	}
+	writeTable(&sb, days)
+
+	writeErrors(&sb, r)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// WriteHistogram writes the report as a summary followed by bar charts of
+// activity by hour and weekday and of the busiest chats. top limits the
+// chats charted (0 charts every chat).
+func WriteHistogram(w io.Writer, r Report, top int) error {
+	var sb strings.Builder
+	writeSummary(&sb, r)
+
+	sb.WriteString("\nMessages by hour\n")
+	var labels []string
+	var counts []int
+	for h, n := range r.ByHour {
+		labels = append(labels, fmt.Sprintf("%02d", h))
+		counts = append(counts, n)
+	}
+	writeBars(&sb, labels, counts)
+
+	sb.WriteString("\nMessages by weekday\n")
+	writeBars(&sb, weekdays[:], r.ByWeekday[:])
+
+	if len(r.Chats) > 0 {
+		sb.WriteString("\nBusiest chats\n")
+		labels, counts = nil, nil
+		for _, c := range limit(r.Chats, top) {
+			labels = append(labels, runewidth.Truncate(chatLabel(c), 30, "…"))
+			counts = append(counts, c.Messages)
+		}
+		writeBars(&sb, labels, counts)
+	}
+
+	writeErrors(&sb, r)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeSummary(sb *strings.Builder, r Report) {
+	period := "all time"
+	if r.Since != "" {
+		period = "since " + r.Since
+	}
+	sb.WriteString(fmt.Sprintf("%d messages in %d chats, %s (%s)\n", r.Messages, len(r.Chats), period, r.TimeZone))
+	sb.WriteString(fmt.Sprintf("Sent %d, received %d\n", r.Sent, r.Received))
+	sb.WriteString(fmt.Sprintf("Median reply: yours %s, theirs %s\n",
+		describeResponse(r.ResponseTimes.Ours), describeResponse(r.ResponseTimes.Theirs)))
+}
+
+func writeErrors(sb *strings.Builder, r Report) {
+	if len(r.Errors) == 0 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("\nNot counted (%d chats failed):\n", len(r.Errors)))
+	for _, e := range r.Errors {
+		title := e.Title
+		if title == "" {
+			title = e.ChatID
+		}
+		sb.WriteString(fmt.Sprintf("  %s: %s\n", title, e.Error))
+	}
+}
+
+// writeTable writes rows as left-aligned columns, the first row a header
+func writeTable(sb *strings.Builder, rows [][]string) {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], runewidth.StringWidth(cell))
+		}
+	}
+	for _, row := range rows {
+		var line strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				line.WriteString("  ")
+			}
+			line.WriteString(runewidth.FillRight(cell, widths[i]))
+		}
+		sb.WriteString(strings.TrimRight(line.String(), " ") + "\n")
+	}
+}
+
+// writeBars draws one bar per label, scaled so the largest count spans
+// histogramWidth
+func writeBars(sb *strings.Builder, labels []string, counts []int) {
+	width, peak := 0, 0
+	for i, label := range labels {
+		width = max(width, runewidth.StringWidth(label))
+		peak = max(peak, counts[i])
+	}
+	for i, label := range labels {
+		bar := 0
+		if peak > 0 {
+			bar = (counts[i]*histogramWidth + peak - 1) / peak
+		}
+		sb.WriteString(fmt.Sprintf("%s │%s %d\n", runewidth.FillRight(label, width), strings.Repeat("█", bar), counts[i]))
+	}
+}
+
+func chatLabel(c ChatStats) string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return c.ID
+}
+
+func limit[T any](items []T, n int) []T {
+	if n > 0 && len(items) > n {
+		return items[:n]
+	}
+	return items
+}
+
+// formatResponse is a median reply time for a table cell, "-" without replies
+func formatResponse(r ResponseTime) string {
+	if r.Replies == 0 {
+		return "-"
+	}
+	return formatDuration(r.Median())
+}
+
+func describeResponse(r ResponseTime) string {
+	if r.Replies == 0 {
+		return "n/a"
+	}
+	replies := "replies"
+	if r.Replies == 1 {
+		replies = "reply"
+	}
+	return fmt.Sprintf("%s (%d %s)", formatDuration(r.Median()), r.Replies, replies)
+}
+
+// formatDuration renders a duration in its two largest units, e.g. "45s",
+// "12m", "3h5m" or "2d4h"
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		h, m := int(d.Hours()), int(d.Minutes())%60
+		if m == 0 {
+			return fmt.Sprintf("%dh", h)
+		}
+		return fmt.Sprintf("%dh%dm", h, m)
+	default:
+		days, h := int(d.Hours())/24, int(d.Hours())%24
+		if h == 0 {
+			return fmt.Sprintf("%dd", days)
+		}
+		return fmt.Sprintf("%dd%dh", days, h)
+	}
+}
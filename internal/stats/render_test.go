@@ -0,0 +1,52 @@
+package stats
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTable(t *testing.T) {
+	r := Compute(testThreads(), time.UTC)
+	r.Since = "2024-03-01T00:00:00Z"
+
+	var sb strings.Builder
+	require.NoError(t, WriteTable(&sb, r, 1))
+	out := sb.String()
+
+	assert.True(t, strings.HasPrefix(out, "7 messages in 2 chats, since 2024-03-01T00:00:00Z (UTC)\n"))
+	assert.Contains(t, out, "Median reply: yours 25m (2 replies), theirs 12h40m (2 replies)\n")
+	assert.Contains(t, out, "CHAT  NETWORK  MESSAGES  SENT  RECEIVED  YOUR REPLY  THEIR REPLY\nAcme  slack    5         2     3         25m         1d\n\n")
+	// --top limits chats and senders, not the activity tables
+	assert.NotContains(t, out, "Bob  ")
+	assert.Contains(t, out, "SENDER  MESSAGES\nAnn     3\n\n")
+	assert.Contains(t, out, "09    5\n")
+	assert.Contains(t, out, "Mon      5\n")
+}
+
+func TestWriteHistogram(t *testing.T) {
+	r := Compute(testThreads(), time.UTC)
+	r.Errors = []ChatError{{ChatID: "!x", Error: "timed out"}}
+
+	var sb strings.Builder
+	require.NoError(t, WriteHistogram(&sb, r, 0))
+	out := sb.String()
+
+	assert.Contains(t, out, "7 messages in 2 chats, all time (UTC)")
+	assert.Contains(t, out, "09 │"+strings.Repeat("█", 40)+" 5\n")
+	assert.Contains(t, out, "10 │"+strings.Repeat("█", 16)+" 2\n")
+	assert.Contains(t, out, "00 │ 0\n")
+	assert.Contains(t, out, "Busiest chats\nAcme │"+strings.Repeat("█", 40)+" 5\nBob  │"+strings.Repeat("█", 16)+" 2\n")
+	assert.Contains(t, out, "Not counted (1 chats failed):\n  !x: timed out\n")
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "45s", formatDuration(45*time.Second))
+	assert.Equal(t, "12m", formatDuration(12*time.Minute+10*time.Second))
+	assert.Equal(t, "3h", formatDuration(3*time.Hour))
+	assert.Equal(t, "3h5m", formatDuration(3*time.Hour+5*time.Minute))
+	assert.Equal(t, "2d4h", formatDuration(52*time.Hour))
+}
@@ -0,0 +1,257 @@
+// Package stats computes messaging analytics: message counts per chat and
+// sender, activity by hour and weekday, and how quickly each side replies.
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// DefaultPageSize is the number of messages requested per page
+const DefaultPageSize = 100
+
+// SelfName is the sender name all of the account owner's messages are
+// counted under, whichever network they were sent on
+const SelfName = "You"
+
+// Source is the part of the API client statistics are fetched from
+type Source interface {
+	ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error)
+}
+
+// Thread is a chat with the messages counted for it
+type Thread struct {
+	Chat     api.Chat
+	Messages []api.Message
+}
+
+// Report holds the statistics over a set of chats
+type Report struct {
+	// Since is the start of the period covered, empty for all history
+	Since    string `json:"since,omitempty"`
+	Until    string `json:"until"`
+	TimeZone string `json:"timeZone"`
+	Messages int    `json:"messages"`
+	Sent     int    `json:"sent"`
+	Received int    `json:"received"`
+	// ResponseTimes are medians over every chat
+	ResponseTimes ResponseTimes `json:"responseTimes"`
+	// Chats are ordered busiest first
+	Chats []ChatStats `json:"chats"`
+	// Senders are ordered by message count, highest first
+	Senders []SenderStats `json:"senders"`
+	// ByHour counts messages per hour of the day, 0 to 23
+	ByHour [24]int `json:"byHour"`
+	// ByWeekday counts messages per day of the week, Monday first
+	ByWeekday [7]int `json:"byWeekday"`
+	// Errors lists chats whose messages could not be fetched
+	Errors []ChatError `json:"errors,omitempty"`
+}
+
+// ChatStats holds the statistics of one chat
+type ChatStats struct {
+	ID            string        `json:"id"`
+	Title         string        `json:"title"`
+	Network       string        `json:"network"`
+	Messages      int           `json:"messages"`
+	Sent          int           `json:"sent"`
+	Received      int           `json:"received"`
+	ResponseTimes ResponseTimes `json:"responseTimes"`
+}
+
+// SenderStats counts one sender's messages
+type SenderStats struct {
+	Name     string `json:"name"`
+	Messages int    `json:"messages"`
+	IsSelf   bool   `json:"isSelf,omitempty"`
+}
+
+// ResponseTimes are the median reply times in each direction. A reply is
+// the first message after the other side's turn, timed from the first
+// message of that turn.
+type ResponseTimes struct {
+	// Ours is how long we took to answer others
+	Ours ResponseTime `json:"ours"`
+	// Theirs is how long others took to answer us
+	Theirs ResponseTime `json:"theirs"`
+}
+
+// ResponseTime is a median reply time and the number of replies it is over
+type ResponseTime struct {
+	MedianSeconds float64 `json:"medianSeconds"`
+	Replies       int     `json:"replies"`
+}
+
+// Median returns the median as a duration
+func (r ResponseTime) Median() time.Duration {
+	return time.Duration(r.MedianSeconds * float64(time.Second))
+}
+
+// ChatError records a chat that could not be fetched
+type ChatError struct {
+	ChatID string `json:"chatId"`
+	Title  string `json:"title"`
+	Error  string `json:"error"`
+}
+
+// Fetch returns a chat's messages sent at or after since, newest first,
+// paging back until it passes since (a zero since fetches all history)
+func Fetch(src Source, chatID string, since time.Time, pageSize int) ([]api.Message, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	var messages []api.Message
+	cursor := ""
+	for {
+		page, err := src.ListMessagesPage(chatID, cursor, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		reached := false
+		for _, msg := range page.Items {
+			if t, ok := msg.Time(); ok && !since.IsZero() && t.Before(since) {
+				reached = true
+				continue
+			}
+			messages = append(messages, msg)
+		}
+		next := page.NextCursor()
+		if reached || !page.HasMore || next == "" || next == cursor {
+			return messages, nil
+		}
+		cursor = next
+	}
+}
+
+// Compute builds a report from threads. Hours and weekdays are counted in
+// loc (nil means local time); messages without a timestamp are counted but
+// left out of the activity and response times.
+func Compute(threads []Thread, loc *time.Location) Report {
+	if loc == nil {
+		loc = time.Local
+	}
+	zone := loc.String()
+	if loc == time.Local {
+		zone, _ = time.Now().Zone()
+	}
+	report := Report{
+		TimeZone: zone,
+		Chats:    []ChatStats{},
+		Senders:  []SenderStats{},
+	}
+
+	senders := make(map[string]*SenderStats)
+	var ours, theirs []time.Duration
+	for _, thread := range threads {
+		chat := ChatStats{ID: thread.Chat.ID, Title: thread.Chat.Title, Network: thread.Chat.Network}
+		for _, msg := range thread.Messages {
+			chat.Messages++
+			if msg.IsSender {
+				chat.Sent++
+			} else {
+				chat.Received++
+			}
+
+			name := msg.Sender
+			if msg.IsSender {
+				name = SelfName
+			} else if name == "" {
+				name = msg.SenderID
+			}
+			sender, ok := senders[name]
+			if !ok {
+				sender = &SenderStats{Name: name, IsSelf: msg.IsSender}
+				senders[name] = sender
+			}
+			sender.Messages++
+
+			if t, ok := msg.Time(); ok {
+				t = t.In(loc)
+				report.ByHour[t.Hour()]++
+				report.ByWeekday[(int(t.Weekday())+6)%7]++
+			}
+		}
+		if chat.Messages == 0 {
+			continue
+		}
+
+		chatOurs, chatTheirs := replyTimes(thread.Messages)
+		chat.ResponseTimes = ResponseTimes{Ours: median(chatOurs), Theirs: median(chatTheirs)}
+		ours = append(ours, chatOurs...)
+		theirs = append(theirs, chatTheirs...)
+
+		report.Messages += chat.Messages
+		report.Sent += chat.Sent
+		report.Received += chat.Received
+		report.Chats = append(report.Chats, chat)
+	}
+	report.ResponseTimes = ResponseTimes{Ours: median(ours), Theirs: median(theirs)}
+
+	sort.SliceStable(report.Chats, func(i, j int) bool {
+		return report.Chats[i].Messages > report.Chats[j].Messages
+	})
+	for _, sender := range senders {
+		report.Senders = append(report.Senders, *sender)
+	}
+	sort.Slice(report.Senders, func(i, j int) bool {
+		a, b := report.Senders[i], report.Senders[j]
+		if a.Messages != b.Messages {
+			return a.Messages > b.Messages
+		}
+		return a.Name < b.Name
+	})
+	return report
+}
+
+// replyTimes walks a chat's messages in time order and returns how long each
+// side took to reply to the other's turn
+func replyTimes(messages []api.Message) (ours, theirs []time.Duration) {
+	type timed struct {
+		at   time.Time
+		self bool
+	}
+	var seq []timed
+	for _, msg := range messages {
+		if t, ok := msg.Time(); ok {
+			seq = append(seq, timed{t, msg.IsSender})
+		}
+	}
+	sort.SliceStable(seq, func(i, j int) bool { return seq[i].at.Before(seq[j].at) })
+
+	for i := 1; i < len(seq); i++ {
+		if seq[i].self == seq[i-1].self {
+			continue
+		}
+		// Walk back to the first message of the turn being answered
+		start := i - 1
+		for start > 0 && seq[start-1].self == seq[i-1].self {
+			start--
+		}
+		wait := seq[i].at.Sub(seq[start].at)
+		if seq[i].self {
+			ours = append(ours, wait)
+		} else {
+			theirs = append(theirs, wait)
+		}
+	}
+	return ours, theirs
+}
+
+// median returns the median of durations and how many there were
+func median(durations []time.Duration) ResponseTime {
+	n := len(durations)
+	if n == 0 {
+		return ResponseTime{}
+	}
+	sorted := make([]time.Duration, n)
+	copy(sorted, durations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := sorted[n/2]
+	if n%2 == 0 {
+		mid = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	return ResponseTime{MedianSeconds: mid.Seconds(), Replies: n}
+}
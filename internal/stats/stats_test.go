@@ -0,0 +1,136 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func msg(id, sender string, self bool, ts string) api.Message {
+	return api.Message{ID: id, Sender: sender, IsSender: self, Timestamp: ts, SortKey: id}
+}
+
+func testThreads() []Thread {
+	return []Thread{
+		{
+			Chat: api.Chat{ID: "!acme", Title: "Acme", Network: "slack"},
+			Messages: []api.Message{
+				// Newest first, as the API returns them
+				msg("5", "Me", true, "2024-03-05T10:30:00Z"),
+				msg("4", "Ann", false, "2024-03-05T10:00:00Z"),
+				msg("3", "Me", true, "2024-03-04T09:20:00Z"),
+				msg("2", "Ann", false, "2024-03-04T09:05:00Z"),
+				msg("1", "Ann", false, "2024-03-04T09:00:00Z"),
+			},
+		},
+		{
+			Chat: api.Chat{ID: "!bob", Title: "Bob", Network: "whatsapp"},
+			Messages: []api.Message{
+				msg("b2", "Bob", false, "2024-03-04T09:50:00Z"),
+				msg("b1", "Me Too", true, "2024-03-04T09:10:00Z"),
+			},
+		},
+		{Chat: api.Chat{ID: "!quiet", Title: "Quiet"}},
+	}
+}
+
+func TestCompute(t *testing.T) {
+	r := Compute(testThreads(), time.UTC)
+
+	assert.Equal(t, 7, r.Messages)
+	assert.Equal(t, 3, r.Sent)
+	assert.Equal(t, 4, r.Received)
+	assert.Equal(t, "UTC", r.TimeZone)
+
+	// Chats without messages are left out; busiest first
+	require.Len(t, r.Chats, 2)
+	assert.Equal(t, "Acme", r.Chats[0].Title)
+	assert.Equal(t, 5, r.Chats[0].Messages)
+	assert.Equal(t, 2, r.Chats[0].Sent)
+
+	// Our own messages are counted as one sender across networks
+	assert.Equal(t, []SenderStats{
+		{Name: "Ann", Messages: 3},
+		{Name: "You", Messages: 3, IsSelf: true},
+		{Name: "Bob", Messages: 1},
+	}, r.Senders)
+
+	assert.Equal(t, 5, r.ByHour[9])
+	assert.Equal(t, 2, r.ByHour[10])
+	assert.Equal(t, 5, r.ByWeekday[0]) // Monday, March 4
+	assert.Equal(t, 2, r.ByWeekday[1])
+}
+
+func TestCompute_ResponseTimes(t *testing.T) {
+	r := Compute(testThreads(), time.UTC)
+
+	// Acme: we answered Ann's turn starting 09:00 at 09:20, and 10:00 at 10:30
+	assert.Equal(t, ResponseTime{MedianSeconds: 25 * 60, Replies: 2}, r.Chats[0].ResponseTimes.Ours)
+	// Ann answered us the next day, 24h40m after 09:20
+	assert.Equal(t, ResponseTime{MedianSeconds: (24*60 + 40) * 60, Replies: 1}, r.Chats[0].ResponseTimes.Theirs)
+
+	// Overall: ours are 20m, 30m and none in Bob's chat; theirs 24h40m
+	// (Acme) and 40m (Bob)
+	assert.Equal(t, ResponseTime{MedianSeconds: 25 * 60, Replies: 2}, r.ResponseTimes.Ours)
+	assert.Equal(t, ResponseTime{MedianSeconds: (12*60 + 40) * 60, Replies: 2}, r.ResponseTimes.Theirs)
+	assert.Equal(t, 25*time.Minute, r.ResponseTimes.Ours.Median())
+}
+
+func TestCompute_Location(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	r := Compute(testThreads(), tokyo)
+
+	assert.Equal(t, 5, r.ByHour[18])
+	assert.Equal(t, "JST", r.TimeZone)
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, ResponseTime{}, median(nil))
+	assert.Equal(t, 2.0, median([]time.Duration{3 * time.Second, time.Second, 2 * time.Second}).MedianSeconds)
+	assert.Equal(t, 2.5, median([]time.Duration{4 * time.Second, time.Second, 2 * time.Second, 3 * time.Second}).MedianSeconds)
+}
+
+// pagedSource serves a chat's messages newest first, two per page
+type pagedSource struct {
+	messages []api.Message
+	requests int
+}
+
+func (s *pagedSource) ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error) {
+	s.requests++
+	start := 0
+	if cursor != "" {
+		for i, m := range s.messages {
+			if m.SortKey == cursor {
+				start = i + 1
+			}
+		}
+	}
+	end := min(start+2, len(s.messages))
+	return &api.MessagesResponse{Items: s.messages[start:end], HasMore: end < len(s.messages)}, nil
+}
+
+func TestFetch(t *testing.T) {
+	var messages []api.Message
+	for i := 9; i >= 0; i-- {
+		messages = append(messages, msg(fmt.Sprint(i), "Ann", false, fmt.Sprintf("2024-03-%02dT12:00:00Z", i+1)))
+	}
+
+	src := &pagedSource{messages: messages}
+	since := time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)
+	got, err := Fetch(src, "chat", since, 2)
+	require.NoError(t, err)
+
+	// March 7 to 10, stopping at the page that reaches before since
+	assert.Len(t, got, 4)
+	assert.Equal(t, 3, src.requests)
+
+	src = &pagedSource{messages: messages}
+	got, err = Fetch(src, "chat", time.Time{}, 2)
+	require.NoError(t, err)
+	assert.Len(t, got, 10)
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// DefaultSearchLimit is the number of results returned when no limit is set
+const DefaultSearchLimit = 20
+
+// SearchOptions narrows a local search
+type SearchOptions struct {
+	// Limit caps the number of results (zero uses DefaultSearchLimit)
+	Limit int
+	// ChatID limits results to one chat
+	ChatID string
+	// Network limits results to chats on this network, case-insensitively
+	Network string
+}
+
+// Search runs an FTS5 query against the mirrored messages, best matches
+// first. The query uses FTS5 syntax: words must all match, "quoted phrases"
+// match exactly, word* matches a prefix, AND/OR/NOT combine terms and
+// sender:name searches the sender column. Each result's Snippet holds the
+// matching excerpt of its text.
+func (s *Store) Search(query string, opts SearchOptions) ([]api.Message, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("search query is empty")
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultSearchLimit
+	}
+
+	where := []string{"messages_fts MATCH ?"}
+	args := []interface{}{query}
+	if opts.ChatID != "" {
+		where = append(where, "m.chat_id = ?")
+		args = append(args, opts.ChatID)
+	}
+	if opts.Network != "" {
+		where = append(where, "m.chat_id IN (SELECT id FROM chats WHERE network = ? COLLATE NOCASE)")
+		args = append(args, opts.Network)
+	}
+	args = append(args, opts.Limit)
+
+	rows, err := s.db.Query(`SELECT m.data, snippet(messages_fts, 0, '', '', '…', 16)
+		FROM messages_fts JOIN messages m ON m.rowid = messages_fts.rowid
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY bm25(messages_fts), m.timestamp DESC
+		LIMIT ?`, args...)
+	if err != nil {
+		return nil, searchError(err)
+	}
+	defer rows.Close()
+
+	messages := []api.Message{}
+	for rows.Next() {
+		var data, snippet string
+		if err := rows.Scan(&data, &snippet); err != nil {
+			return nil, fmt.Errorf("failed to read search result: %w", err)
+		}
+		var msg api.Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return nil, fmt.Errorf("failed to parse stored message: %w", err)
+		}
+		msg.Snippet = snippet
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, searchError(err)
+	}
+	return messages, nil
+}
+
+// searchError reports malformed FTS5 queries as such rather than as
+// database failures
+func searchError(err error) error {
+	msg := err.Error()
+	if strings.Contains(msg, "fts5:") || strings.Contains(msg, "no such column") || strings.Contains(msg, "unterminated string") {
+		return fmt.Errorf("invalid search query: %w", err)
+	}
+	return fmt.Errorf("failed to search messages: %w", err)
+}
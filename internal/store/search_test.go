@@ -0,0 +1,97 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func searchFixture(t *testing.T) *Store {
+	t.Helper()
+	s := openTestStore(t)
+	src := &fakeSource{
+		chats: []api.Chat{{ID: "c1", Network: "slack"}, {ID: "c2", Network: "whatsapp"}},
+		messages: map[string][]api.Message{
+			"c1": {
+				{ID: "a", SortKey: "1", Sender: "Alice", Text: "Lunch at the new ramen place?", Timestamp: "2024-01-01T12:00:00Z"},
+				{ID: "b", SortKey: "2", Sender: "Bob", Text: "Deploying the release candidate now", Timestamp: "2024-01-01T13:00:00Z"},
+				{ID: "c", SortKey: "3", Sender: "Alice", Text: "release release release notes", Timestamp: "2024-01-01T14:00:00Z"},
+			},
+			"c2": {
+				{ID: "d", SortKey: "1", Sender: "Carol", Text: "Café opens at nine", Timestamp: "2024-01-02T09:00:00Z"},
+				{ID: "e", SortKey: "2", Sender: "Bob", Text: "ramen tonight", Timestamp: "2024-01-02T18:00:00Z"},
+			},
+		},
+	}
+	_, err := s.Sync(src, SyncOptions{})
+	require.NoError(t, err)
+	return s
+}
+
+func ids(messages []api.Message) []string {
+	var out []string
+	for _, m := range messages {
+		out = append(out, m.ID)
+	}
+	return out
+}
+
+func TestSearchQueries(t *testing.T) {
+	s := searchFixture(t)
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"word", "ramen", []string{"e", "a"}},
+		{"phrase", `"release candidate"`, []string{"b"}},
+		{"prefix", "deploy*", []string{"b"}},
+		{"and", "ramen AND lunch", []string{"a"}},
+		{"or", "lunch OR café", []string{"a", "d"}},
+		{"not", "ramen NOT tonight", []string{"a"}},
+		{"diacritics", "cafe", []string{"d"}},
+		{"sender column", "sender:carol", []string{"d"}},
+		{"ranked", "release", []string{"c", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := s.Search(tt.query, SearchOptions{})
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, ids(results))
+			if tt.name == "ranked" {
+				assert.Equal(t, tt.want, ids(results))
+			}
+		})
+	}
+}
+
+func TestSearchFiltersAndSnippets(t *testing.T) {
+	s := searchFixture(t)
+
+	results, err := s.Search("ramen", SearchOptions{ChatID: "c2"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e"}, ids(results))
+	assert.Equal(t, "c2", results[0].ChatID)
+	assert.Equal(t, "ramen tonight", results[0].Snippet)
+
+	results, err = s.Search("ramen", SearchOptions{Network: "SLACK"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, ids(results))
+
+	results, err = s.Search("release", SearchOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestSearchInvalidQuery(t *testing.T) {
+	s := searchFixture(t)
+
+	_, err := s.Search(`"unterminated`, SearchOptions{})
+	assert.ErrorContains(t, err, "invalid search query")
+
+	_, err = s.Search("  ", SearchOptions{})
+	assert.ErrorContains(t, err, "empty")
+}
@@ -0,0 +1,174 @@
+// Package store mirrors chats and messages into a local SQLite database with
+// an FTS5 full-text index, for fast offline search.
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+
+	// Pure-Go SQLite driver, so the CLI stays a single static binary
+	_ "modernc.org/sqlite"
+)
+
+// DefaultFile is the database file name in the config directory
+const DefaultFile = "beeper.db"
+
+// schemaVersion is stored in PRAGMA user_version and bumped with the schema
+const schemaVersion = 1
+
+const schema = `
+CREATE TABLE IF NOT EXISTS chats (
+	id           TEXT PRIMARY KEY,
+	title        TEXT NOT NULL DEFAULT '',
+	type         TEXT NOT NULL DEFAULT '',
+	network      TEXT NOT NULL DEFAULT '',
+	unread_count INTEGER NOT NULL DEFAULT 0,
+	is_muted     INTEGER NOT NULL DEFAULT 0,
+	is_archived  INTEGER NOT NULL DEFAULT 0,
+	is_pinned    INTEGER NOT NULL DEFAULT 0,
+	data         TEXT NOT NULL,
+	synced_at    TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+	rowid     INTEGER PRIMARY KEY,
+	id        TEXT NOT NULL UNIQUE,
+	chat_id   TEXT NOT NULL,
+	sender    TEXT NOT NULL DEFAULT '',
+	text      TEXT NOT NULL DEFAULT '',
+	timestamp TEXT NOT NULL DEFAULT '',
+	sort_key  TEXT NOT NULL DEFAULT '',
+	is_sender INTEGER NOT NULL DEFAULT 0,
+	data      TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS messages_chat_time ON messages (chat_id, timestamp);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5 (
+	text, sender,
+	content = 'messages', content_rowid = 'rowid',
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS messages_ai AFTER INSERT ON messages BEGIN
+	INSERT INTO messages_fts (rowid, text, sender) VALUES (new.rowid, new.text, new.sender);
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_ad AFTER DELETE ON messages BEGIN
+	INSERT INTO messages_fts (messages_fts, rowid, text, sender) VALUES ('delete', old.rowid, old.text, old.sender);
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_au AFTER UPDATE ON messages BEGIN
+	INSERT INTO messages_fts (messages_fts, rowid, text, sender) VALUES ('delete', old.rowid, old.text, old.sender);
+	INSERT INTO messages_fts (rowid, text, sender) VALUES (new.rowid, new.text, new.sender);
+END;
+
+CREATE TABLE IF NOT EXISTS sync_state (
+	chat_id        TEXT PRIMARY KEY,
+	last_sort_key  TEXT NOT NULL DEFAULT '',
+	pending        INTEGER NOT NULL DEFAULT 0,
+	pending_after  TEXT NOT NULL DEFAULT '',
+	pending_cursor TEXT NOT NULL DEFAULT '',
+	pending_top    TEXT NOT NULL DEFAULT '',
+	synced_at      TEXT NOT NULL DEFAULT ''
+);
+`
+
+// Store is a local mirror of chats and messages
+type Store struct {
+	db *sql.DB
+}
+
+// DefaultPath returns the database path in the config directory
+func DefaultPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), DefaultFile)
+}
+
+// Open opens the database at path, creating it and its schema if needed
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	// WAL lets searches run while a sync is writing; the busy timeout makes
+	// concurrent writers wait instead of failing
+	dsn := "file:" + filepath.ToSlash(path) + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if version > schemaVersion {
+		db.Close()
+		return nil, fmt.Errorf("database schema version %d is newer than this CLI supports (%d); upgrade beeper", version, schemaVersion)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create database schema: %w", err)
+	}
+	if _, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", schemaVersion)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create database schema: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Stats summarizes the contents of the mirror
+type Stats struct {
+	Chats    int `json:"chats"`
+	Messages int `json:"messages"`
+}
+
+// Stats counts the chats and messages in the mirror
+func (s *Store) Stats() (Stats, error) {
+	var st Stats
+	if err := s.db.QueryRow("SELECT (SELECT count(*) FROM chats), (SELECT count(*) FROM messages)").Scan(&st.Chats, &st.Messages); err != nil {
+		return st, fmt.Errorf("failed to count rows: %w", err)
+	}
+	return st, nil
+}
+
+// Chats lists the mirrored chats, without participants
+func (s *Store) Chats() ([]api.Chat, error) {
+	rows, err := s.db.Query("SELECT id, title, type, network FROM chats ORDER BY title, id")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chats: %w", err)
+	}
+	defer rows.Close()
+
+	var chats []api.Chat
+	for rows.Next() {
+		var chat api.Chat
+		if err := rows.Scan(&chat.ID, &chat.Title, &chat.Type, &chat.Network); err != nil {
+			return nil, fmt.Errorf("failed to list chats: %w", err)
+		}
+		chats = append(chats, chat)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list chats: %w", err)
+	}
+	return chats, nil
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,293 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// DefaultPageSize is the number of messages requested per page
+const DefaultPageSize = 100
+
+// now returns the current time; tests replace it
+var now = time.Now
+
+// Source is the part of the API client a sync reads from
+type Source interface {
+	ListChatsPage(cursor string) (*api.ChatsResponse, error)
+	GetChat(chatID string) (*api.Chat, error)
+	ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error)
+}
+
+// SyncOptions selects what to sync
+type SyncOptions struct {
+	// ChatIDs limits the sync to these chats (empty syncs every chat)
+	ChatIDs []string
+	// Network limits the sync to chats on this network, case-insensitively
+	Network string
+	// PageSize is the number of messages requested per page
+	PageSize int
+	// Progress, if set, is called after each chat is synced
+	Progress func(ChatResult)
+}
+
+// ChatResult reports the sync of one chat
+type ChatResult struct {
+	ChatID      string `json:"chat_id"`
+	Title       string `json:"title"`
+	NewMessages int    `json:"new_messages"`
+	Error       string `json:"error,omitempty"`
+}
+
+// SyncSummary reports a complete sync run
+type SyncSummary struct {
+	Chats []ChatResult `json:"chats"`
+	Stats Stats        `json:"stats"`
+}
+
+// Failed returns the number of chats that could not be synced
+func (s *SyncSummary) Failed() int {
+	failed := 0
+	for _, c := range s.Chats {
+		if c.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// syncState is a chat's row in sync_state. A pass pages from the newest
+// message back to the last synced one; while it runs, pending is set and
+// the cursor is checkpointed after every page so an interrupted pass resumes.
+type syncState struct {
+	LastSortKey string
+	Pending     bool
+	After       string
+	Cursor      string
+	Top         string
+}
+
+// Sync mirrors the selected chats and their new messages into the store. A
+// failure in one chat is recorded in its result and the sync moves on to the
+// next chat; only problems listing chats or with the database abort the run.
+func (s *Store) Sync(src Source, opts SyncOptions) (*SyncSummary, error) {
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultPageSize
+	}
+
+	chats, err := selectChats(src, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &SyncSummary{}
+	for _, chat := range chats {
+		result := ChatResult{ChatID: chat.ID, Title: chat.Title}
+		if err := s.syncChat(src, chat, opts.PageSize, &result); err != nil {
+			result.Error = err.Error()
+		}
+		summary.Chats = append(summary.Chats, result)
+		if opts.Progress != nil {
+			opts.Progress(result)
+		}
+	}
+
+	stats, err := s.Stats()
+	if err != nil {
+		return nil, err
+	}
+	summary.Stats = stats
+	return summary, nil
+}
+
+// selectChats returns the chats matching the chat ID and network filters
+func selectChats(src Source, opts SyncOptions) ([]api.Chat, error) {
+	var chats []api.Chat
+	if len(opts.ChatIDs) > 0 {
+		for _, id := range opts.ChatIDs {
+			chat, err := src.GetChat(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get chat %s: %w", id, err)
+			}
+			chats = append(chats, *chat)
+		}
+	} else {
+		cursor := ""
+		for {
+			page, err := src.ListChatsPage(cursor)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list chats: %w", err)
+			}
+			chats = append(chats, page.Items...)
+			if !page.HasMore || page.OldestCursor == "" || page.OldestCursor == cursor {
+				break
+			}
+			cursor = page.OldestCursor
+		}
+	}
+
+	if opts.Network == "" {
+		return chats, nil
+	}
+	selected := chats[:0]
+	for _, chat := range chats {
+		if strings.EqualFold(chat.Network, opts.Network) {
+			selected = append(selected, chat)
+		}
+	}
+	return selected, nil
+}
+
+// syncChat stores the chat, completes any interrupted pass, then runs
+// passes until one starting from the newest message completes
+func (s *Store) syncChat(src Source, chat api.Chat, pageSize int, result *ChatResult) error {
+	if err := s.putChat(chat); err != nil {
+		return err
+	}
+
+	state, err := s.loadState(chat.ID)
+	if err != nil {
+		return err
+	}
+	for {
+		resumed := state.Pending && state.Cursor != ""
+		if !state.Pending {
+			state = syncState{LastSortKey: state.LastSortKey, Pending: true, After: state.LastSortKey}
+		}
+		if err := s.pass(src, chat.ID, pageSize, &state, result); err != nil {
+			return err
+		}
+		if !resumed {
+			return nil
+		}
+	}
+}
+
+// pass pages back from the pass cursor until it reaches the last synced
+// message or the start of the history, storing and checkpointing each page
+func (s *Store) pass(src Source, chatID string, pageSize int, state *syncState, result *ChatResult) error {
+	for {
+		page, err := src.ListMessagesPage(chatID, state.Cursor, pageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+
+		if state.Top == "" && len(page.Items) > 0 {
+			state.Top = page.Items[0].SortKey
+		}
+
+		fresh := page.Items
+		for i, msg := range page.Items {
+			if state.After != "" && api.CompareSortKeys(msg.SortKey, state.After) <= 0 {
+				fresh = page.Items[:i]
+				break
+			}
+		}
+
+		next := page.NextCursor()
+		done := len(fresh) < len(page.Items) || !page.HasMore || next == "" || next == state.Cursor
+		state.Cursor = next
+		if done {
+			if state.Top != "" && (state.LastSortKey == "" || api.CompareSortKeys(state.Top, state.LastSortKey) > 0) {
+				state.LastSortKey = state.Top
+			}
+			state.Pending, state.After, state.Cursor, state.Top = false, "", "", ""
+		}
+
+		if err := s.putMessages(chatID, fresh, state); err != nil {
+			return err
+		}
+		result.NewMessages += len(fresh)
+		if done {
+			return nil
+		}
+	}
+}
+
+// putChat inserts or updates a chat
+func (s *Store) putChat(chat api.Chat) error {
+	data, err := json.Marshal(chat)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chat: %w", err)
+	}
+	_, err = s.db.Exec(`INSERT INTO chats (id, title, type, network, unread_count, is_muted, is_archived, is_pinned, data, synced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET title = excluded.title, type = excluded.type, network = excluded.network,
+			unread_count = excluded.unread_count, is_muted = excluded.is_muted, is_archived = excluded.is_archived,
+			is_pinned = excluded.is_pinned, data = excluded.data, synced_at = excluded.synced_at`,
+		chat.ID, chat.Title, chat.Type, chat.Network, chat.UnreadCount,
+		boolInt(chat.IsMuted), boolInt(chat.IsArchived), boolInt(chat.IsPinned),
+		string(data), now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to store chat: %w", err)
+	}
+	return nil
+}
+
+// putMessages inserts or updates a page of messages and saves the sync
+// state in the same transaction, so a checkpoint never runs ahead of the
+// messages it covers
+func (s *Store) putMessages(chatID string, messages []api.Message, state *syncState) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to store messages: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, msg := range messages {
+		if msg.ChatID == "" {
+			msg.ChatID = chatID
+		}
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal message %s: %w", msg.ID, err)
+		}
+		_, err = tx.Exec(`INSERT INTO messages (id, chat_id, sender, text, timestamp, sort_key, is_sender, data)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) DO UPDATE SET chat_id = excluded.chat_id, sender = excluded.sender, text = excluded.text,
+				timestamp = excluded.timestamp, sort_key = excluded.sort_key, is_sender = excluded.is_sender, data = excluded.data`,
+			msg.ID, msg.ChatID, msg.Sender, msg.Text, msg.Timestamp, msg.SortKey, boolInt(msg.IsSender), string(data))
+		if err != nil {
+			return fmt.Errorf("failed to store message %s: %w", msg.ID, err)
+		}
+	}
+
+	syncedAt := ""
+	if !state.Pending {
+		syncedAt = now().UTC().Format(time.RFC3339)
+	}
+	_, err = tx.Exec(`INSERT INTO sync_state (chat_id, last_sort_key, pending, pending_after, pending_cursor, pending_top, synced_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (chat_id) DO UPDATE SET last_sort_key = excluded.last_sort_key, pending = excluded.pending,
+			pending_after = excluded.pending_after, pending_cursor = excluded.pending_cursor, pending_top = excluded.pending_top,
+			synced_at = CASE WHEN excluded.synced_at = '' THEN sync_state.synced_at ELSE excluded.synced_at END`,
+		chatID, state.LastSortKey, boolInt(state.Pending), state.After, state.Cursor, state.Top, syncedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save sync state: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to store messages: %w", err)
+	}
+	return nil
+}
+
+// loadState reads a chat's sync state; a chat never synced has a zero state
+func (s *Store) loadState(chatID string) (syncState, error) {
+	var state syncState
+	var pending int
+	err := s.db.QueryRow(`SELECT last_sort_key, pending, pending_after, pending_cursor, pending_top FROM sync_state WHERE chat_id = ?`, chatID).
+		Scan(&state.LastSortKey, &pending, &state.After, &state.Cursor, &state.Top)
+	if err == sql.ErrNoRows {
+		return syncState{}, nil
+	}
+	if err != nil {
+		return syncState{}, fmt.Errorf("failed to read sync state: %w", err)
+	}
+	state.Pending = pending != 0
+	return state, nil
+}
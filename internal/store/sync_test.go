@@ -0,0 +1,192 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSource serves chats and newest-first message pages from memory
+type fakeSource struct {
+	chats    []api.Chat
+	messages map[string][]api.Message // oldest first
+	// failAfter makes ListMessagesPage fail once this many pages were served
+	failAfter int
+	served    int
+	cursors   []string
+}
+
+func (f *fakeSource) ListChatsPage(cursor string) (*api.ChatsResponse, error) {
+	return &api.ChatsResponse{Items: f.chats}, nil
+}
+
+func (f *fakeSource) GetChat(chatID string) (*api.Chat, error) {
+	for _, c := range f.chats {
+		if c.ID == chatID {
+			return &c, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeSource) ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error) {
+	if f.failAfter > 0 && f.served >= f.failAfter {
+		return nil, errors.New("connection reset")
+	}
+	f.served++
+	f.cursors = append(f.cursors, cursor)
+
+	all := f.messages[chatID]
+	end := len(all)
+	if cursor != "" {
+		end = 0
+		for i, m := range all {
+			if m.SortKey == cursor {
+				end = i
+			}
+		}
+	}
+	start := end - limit
+	if start < 0 {
+		start = 0
+	}
+	var page []api.Message
+	for i := end - 1; i >= start; i-- {
+		page = append(page, all[i])
+	}
+	return &api.MessagesResponse{Items: page, HasMore: start > 0}, nil
+}
+
+// history returns n messages one minute apart with sort keys from..from+n-1
+func history(chatID string, from, n int) []api.Message {
+	var msgs []api.Message
+	for i := from; i < from+n; i++ {
+		msgs = append(msgs, api.Message{
+			ID:        fmt.Sprintf("%s-m%d", chatID, i),
+			ChatID:    chatID,
+			SortKey:   strconv.Itoa(i),
+			Sender:    "Alice",
+			Text:      fmt.Sprintf("message %d", i),
+			Timestamp: time.Date(2024, 1, 1, 0, i, 0, 0, time.UTC).Format(time.RFC3339),
+		})
+	}
+	return msgs
+}
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(filepath.Join(t.TempDir(), "nested", DefaultFile))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSyncMirrorsIncrementally(t *testing.T) {
+	s := openTestStore(t)
+	src := &fakeSource{
+		chats:    []api.Chat{{ID: "c1", Title: "Team", Network: "slack"}},
+		messages: map[string][]api.Message{"c1": history("c1", 1, 5)},
+	}
+
+	summary, err := s.Sync(src, SyncOptions{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, summary.Chats, 1)
+	assert.Equal(t, 5, summary.Chats[0].NewMessages)
+	assert.Equal(t, Stats{Chats: 1, Messages: 5}, summary.Stats)
+
+	chats, err := s.Chats()
+	require.NoError(t, err)
+	assert.Equal(t, []api.Chat{{ID: "c1", Title: "Team", Network: "slack"}}, chats)
+
+	src.messages["c1"] = append(src.messages["c1"], history("c1", 6, 3)...)
+	src.cursors = nil
+	summary, err = s.Sync(src, SyncOptions{PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, summary.Chats[0].NewMessages)
+	assert.Equal(t, 8, summary.Stats.Messages)
+	// Two pages reach back to message 5; nothing older is fetched again
+	assert.Equal(t, []string{"", "7"}, src.cursors)
+}
+
+func TestSyncResumesInterruptedPass(t *testing.T) {
+	s := openTestStore(t)
+	src := &fakeSource{
+		chats:     []api.Chat{{ID: "c1", Title: "Team"}},
+		messages:  map[string][]api.Message{"c1": history("c1", 1, 6)},
+		failAfter: 2,
+	}
+
+	summary, err := s.Sync(src, SyncOptions{PageSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Failed())
+	assert.Equal(t, 4, summary.Stats.Messages)
+
+	src.failAfter, src.cursors = 0, nil
+	summary, err = s.Sync(src, SyncOptions{PageSize: 2})
+	require.NoError(t, err)
+	assert.Zero(t, summary.Failed())
+	assert.Equal(t, 6, summary.Stats.Messages)
+	// The interrupted pass resumes at message 3, then a fresh pass finds
+	// nothing new
+	assert.Equal(t, []string{"3", ""}, src.cursors)
+}
+
+func TestSyncFiltersByNetwork(t *testing.T) {
+	s := openTestStore(t)
+	src := &fakeSource{
+		chats: []api.Chat{{ID: "c1", Network: "slack"}, {ID: "c2", Network: "WhatsApp"}},
+		messages: map[string][]api.Message{
+			"c1": history("c1", 1, 2),
+			"c2": history("c2", 1, 3),
+		},
+	}
+
+	summary, err := s.Sync(src, SyncOptions{Network: "whatsapp"})
+	require.NoError(t, err)
+	require.Len(t, summary.Chats, 1)
+	assert.Equal(t, "c2", summary.Chats[0].ChatID)
+	assert.Equal(t, Stats{Chats: 1, Messages: 3}, summary.Stats)
+}
+
+func TestSyncUpdatesEditedMessages(t *testing.T) {
+	s := openTestStore(t)
+	msgs := history("c1", 1, 2)
+	src := &fakeSource{chats: []api.Chat{{ID: "c1"}}, messages: map[string][]api.Message{"c1": msgs}}
+	_, err := s.Sync(src, SyncOptions{})
+	require.NoError(t, err)
+
+	// A full re-sync of the same chat replaces stored rows in place
+	_, err = s.db.Exec("DELETE FROM sync_state")
+	require.NoError(t, err)
+	msgs[1].Text = "edited banana"
+	_, err = s.Sync(src, SyncOptions{})
+	require.NoError(t, err)
+
+	results, err := s.Search("banana", SearchOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "c1-m2", results[0].ID)
+
+	results, err = s.Search(`"message 2"`, SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestOpenRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFile)
+	s, err := Open(path)
+	require.NoError(t, err)
+	_, err = s.db.Exec("PRAGMA user_version = 99")
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	_, err = Open(path)
+	assert.ErrorContains(t, err, "schema version 99")
+}
@@ -0,0 +1,104 @@
+package tui
+
+import "unicode/utf8"
+
+// KeyCode identifies a key press
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPgUp
+	KeyPgDn
+	KeyDelete
+	// KeyCtrl is a control key; Rune holds its letter, e.g. 'c' for Ctrl-C
+	KeyCtrl
+)
+
+// Key is one key press read from the terminal
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// escapeKeys maps the escape sequences terminals send for special keys
+var escapeKeys = map[string]KeyCode{
+	"[A": KeyUp, "[B": KeyDown, "[C": KeyRight, "[D": KeyLeft,
+	"OA": KeyUp, "OB": KeyDown, "OC": KeyRight, "OD": KeyLeft,
+	"[H": KeyHome, "[F": KeyEnd, "OH": KeyHome, "OF": KeyEnd,
+	"[1~": KeyHome, "[4~": KeyEnd, "[7~": KeyHome, "[8~": KeyEnd,
+	"[5~": KeyPgUp, "[6~": KeyPgDn, "[3~": KeyDelete,
+}
+
+// parseKeys decodes a chunk of raw terminal input. A lone ESC is the Escape
+// key; an ESC starting an unknown sequence is dropped with the sequence.
+func parseKeys(buf []byte) []Key {
+	var keys []Key
+	for len(buf) > 0 {
+		b := buf[0]
+		switch {
+		case b == 0x1b:
+			if len(buf) == 1 {
+				keys = append(keys, Key{Code: KeyEsc})
+				buf = buf[1:]
+				continue
+			}
+			n := escapeLen(buf)
+			if code, ok := escapeKeys[string(buf[1:n])]; ok {
+				keys = append(keys, Key{Code: code})
+			} else if n == 1 {
+				keys = append(keys, Key{Code: KeyEsc})
+			}
+			buf = buf[n:]
+		case b == '\r' || b == '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+			buf = buf[1:]
+		case b == '\t':
+			keys = append(keys, Key{Code: KeyTab})
+			buf = buf[1:]
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, Key{Code: KeyBackspace})
+			buf = buf[1:]
+		case b < 0x20:
+			keys = append(keys, Key{Code: KeyCtrl, Rune: rune('a' + b - 1)})
+			buf = buf[1:]
+		default:
+			r, size := utf8.DecodeRune(buf)
+			if r != utf8.RuneError || size > 1 {
+				keys = append(keys, Key{Code: KeyRune, Rune: r})
+			}
+			buf = buf[size:]
+		}
+	}
+	return keys
+}
+
+// escapeLen returns the length of the escape sequence at the start of buf:
+// ESC [ params final, ESC O final, or just ESC when nothing follows that
+// starts a sequence
+func escapeLen(buf []byte) int {
+	if len(buf) < 2 {
+		return 1
+	}
+	switch buf[1] {
+	case 'O':
+		return min(3, len(buf))
+	case '[':
+		for i := 2; i < len(buf); i++ {
+			if buf[i] >= 0x40 && buf[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(buf)
+	}
+	return 1
+}
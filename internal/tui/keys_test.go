@@ -0,0 +1,30 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []Key
+	}{
+		{"runes", "hé", []Key{{Code: KeyRune, Rune: 'h'}, {Code: KeyRune, Rune: 'é'}}},
+		{"enter", "\r", []Key{{Code: KeyEnter}}},
+		{"backspace", "\x7f", []Key{{Code: KeyBackspace}}},
+		{"ctrl-c", "\x03", []Key{{Code: KeyCtrl, Rune: 'c'}}},
+		{"lone escape", "\x1b", []Key{{Code: KeyEsc}}},
+		{"arrows", "\x1b[A\x1b[B\x1bOC", []Key{{Code: KeyUp}, {Code: KeyDown}, {Code: KeyRight}}},
+		{"page keys", "\x1b[5~\x1b[6~", []Key{{Code: KeyPgUp}, {Code: KeyPgDn}}},
+		{"unknown sequence dropped", "\x1b[99;5Ux", []Key{{Code: KeyRune, Rune: 'x'}}},
+		{"alt letter", "\x1bx", []Key{{Code: KeyEsc}, {Code: KeyRune, Rune: 'x'}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseKeys([]byte(tt.in)))
+		})
+	}
+}
@@ -0,0 +1,718 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// Backend is the part of the API client the interface uses
+type Backend interface {
+	ListChatsPage(cursor string) (*api.ChatsResponse, error)
+	ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error)
+	SearchMessagesPage(query, cursor string, limit int) (*api.SearchResponse, error)
+	SendMessage(chatID, text string) (string, error)
+	ArchiveChat(chatID string, archived bool) error
+	MarkChatRead(chatID string) error
+}
+
+// focus is the part of the screen receiving keys
+type focus int
+
+const (
+	focusChats focus = iota
+	focusFilter
+	focusMessages
+	focusCompose
+	focusSearchInput
+	focusSearchResults
+)
+
+// searchLimit is the number of search results requested
+const searchLimit = 20
+
+// msg is the result of a background command, fed back into the model
+type msg interface{}
+
+// cmd runs in the background and reports back with a msg
+type cmd func() msg
+
+type chatsLoaded struct {
+	cursor string
+	page   *api.ChatsResponse
+	err    error
+}
+
+type messagesLoaded struct {
+	chatID string
+	older  bool
+	page   *api.MessagesResponse
+	err    error
+}
+
+type searchLoaded struct {
+	query string
+	resp  *api.SearchResponse
+	err   error
+}
+
+type messageSent struct {
+	chatID string
+	text   string
+	err    error
+}
+
+type chatArchived struct {
+	chatID   string
+	archived bool
+	err      error
+}
+
+type chatRead struct {
+	chatID string
+	err    error
+}
+
+// tick asks for a refresh of the chat list and the open chat
+type tick struct{}
+
+// openChat is the chat shown in the message pane
+type openChat struct {
+	chat     api.Chat
+	messages []api.Message // oldest first
+	hasMore  bool
+	loading  bool
+	// scroll is the number of lines scrolled up from the newest message
+	scroll int
+}
+
+// searchResults are shown in the message pane in place of the open chat
+type searchResults struct {
+	query    string
+	items    []api.Message
+	selected int
+	loading  bool
+}
+
+// model holds the interface state. Key presses and background results
+// update it and return commands to run; view renders it.
+type model struct {
+	b        Backend
+	pageSize int
+
+	width, height int
+
+	chats        []api.Chat
+	chatsHasMore bool
+	chatsCursor  string
+	chatsLoading bool
+	filter       []rune
+	selected     int // index into visibleChats
+
+	focus   focus
+	open    *openChat
+	compose []rune
+	sending bool
+	query   []rune
+	search  *searchResults
+
+	status    string
+	statusErr bool
+	quit      bool
+}
+
+func newModel(b Backend, pageSize int) *model {
+	return &model{b: b, pageSize: pageSize, width: 80, height: 24}
+}
+
+// init loads the first page of chats
+func (m *model) init() []cmd {
+	m.chatsLoading = true
+	m.setStatus("Loading chats…")
+	return []cmd{m.loadChats("")}
+}
+
+func (m *model) setStatus(format string, args ...interface{}) {
+	m.status, m.statusErr = fmt.Sprintf(format, args...), false
+}
+
+func (m *model) setError(err error) {
+	m.status, m.statusErr = "Error: "+err.Error(), true
+}
+
+// visibleChats returns the chats matching the filter
+func (m *model) visibleChats() []api.Chat {
+	if len(m.filter) == 0 {
+		return m.chats
+	}
+	needle := strings.ToLower(string(m.filter))
+	var chats []api.Chat
+	for _, chat := range m.chats {
+		if strings.Contains(strings.ToLower(chat.Title), needle) || strings.EqualFold(chat.Network, needle) {
+			chats = append(chats, chat)
+		}
+	}
+	return chats
+}
+
+func (m *model) selectedChat() (api.Chat, bool) {
+	chats := m.visibleChats()
+	if m.selected < 0 || m.selected >= len(chats) {
+		return api.Chat{}, false
+	}
+	return chats[m.selected], true
+}
+
+// targetChat is the chat archive and mark read act on: the open chat when
+// the message pane has focus, otherwise the selected one
+func (m *model) targetChat() (api.Chat, bool) {
+	if m.open != nil && (m.focus == focusMessages || m.focus == focusCompose) {
+		return m.open.chat, true
+	}
+	return m.selectedChat()
+}
+
+func (m *model) chatIndex(chatID string) int {
+	return slices.IndexFunc(m.chats, func(c api.Chat) bool { return c.ID == chatID })
+}
+
+// bodyHeight is the number of rows between the header and the input line
+func (m *model) bodyHeight() int {
+	return max(1, m.height-3)
+}
+
+// leftWidth is the width of the chat list pane
+func (m *model) leftWidth() int {
+	return min(40, max(16, m.width/3))
+}
+
+// rightWidth is the width of the message pane
+func (m *model) rightWidth() int {
+	return max(10, m.width-m.leftWidth()-1)
+}
+
+func (m *model) resize(width, height int) {
+	m.width, m.height = width, height
+	m.clampScroll()
+}
+
+// update applies a background result
+func (m *model) update(message msg) []cmd {
+	switch r := message.(type) {
+	case tick:
+		return m.refresh()
+	case chatsLoaded:
+		return m.chatsLoaded(r)
+	case messagesLoaded:
+		return m.messagesLoaded(r)
+	case searchLoaded:
+		if m.search == nil || m.search.query != r.query {
+			return nil
+		}
+		m.search.loading = false
+		if r.err != nil {
+			m.setError(r.err)
+			return nil
+		}
+		m.search.items = r.resp.Items
+		m.setStatus("%d results for %q", len(r.resp.Items), r.query)
+	case messageSent:
+		m.sending = false
+		here := m.open != nil && m.open.chat.ID == r.chatID
+		if r.err != nil {
+			// The draft was kept while sending; put it back if it was cleared
+			if here && len(m.compose) == 0 {
+				m.compose = []rune(r.text)
+			}
+			m.setError(r.err)
+			return nil
+		}
+		m.setStatus("Sent")
+		if here {
+			if strings.TrimSpace(string(m.compose)) == r.text {
+				m.compose = nil
+			}
+			m.open.scroll = 0
+			return []cmd{m.loadMessages(r.chatID, "", false)}
+		}
+	case chatArchived:
+		if r.err != nil {
+			m.setError(r.err)
+			return nil
+		}
+		m.updateChat(r.chatID, func(c *api.Chat) { c.IsArchived = r.archived })
+		if r.archived {
+			m.setStatus("Archived")
+		} else {
+			m.setStatus("Moved to inbox")
+		}
+	case chatRead:
+		if r.err != nil {
+			m.setError(r.err)
+			return nil
+		}
+		m.updateChat(r.chatID, func(c *api.Chat) { c.UnreadCount = 0 })
+		m.setStatus("Marked read")
+	}
+	return nil
+}
+
+func (m *model) updateChat(chatID string, fn func(*api.Chat)) {
+	if i := m.chatIndex(chatID); i >= 0 {
+		fn(&m.chats[i])
+	}
+	if m.open != nil && m.open.chat.ID == chatID {
+		fn(&m.open.chat)
+	}
+}
+
+// refresh polls for new chats and messages
+func (m *model) refresh() []cmd {
+	var cmds []cmd
+	if !m.chatsLoading {
+		m.chatsLoading = true
+		cmds = append(cmds, m.loadChats(""))
+	}
+	if m.open != nil && !m.open.loading {
+		cmds = append(cmds, m.loadMessages(m.open.chat.ID, "", false))
+	}
+	return cmds
+}
+
+// chatsLoaded merges a page of chats. The first page is a refresh: its
+// chats move to the top, most recently active first, and keep their
+// position in any further pages already loaded.
+func (m *model) chatsLoaded(r chatsLoaded) []cmd {
+	m.chatsLoading = false
+	if r.err != nil {
+		m.setError(r.err)
+		return nil
+	}
+
+	selectedID := ""
+	if chat, ok := m.selectedChat(); ok {
+		selectedID = chat.ID
+	}
+
+	if r.cursor == "" {
+		first := len(m.chats) == 0
+		seen := make(map[string]bool, len(r.page.Items))
+		merged := make([]api.Chat, 0, len(m.chats)+len(r.page.Items))
+		for _, chat := range r.page.Items {
+			seen[chat.ID] = true
+			merged = append(merged, chat)
+		}
+		for _, chat := range m.chats {
+			if !seen[chat.ID] {
+				merged = append(merged, chat)
+			}
+		}
+		m.chats = merged
+		if first {
+			m.chatsHasMore, m.chatsCursor = r.page.HasMore, r.page.OldestCursor
+			m.setStatus("%d chats", len(m.chats))
+		}
+	} else {
+		for _, chat := range r.page.Items {
+			if m.chatIndex(chat.ID) < 0 {
+				m.chats = append(m.chats, chat)
+			}
+		}
+		m.chatsHasMore, m.chatsCursor = r.page.HasMore && r.page.OldestCursor != r.cursor, r.page.OldestCursor
+	}
+
+	if m.open != nil {
+		if i := m.chatIndex(m.open.chat.ID); i >= 0 {
+			m.open.chat = m.chats[i]
+		}
+	}
+	m.selected = 0
+	if selectedID != "" {
+		if i := slices.IndexFunc(m.visibleChats(), func(c api.Chat) bool { return c.ID == selectedID }); i >= 0 {
+			m.selected = i
+		}
+	}
+	return nil
+}
+
+// messagesLoaded merges a page of messages into the open chat. Older pages
+// extend the scrollback; the newest page adds new and edited messages, and
+// when none of them were held yet the pages before it are loaded too, until
+// they meet the scrollback.
+func (m *model) messagesLoaded(r messagesLoaded) []cmd {
+	if m.open == nil || m.open.chat.ID != r.chatID {
+		return nil
+	}
+	open := m.open
+	open.loading = false
+	if r.err != nil {
+		m.setError(r.err)
+		return nil
+	}
+
+	first := len(open.messages) == 0
+	byID := make(map[string]int, len(open.messages))
+	for i, message := range open.messages {
+		byID[message.ID] = i
+	}
+	overlap := false
+	oldest := ""
+	for _, message := range r.page.Items {
+		if oldest == "" || api.CompareSortKeys(message.SortKey, oldest) < 0 {
+			oldest = message.SortKey
+		}
+		if i, ok := byID[message.ID]; ok {
+			open.messages[i] = message
+			overlap = true
+			continue
+		}
+		byID[message.ID] = len(open.messages)
+		open.messages = append(open.messages, message)
+	}
+	slices.SortStableFunc(open.messages, func(a, b api.Message) int {
+		return api.CompareSortKeys(a.SortKey, b.SortKey)
+	})
+	if r.older || first {
+		open.hasMore = r.page.HasMore
+	}
+
+	// More arrived since the last refresh than one page holds
+	if !r.older && !first && !overlap && r.page.HasMore && oldest != "" {
+		return []cmd{m.loadMessages(r.chatID, oldest, false)}
+	}
+
+	// Keep loading history until the pane is full
+	if open.hasMore && m.maxScroll() == 0 {
+		return m.loadOlder()
+	}
+	return nil
+}
+
+func (m *model) loadChats(cursor string) cmd {
+	return func() msg {
+		page, err := m.b.ListChatsPage(cursor)
+		return chatsLoaded{cursor: cursor, page: page, err: err}
+	}
+}
+
+func (m *model) loadMessages(chatID, cursor string, older bool) cmd {
+	if m.open != nil && m.open.chat.ID == chatID {
+		m.open.loading = true
+	}
+	return func() msg {
+		page, err := m.b.ListMessagesPage(chatID, cursor, m.pageSize)
+		return messagesLoaded{chatID: chatID, older: older, page: page, err: err}
+	}
+}
+
+// loadOlder fetches the page before the oldest loaded message
+func (m *model) loadOlder() []cmd {
+	if m.open == nil || m.open.loading || !m.open.hasMore || len(m.open.messages) == 0 {
+		return nil
+	}
+	return []cmd{m.loadMessages(m.open.chat.ID, m.open.messages[0].SortKey, true)}
+}
+
+// loadMoreChats fetches the next page of chats when the selection nears
+// the end of the list
+func (m *model) loadMoreChats() []cmd {
+	if m.chatsLoading || !m.chatsHasMore || len(m.filter) > 0 || m.selected < len(m.chats)-m.bodyHeight()/2 {
+		return nil
+	}
+	m.chatsLoading = true
+	return []cmd{m.loadChats(m.chatsCursor)}
+}
+
+func (m *model) openChat(chat api.Chat) []cmd {
+	if m.open != nil && m.open.chat.ID == chat.ID {
+		return nil
+	}
+	m.open = &openChat{chat: chat}
+	m.compose = nil
+	return []cmd{m.loadMessages(chat.ID, "", false)}
+}
+
+func (m *model) maxScroll() int {
+	if m.open == nil {
+		return 0
+	}
+	return max(0, len(m.messageLines())-(m.bodyHeight()-1))
+}
+
+func (m *model) clampScroll() {
+	if m.open != nil {
+		m.open.scroll = min(max(0, m.open.scroll), m.maxScroll())
+	}
+}
+
+// scroll moves the message pane by delta lines (positive is back in time),
+// loading older messages on reaching the top
+func (m *model) scroll(delta int) []cmd {
+	if m.open == nil {
+		return nil
+	}
+	m.open.scroll += delta
+	m.clampScroll()
+	if m.open.scroll == m.maxScroll() {
+		return m.loadOlder()
+	}
+	return nil
+}
+
+func (m *model) archive() []cmd {
+	chat, ok := m.targetChat()
+	if !ok {
+		return nil
+	}
+	archived := !chat.IsArchived
+	return []cmd{func() msg {
+		return chatArchived{chatID: chat.ID, archived: archived, err: m.b.ArchiveChat(chat.ID, archived)}
+	}}
+}
+
+func (m *model) markRead() []cmd {
+	chat, ok := m.targetChat()
+	if !ok {
+		return nil
+	}
+	return []cmd{func() msg {
+		return chatRead{chatID: chat.ID, err: m.b.MarkChatRead(chat.ID)}
+	}}
+}
+
+func (m *model) send() []cmd {
+	text := strings.TrimSpace(string(m.compose))
+	if m.open == nil || text == "" || m.sending {
+		return nil
+	}
+	// The draft stays until the message is sent, so a failure loses nothing
+	chatID := m.open.chat.ID
+	m.sending = true
+	m.setStatus("Sending…")
+	return []cmd{func() msg {
+		_, err := m.b.SendMessage(chatID, text)
+		return messageSent{chatID: chatID, text: text, err: err}
+	}}
+}
+
+func (m *model) runSearch() []cmd {
+	query := strings.TrimSpace(string(m.query))
+	if query == "" {
+		m.focus = focusChats
+		return nil
+	}
+	m.search = &searchResults{query: query, loading: true}
+	m.focus = focusSearchResults
+	m.setStatus("Searching for %q…", query)
+	return []cmd{func() msg {
+		resp, err := m.b.SearchMessagesPage(query, "", searchLimit)
+		return searchLoaded{query: query, resp: resp, err: err}
+	}}
+}
+
+// openSearchResult opens the chat holding the selected search result
+func (m *model) openSearchResult() []cmd {
+	if m.search == nil || m.search.selected >= len(m.search.items) {
+		return nil
+	}
+	chatID := m.search.items[m.search.selected].ChatID
+	chat := api.Chat{ID: chatID, Title: chatID}
+	if i := m.chatIndex(chatID); i >= 0 {
+		chat = m.chats[i]
+	}
+	m.search = nil
+	m.focus = focusMessages
+	return m.openChat(chat)
+}
+
+// handleKey applies a key press
+func (m *model) handleKey(k Key) []cmd {
+	if k.Code == KeyCtrl && k.Rune == 'c' {
+		m.quit = true
+		return nil
+	}
+
+	switch m.focus {
+	case focusFilter:
+		return m.filterKey(k)
+	case focusCompose:
+		return m.composeKey(k)
+	case focusSearchInput:
+		return m.searchInputKey(k)
+	case focusSearchResults:
+		return m.searchResultsKey(k)
+	case focusMessages:
+		return m.messagesKey(k)
+	}
+	return m.chatsKey(k)
+}
+
+func (m *model) chatsKey(k Key) []cmd {
+	switch {
+	case k.Code == KeyUp || k.is('k'):
+		m.selected = max(0, m.selected-1)
+	case k.Code == KeyDown || k.is('j'):
+		m.selected = min(max(0, len(m.visibleChats())-1), m.selected+1)
+		return m.loadMoreChats()
+	case k.Code == KeyPgUp:
+		m.selected = max(0, m.selected-m.bodyHeight())
+	case k.Code == KeyPgDn:
+		m.selected = min(max(0, len(m.visibleChats())-1), m.selected+m.bodyHeight())
+		return m.loadMoreChats()
+	case k.Code == KeyHome || k.is('g'):
+		m.selected = 0
+	case k.Code == KeyEnd || k.is('G'):
+		m.selected = max(0, len(m.visibleChats())-1)
+		return m.loadMoreChats()
+	case k.Code == KeyEnter || k.Code == KeyRight || k.is('l'):
+		if chat, ok := m.selectedChat(); ok {
+			m.focus = focusMessages
+			return m.openChat(chat)
+		}
+	case k.Code == KeyTab:
+		if m.open != nil {
+			m.focus = focusMessages
+		}
+	case k.is('c') || k.is('i'):
+		if chat, ok := m.selectedChat(); ok {
+			m.focus = focusCompose
+			return m.openChat(chat)
+		}
+	case k.is('/'):
+		m.focus = focusFilter
+	case k.Code == KeyEsc:
+		m.filter, m.selected = nil, 0
+	case k.is('s'):
+		m.query, m.focus = nil, focusSearchInput
+	case k.is('a'):
+		return m.archive()
+	case k.is('r'):
+		return m.markRead()
+	case k.is('R') || (k.Code == KeyCtrl && k.Rune == 'r'):
+		m.setStatus("Refreshing…")
+		return m.refresh()
+	case k.is('q'):
+		m.quit = true
+	}
+	return nil
+}
+
+func (m *model) filterKey(k Key) []cmd {
+	switch k.Code {
+	case KeyRune:
+		m.filter = append(m.filter, k.Rune)
+		m.selected = 0
+	case KeyBackspace:
+		if len(m.filter) > 0 {
+			m.filter = m.filter[:len(m.filter)-1]
+			m.selected = 0
+		}
+	case KeyEsc:
+		m.filter, m.selected = nil, 0
+		m.focus = focusChats
+	case KeyEnter:
+		m.focus = focusChats
+		if chat, ok := m.selectedChat(); ok {
+			m.focus = focusMessages
+			return m.openChat(chat)
+		}
+	case KeyUp, KeyDown:
+		return m.chatsKey(k)
+	}
+	return nil
+}
+
+func (m *model) messagesKey(k Key) []cmd {
+	page := max(1, m.bodyHeight()-2)
+	switch {
+	case k.Code == KeyUp || k.is('k'):
+		return m.scroll(1)
+	case k.Code == KeyDown || k.is('j'):
+		return m.scroll(-1)
+	case k.Code == KeyPgUp:
+		return m.scroll(page)
+	case k.Code == KeyPgDn:
+		return m.scroll(-page)
+	case k.Code == KeyHome || k.is('g'):
+		return m.scroll(m.maxScroll())
+	case k.Code == KeyEnd || k.is('G'):
+		return m.scroll(-m.maxScroll())
+	case k.Code == KeyEsc || k.Code == KeyLeft || k.Code == KeyTab || k.is('h') || k.is('q'):
+		m.focus = focusChats
+	case k.Code == KeyEnter || k.is('c') || k.is('i'):
+		m.focus = focusCompose
+	case k.is('s'):
+		m.query, m.focus = nil, focusSearchInput
+	case k.is('a'):
+		return m.archive()
+	case k.is('r'):
+		return m.markRead()
+	case k.is('R') || (k.Code == KeyCtrl && k.Rune == 'r'):
+		m.setStatus("Refreshing…")
+		return m.refresh()
+	}
+	return nil
+}
+
+func (m *model) composeKey(k Key) []cmd {
+	switch {
+	case k.Code == KeyRune:
+		m.compose = append(m.compose, k.Rune)
+	case k.Code == KeyBackspace:
+		if len(m.compose) > 0 {
+			m.compose = m.compose[:len(m.compose)-1]
+		}
+	case k.Code == KeyCtrl && k.Rune == 'u':
+		m.compose = nil
+	case k.Code == KeyEnter:
+		return m.send()
+	case k.Code == KeyEsc:
+		// The draft is kept until another chat is opened
+		m.focus = focusMessages
+	}
+	return nil
+}
+
+func (m *model) searchInputKey(k Key) []cmd {
+	switch k.Code {
+	case KeyRune:
+		m.query = append(m.query, k.Rune)
+	case KeyBackspace:
+		if len(m.query) > 0 {
+			m.query = m.query[:len(m.query)-1]
+		}
+	case KeyEnter:
+		return m.runSearch()
+	case KeyEsc:
+		m.query = nil
+		m.focus = focusChats
+		if m.search != nil {
+			m.focus = focusSearchResults
+		}
+	}
+	return nil
+}
+
+func (m *model) searchResultsKey(k Key) []cmd {
+	switch {
+	case k.Code == KeyUp || k.is('k'):
+		m.search.selected = max(0, m.search.selected-1)
+	case k.Code == KeyDown || k.is('j'):
+		m.search.selected = min(max(0, len(m.search.items)-1), m.search.selected+1)
+	case k.Code == KeyEnter:
+		return m.openSearchResult()
+	case k.is('s') || k.is('/'):
+		m.query, m.focus = nil, focusSearchInput
+	case k.Code == KeyEsc || k.is('q'):
+		m.search = nil
+		m.focus = focusChats
+	}
+	return nil
+}
+
+// is reports whether the key is the rune r
+func (k Key) is(r rune) bool {
+	return k.Code == KeyRune && k.Rune == r
+}
@@ -0,0 +1,297 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+type fakeBackend struct {
+	chats    []api.Chat
+	messages map[string][]api.Message // newest first
+	sent     []string
+	sendErr  error
+	archived map[string]bool
+	read     []string
+	queries  []string
+	cursors  []string
+}
+
+func (f *fakeBackend) ListChatsPage(cursor string) (*api.ChatsResponse, error) {
+	if cursor != "" {
+		return &api.ChatsResponse{}, nil
+	}
+	return &api.ChatsResponse{Items: f.chats}, nil
+}
+
+func (f *fakeBackend) ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error) {
+	f.cursors = append(f.cursors, cursor)
+	all := f.messages[chatID]
+	start := 0
+	if cursor != "" {
+		for start < len(all) && all[start].SortKey != cursor {
+			start++
+		}
+		start++
+	}
+	end := min(len(all), start+limit)
+	if start > end {
+		start = end
+	}
+	return &api.MessagesResponse{Items: all[start:end], HasMore: end < len(all)}, nil
+}
+
+func (f *fakeBackend) SearchMessagesPage(query, cursor string, limit int) (*api.SearchResponse, error) {
+	f.queries = append(f.queries, query)
+	return &api.SearchResponse{Items: []api.Message{{ID: "m1", ChatID: "c2", Sender: "Ann", Text: "lunch?"}}}, nil
+}
+
+func (f *fakeBackend) SendMessage(chatID, text string) (string, error) {
+	if f.sendErr != nil {
+		return "", f.sendErr
+	}
+	f.sent = append(f.sent, chatID+": "+text)
+	return "new", nil
+}
+
+func (f *fakeBackend) ArchiveChat(chatID string, archived bool) error {
+	if f.archived == nil {
+		f.archived = map[string]bool{}
+	}
+	f.archived[chatID] = archived
+	return nil
+}
+
+func (f *fakeBackend) MarkChatRead(chatID string) error {
+	f.read = append(f.read, chatID)
+	return nil
+}
+
+// history returns n messages, newest first, with increasing sort keys
+func history(chatID string, n int) []api.Message {
+	messages := make([]api.Message, n)
+	for i := range messages {
+		k := n - i
+		messages[i] = api.Message{ID: fmt.Sprintf("%s-%d", chatID, k), ChatID: chatID, Sender: "Ann", Text: fmt.Sprintf("message %d", k), SortKey: fmt.Sprint(k)}
+	}
+	return messages
+}
+
+// drive runs commands to completion, feeding results back into the model
+func drive(m *model, cmds []cmd) {
+	for len(cmds) > 0 {
+		c := cmds[0]
+		cmds = append(cmds[1:], m.update(c())...)
+	}
+}
+
+func typeKeys(m *model, s string) {
+	for _, r := range s {
+		drive(m, m.handleKey(Key{Code: KeyRune, Rune: r}))
+	}
+}
+
+func press(m *model, code KeyCode) {
+	drive(m, m.handleKey(Key{Code: code}))
+}
+
+var ansi = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+func screen(m *model) string {
+	return ansi.ReplaceAllString(strings.Join(m.view(), "\n"), "")
+}
+
+func newTestModel(t *testing.T) (*model, *fakeBackend) {
+	t.Helper()
+	b := &fakeBackend{
+		chats: []api.Chat{
+			{ID: "c1", Title: "Family", Network: "whatsapp", UnreadCount: 3, IsPinned: true},
+			{ID: "c2", Title: "Work", Network: "slack"},
+			{ID: "c3", Title: "Book club", Network: "signal", UnreadCount: 1},
+		},
+		messages: map[string][]api.Message{
+			"c1": history("c1", 30),
+			"c2": history("c2", 2),
+		},
+	}
+	m := newModel(b, 10)
+	m.resize(80, 12)
+	drive(m, m.init())
+	return m, b
+}
+
+func TestChatList(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	s := screen(m)
+	assert.Contains(t, s, "3 chats · 2 unread")
+	assert.Contains(t, s, "*● Family")
+	assert.Contains(t, s, " ● Book club")
+	assert.Regexp(t, `Family\s+3`, s)
+}
+
+func TestFilterAsYouType(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	typeKeys(m, "/bo")
+	require.Len(t, m.visibleChats(), 1)
+	assert.Contains(t, screen(m), " / bo")
+
+	press(m, KeyEnter)
+	require.NotNil(t, m.open)
+	assert.Equal(t, "c3", m.open.chat.ID)
+	assert.Equal(t, focusMessages, m.focus)
+}
+
+func TestOpenChatLoadsScrollback(t *testing.T) {
+	m, b := newTestModel(t)
+
+	press(m, KeyEnter)
+	require.NotNil(t, m.open)
+	// Ten messages fill the 8-row pane; no more pages are needed yet
+	assert.Len(t, m.open.messages, 10)
+	assert.Contains(t, screen(m), "message 30")
+
+	// Scrolling to the top loads the next page back
+	press(m, KeyHome)
+	assert.Len(t, m.open.messages, 20)
+	assert.Equal(t, []string{"", "21"}, b.cursors)
+	assert.Equal(t, "21", m.open.messages[10].SortKey, "messages stay oldest first")
+
+	press(m, KeyEnd)
+	assert.Equal(t, 0, m.open.scroll)
+}
+
+func TestSmallChatLoadsWholeHistory(t *testing.T) {
+	m, _ := newTestModel(t)
+
+	typeKeys(m, "j")
+	press(m, KeyEnter)
+	assert.Len(t, m.open.messages, 2)
+	assert.False(t, m.open.hasMore)
+}
+
+func TestCompose(t *testing.T) {
+	m, b := newTestModel(t)
+
+	typeKeys(m, "c")
+	assert.Equal(t, focusCompose, m.focus)
+	typeKeys(m, "hi there")
+	assert.Contains(t, screen(m), " > hi there")
+
+	press(m, KeyEnter)
+	assert.Equal(t, []string{"c1: hi there"}, b.sent)
+	assert.Empty(t, m.compose)
+	assert.Equal(t, "Sent", m.status)
+}
+
+func TestCompose_FailedSendKeepsDraft(t *testing.T) {
+	m, b := newTestModel(t)
+	b.sendErr = errors.New("network down")
+
+	typeKeys(m, "chi there")
+	cmds := m.handleKey(Key{Code: KeyEnter})
+	assert.Equal(t, "hi there", string(m.compose), "the draft stays while sending")
+	assert.Nil(t, m.handleKey(Key{Code: KeyEnter}), "no second send while one is pending")
+	drive(m, cmds)
+
+	assert.Empty(t, b.sent)
+	assert.Equal(t, "hi there", string(m.compose))
+	assert.True(t, m.statusErr)
+
+	b.sendErr = nil
+	press(m, KeyEnter)
+	assert.Equal(t, []string{"c1: hi there"}, b.sent)
+	assert.Empty(t, m.compose)
+}
+
+func TestArchiveAndMarkRead(t *testing.T) {
+	m, b := newTestModel(t)
+
+	typeKeys(m, "r")
+	assert.Equal(t, []string{"c1"}, b.read)
+	assert.Equal(t, 0, m.chats[0].UnreadCount)
+
+	typeKeys(m, "a")
+	assert.True(t, b.archived["c1"])
+	assert.True(t, m.chats[0].IsArchived)
+
+	// From the message pane, actions apply to the open chat
+	typeKeys(m, "j")
+	press(m, KeyEnter)
+	typeKeys(m, "a")
+	assert.True(t, b.archived["c2"])
+}
+
+func TestSearch(t *testing.T) {
+	m, b := newTestModel(t)
+
+	typeKeys(m, "slunch")
+	press(m, KeyEnter)
+	assert.Equal(t, []string{"lunch"}, b.queries)
+	assert.Contains(t, screen(m), "Work · Ann: lunch?")
+
+	press(m, KeyEnter)
+	require.NotNil(t, m.open)
+	assert.Equal(t, "c2", m.open.chat.ID)
+	assert.Nil(t, m.search)
+}
+
+func TestRefreshMergesNewChatsAndMessages(t *testing.T) {
+	m, b := newTestModel(t)
+	typeKeys(m, "j")
+	press(m, KeyEnter)
+
+	b.chats = append([]api.Chat{{ID: "c4", Title: "New"}}, b.chats...)
+	b.messages["c2"] = append([]api.Message{{ID: "c2-3", ChatID: "c2", Text: "fresh", SortKey: "3"}}, b.messages["c2"]...)
+	drive(m, m.update(tick{}))
+
+	assert.Equal(t, "c4", m.chats[0].ID)
+	chat, ok := m.selectedChat()
+	require.True(t, ok)
+	assert.Equal(t, "c2", chat.ID, "selection follows the chat")
+	require.Len(t, m.open.messages, 3)
+	assert.Equal(t, "fresh", m.open.messages[2].Text)
+}
+
+func TestRefreshPagesBackToHeldMessages(t *testing.T) {
+	m, b := newTestModel(t)
+	press(m, KeyEnter)
+	require.Len(t, m.open.messages, 10)
+
+	// 25 new messages are more than a page: refresh pages back until it
+	// meets the messages already held, leaving no gap
+	b.messages["c1"] = history("c1", 55)
+	b.cursors = nil
+	drive(m, m.update(tick{}))
+
+	assert.Equal(t, []string{"", "46", "36"}, b.cursors)
+	require.Len(t, m.open.messages, 35)
+	for i, message := range m.open.messages {
+		assert.Equal(t, fmt.Sprint(21+i), message.SortKey)
+	}
+	assert.True(t, m.open.hasMore)
+}
+
+func TestWrap(t *testing.T) {
+	assert.Equal(t, []string{"one two", "  three"}, wrap("one two three", 8, 2))
+	assert.Equal(t, []string{"abcdefgh", "  ijkl"}, wrap("abcdefghijkl", 8, 2))
+	assert.Equal(t, []string{"a", "  b"}, wrap("a\nb", 8, 2))
+}
+
+func TestQuit(t *testing.T) {
+	m, _ := newTestModel(t)
+	typeKeys(m, "q")
+	assert.True(t, m.quit)
+
+	m, _ = newTestModel(t)
+	drive(m, m.handleKey(Key{Code: KeyCtrl, Rune: 'c'}))
+	assert.True(t, m.quit)
+}
@@ -0,0 +1,129 @@
+// Package tui is a full-screen terminal interface for triaging chats: a
+// chat list, a scrollable message pane and a compose box.
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Options configures the interface
+type Options struct {
+	// PollInterval is how often the chat list and open chat are refreshed
+	PollInterval time.Duration
+	// PageSize is the number of messages loaded per page of scrollback
+	PageSize int
+}
+
+// ErrNotTerminal is returned when stdin or stdout is not a terminal
+var ErrNotTerminal = errors.New("beeper tui needs an interactive terminal")
+
+// Terminal control sequences
+const (
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+	exitAltScreen  = "\x1b[?25h\x1b[?1049l"
+	cursorHome     = "\x1b[H"
+)
+
+// Run shows the interface until the user quits
+func Run(b Backend, opts Options) error {
+	inFd, outFd := int(os.Stdin.Fd()), int(os.Stdout.Fd())
+	if !term.IsTerminal(inFd) || !term.IsTerminal(outFd) {
+		return ErrNotTerminal
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 50
+	}
+
+	state, err := term.MakeRaw(inFd)
+	if err != nil {
+		return fmt.Errorf("failed to set up terminal: %w", err)
+	}
+	defer term.Restore(inFd, state)
+
+	out := bufio.NewWriter(os.Stdout)
+	out.WriteString(enterAltScreen)
+	defer func() {
+		out.WriteString(exitAltScreen)
+		out.Flush()
+	}()
+
+	m := newModel(b, opts.PageSize)
+	if width, height, err := term.GetSize(outFd); err == nil {
+		m.resize(width, height)
+	}
+
+	keys := make(chan []Key)
+	go readKeys(keys)
+
+	results := make(chan msg, 16)
+	run := func(cmds []cmd) {
+		for _, c := range cmds {
+			go func() { results <- c() }()
+		}
+	}
+	run(m.init())
+
+	poll := time.NewTicker(opts.PollInterval)
+	defer poll.Stop()
+	// Resizes are picked up by polling the size, which works on every platform
+	resize := time.NewTicker(250 * time.Millisecond)
+	defer resize.Stop()
+
+	draw(out, m)
+	for !m.quit {
+		select {
+		case batch, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range batch {
+				run(m.handleKey(k))
+			}
+		case r := <-results:
+			run(m.update(r))
+		case <-poll.C:
+			run(m.update(tick{}))
+		case <-resize.C:
+			width, height, err := term.GetSize(outFd)
+			if err != nil || (width == m.width && height == m.height) {
+				continue
+			}
+			m.resize(width, height)
+		}
+		draw(out, m)
+	}
+	return nil
+}
+
+// readKeys sends key presses from stdin until it is closed
+func readKeys(keys chan<- []Key) {
+	buf := make([]byte, 256)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			keys <- parseKeys(buf[:n])
+		}
+		if err != nil {
+			close(keys)
+			return
+		}
+	}
+}
+
+// draw repaints the whole screen. Every row is padded to the full width,
+// so nothing needs clearing first and the screen doesn't flicker.
+func draw(out *bufio.Writer, m *model) {
+	out.WriteString(cursorHome)
+	out.WriteString(strings.Join(m.view(), "\r\n"))
+	out.Flush()
+}
@@ -0,0 +1,332 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// ANSI styles
+const (
+	styleReset   = "\x1b[0m"
+	styleBold    = "\x1b[1m"
+	styleDim     = "\x1b[2m"
+	styleReverse = "\x1b[7m"
+	styleRed     = "\x1b[31m"
+	styleCyan    = "\x1b[36m"
+)
+
+// line is one row of a pane: plain text and the style it is drawn in
+type line struct {
+	text  string
+	style string
+}
+
+// help lists the keys available in each focus
+var help = map[focus]string{
+	focusChats:         "↑↓ move  enter open  / filter  c compose  a archive  r read  s search  R refresh  q quit",
+	focusFilter:        "type to filter  ↑↓ move  enter open  esc clear",
+	focusMessages:      "↑↓ pgup pgdn scroll  enter compose  a archive  r read  s search  esc chats",
+	focusCompose:       "enter send  esc back  ctrl-u clear",
+	focusSearchInput:   "enter search  esc cancel",
+	focusSearchResults: "↑↓ move  enter open chat  s new search  esc close",
+}
+
+// view renders the screen as one string per row
+func (m *model) view() []string {
+	rows := make([]string, 0, m.height)
+	rows = append(rows, styled(fit(m.header(), m.width), styleReverse))
+
+	left := m.chatLines()
+	right := m.rightLines()
+	lw, rw := m.leftWidth(), m.rightWidth()
+	for i := 0; i < m.bodyHeight(); i++ {
+		var l, r line
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		rows = append(rows, styled(fit(l.text, lw), l.style)+styled("│", styleDim)+styled(fit(r.text, rw), r.style))
+	}
+
+	input := m.inputLine()
+	rows = append(rows, styled(fit(input.text, m.width), input.style))
+
+	status := line{text: help[m.focus], style: styleDim}
+	if m.status != "" {
+		status = line{text: m.status + "  ·  " + help[m.focus], style: styleDim}
+		if m.statusErr {
+			status = line{text: m.status, style: styleRed}
+		}
+	}
+	rows = append(rows, styled(fit(status.text, m.width), status.style))
+	return rows[:min(len(rows), m.height)]
+}
+
+func (m *model) header() string {
+	unread := 0
+	for _, chat := range m.chats {
+		if chat.UnreadCount > 0 {
+			unread++
+		}
+	}
+	h := fmt.Sprintf(" Beeper  %d chats · %d unread", len(m.chats), unread)
+	if m.chatsLoading {
+		h += " · loading…"
+	}
+	return h
+}
+
+// chatLines renders the chat list, scrolled to keep the selection visible
+func (m *model) chatLines() []line {
+	chats := m.visibleChats()
+	height := m.bodyHeight()
+	if len(chats) == 0 {
+		if len(m.filter) > 0 {
+			return []line{{text: " No chats match", style: styleDim}}
+		}
+		return nil
+	}
+
+	start := 0
+	if m.selected >= height {
+		start = m.selected - height + 1
+	}
+	width := m.leftWidth()
+	var lines []line
+	for i := start; i < len(chats) && len(lines) < height; i++ {
+		chat := chats[i]
+		marker := " "
+		if chat.IsPinned {
+			marker = "*"
+		}
+		if chat.UnreadCount > 0 {
+			marker += "●"
+		} else {
+			marker += " "
+		}
+		count := ""
+		if chat.UnreadCount > 0 {
+			count = fmt.Sprintf(" %d", chat.UnreadCount)
+		}
+		text := marker + " " + fit(chatTitle(chat), width-3-runewidth.StringWidth(count)) + count
+
+		style := ""
+		switch {
+		case i == m.selected && (m.focus == focusChats || m.focus == focusFilter):
+			style = styleReverse
+		case i == m.selected:
+			style = styleBold
+		case chat.IsArchived:
+			style = styleDim
+		case chat.UnreadCount > 0:
+			style = styleBold
+		}
+		lines = append(lines, line{text: text, style: style})
+	}
+	return lines
+}
+
+// rightLines renders search results, the open chat, or a hint
+func (m *model) rightLines() []line {
+	if m.search != nil {
+		return m.searchLines()
+	}
+	if m.open == nil {
+		return []line{{text: " Select a chat and press enter", style: styleDim}}
+	}
+
+	title := chatTitle(m.open.chat)
+	if m.open.chat.Network != "" {
+		title += " · " + m.open.chat.Network
+	}
+	if m.open.chat.IsArchived {
+		title += " · archived"
+	}
+	lines := []line{{text: " " + title, style: styleBold}}
+
+	all := m.messageLines()
+	height := m.bodyHeight() - 1
+	end := len(all) - m.open.scroll
+	start := max(0, end-height)
+	if start == 0 && m.open.loading {
+		lines = append(lines, line{text: " loading…", style: styleDim})
+		height--
+		start = max(0, end-height)
+	}
+	// Short histories sit at the bottom, next to the compose box
+	visible := all[start:max(start, end)]
+	for i := len(visible); i < height; i++ {
+		lines = append(lines, line{})
+	}
+	return append(lines, visible...)
+}
+
+// messageLines renders the open chat's messages, wrapped to the pane width
+func (m *model) messageLines() []line {
+	if m.open == nil {
+		return nil
+	}
+	width := m.rightWidth() - 1
+	var lines []line
+	for _, message := range m.open.messages {
+		sender := message.Sender
+		if message.IsSender {
+			sender = "You"
+		}
+		prefix := formatTime(message.Timestamp) + " " + sender + ": "
+		text := message.Text
+		if text == "" && len(message.Attachments) > 0 {
+			text = fmt.Sprintf("[%d attachment(s)]", len(message.Attachments))
+		}
+		for i, row := range wrap(prefix+text, width, 2) {
+			style := ""
+			if i == 0 && message.IsSender {
+				style = styleCyan
+			}
+			lines = append(lines, line{text: " " + row, style: style})
+		}
+	}
+	return lines
+}
+
+func (m *model) searchLines() []line {
+	lines := []line{{text: fmt.Sprintf(" Search: %s", m.search.query), style: styleBold}}
+	if m.search.loading {
+		return append(lines, line{text: " searching…", style: styleDim})
+	}
+	if len(m.search.items) == 0 {
+		return append(lines, line{text: " No results", style: styleDim})
+	}
+
+	height := m.bodyHeight() - 1
+	start := 0
+	if m.search.selected >= height {
+		start = m.search.selected - height + 1
+	}
+	for i := start; i < len(m.search.items) && len(lines) <= height; i++ {
+		message := m.search.items[i]
+		chat := message.ChatID
+		if j := m.chatIndex(message.ChatID); j >= 0 {
+			chat = chatTitle(m.chats[j])
+		}
+		text := fmt.Sprintf(" %s · %s: %s", chat, message.Sender, strings.Join(strings.Fields(message.Text), " "))
+		style := ""
+		if i == m.search.selected {
+			style = styleReverse
+		}
+		lines = append(lines, line{text: text, style: style})
+	}
+	return lines
+}
+
+// inputLine is the filter, search or compose prompt
+func (m *model) inputLine() line {
+	cursor := "█"
+	switch m.focus {
+	case focusFilter:
+		return line{text: " / " + string(m.filter) + cursor}
+	case focusSearchInput:
+		return line{text: " search: " + string(m.query) + cursor}
+	case focusCompose:
+		return line{text: " > " + tail(string(m.compose)+cursor, m.width-3)}
+	}
+	if len(m.filter) > 0 {
+		return line{text: " / " + string(m.filter), style: styleDim}
+	}
+	if len(m.compose) > 0 && m.open != nil {
+		return line{text: " > " + tail(string(m.compose), m.width-3), style: styleDim}
+	}
+	return line{}
+}
+
+// formatTime shows today's messages as a time and older ones with the date
+func formatTime(timestamp string) string {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return timestamp
+	}
+	t = t.Local()
+	now := time.Now()
+	if t.Year() == now.Year() && t.YearDay() == now.YearDay() {
+		return t.Format("15:04")
+	}
+	return t.Format("Jan 2 15:04")
+}
+
+// wrap breaks text into rows of at most width cells, breaking at spaces
+// where possible; continuation rows are indented
+func wrap(text string, width, indent int) []string {
+	if width <= indent+1 {
+		return []string{text}
+	}
+	pad := strings.Repeat(" ", indent)
+	var rows []string
+	row := ""
+	for p, paragraph := range strings.Split(text, "\n") {
+		if p > 0 {
+			rows, row = append(rows, row), pad
+		}
+		for i, word := range strings.Split(paragraph, " ") {
+			next := word
+			if i > 0 {
+				next = " " + word
+			}
+			if runewidth.StringWidth(row+next) > width && strings.TrimSpace(row) != "" {
+				rows, row, next = append(rows, row), pad, word
+			}
+			row += next
+			// Hard-break words longer than a row
+			for runewidth.StringWidth(row) > width {
+				head := runewidth.Truncate(row, width, "")
+				rows, row = append(rows, head), pad+strings.TrimPrefix(row, head)
+			}
+		}
+	}
+	return append(rows, row)
+}
+
+// fit truncates or pads s to exactly width cells
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 {
+			return ' '
+		}
+		return r
+	}, s)
+	return runewidth.FillRight(runewidth.Truncate(s, width, "…"), width)
+}
+
+// tail keeps the end of s that fits in width cells, so typing stays visible
+func tail(s string, width int) string {
+	for s != "" && runewidth.StringWidth(s) > width {
+		_, size := utf8.DecodeRuneInString(s)
+		s = s[size:]
+	}
+	return s
+}
+
+func styled(s, style string) string {
+	if style == "" {
+		return s
+	}
+	return style + s + styleReset
+}
+
+// chatTitle returns a chat's title, or its ID when untitled
+func chatTitle(chat api.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	return chat.ID
+}
@@ -0,0 +1,248 @@
+// Package watch polls Beeper for new messages. It remembers the newest
+// message seen in each chat, so every message is reported once, and across
+// restarts when the state is saved between polls.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+)
+
+// DefaultPageSize is the number of messages requested per page
+const DefaultPageSize = 20
+
+// Source is the part of the API client messages are polled from
+type Source interface {
+	ListChatsPage(cursor string) (*api.ChatsResponse, error)
+	ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error)
+}
+
+// State is how far a watcher has read
+type State struct {
+	// Polled is when the previous poll began. In a chat seen for the first
+	// time only messages from then on are new.
+	Polled time.Time `json:"polled"`
+	// Cursors maps chat IDs to the sort key of the newest message seen
+	Cursors map[string]string `json:"cursors"`
+	// Pending maps chats seen for the first time that couldn't be read to
+	// when they were first polled, so the messages from then on are still
+	// new once they can be
+	Pending map[string]time.Time `json:"pending,omitempty"`
+}
+
+// Event is a new message and the chat it arrived in
+type Event struct {
+	Chat    api.Chat
+	Message api.Message
+}
+
+// Watcher finds messages that arrived since the previous poll
+type Watcher struct {
+	Source Source
+	// Chats are the chats polled. If empty, the most recently active chats
+	// (the first page of the chat list) are polled.
+	Chats    []api.Chat
+	PageSize int
+	// MaxPages caps how far back a chat is read in one poll (no limit if 0).
+	// When it is reached the older new messages are skipped, with a warning.
+	MaxPages int
+	State    *State
+	// Now returns the current time (time.Now if nil)
+	Now func() time.Time
+}
+
+// Poll returns the messages that arrived since the previous poll, oldest
+// first within each chat, and moves the cursors past them. A chat that
+// can't be read is skipped and reported in the error, alongside the events
+// of the others; so is a chat that hit MaxPages, with what was read.
+func (w *Watcher) Poll() ([]Event, error) {
+	if w.State.Cursors == nil {
+		w.State.Cursors = make(map[string]string)
+	}
+	if w.State.Pending == nil {
+		w.State.Pending = make(map[string]time.Time)
+	}
+	now := w.now()
+	since := w.State.Polled
+	if since.IsZero() {
+		since = now
+	}
+
+	chats := w.Chats
+	if len(chats) == 0 {
+		page, err := w.Source.ListChatsPage("")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list chats: %w", err)
+		}
+		chats = page.Items
+	}
+
+	var events []Event
+	var errs []error
+	for _, chat := range chats {
+		chat.Participants = nil
+		chatSince := since
+		if pending, ok := w.State.Pending[chat.ID]; ok {
+			chatSince = pending
+		}
+		messages, err := w.newMessages(chat.ID, chatSince)
+		if errors.Is(err, errMaxPages) {
+			errs = append(errs, fmt.Errorf("chat %s: %w", chat.ID, err))
+		} else if err != nil {
+			if _, seen := w.State.Cursors[chat.ID]; !seen {
+				w.State.Pending[chat.ID] = chatSince
+			}
+			errs = append(errs, fmt.Errorf("failed to poll chat %s: %w", chat.ID, err))
+			continue
+		}
+		delete(w.State.Pending, chat.ID)
+		for _, msg := range messages {
+			events = append(events, Event{Chat: chat, Message: msg})
+		}
+	}
+	w.State.Polled = now
+	return events, errors.Join(errs...)
+}
+
+// errMaxPages reports a poll that stopped at MaxPages short of the cursor
+var errMaxPages = errors.New("too many new messages to read; older ones were skipped")
+
+// newMessages pages back through a chat until it reaches its cursor (or,
+// without one, since) and returns what is newer, oldest first. If MaxPages
+// runs out first it returns what it read along with errMaxPages.
+func (w *Watcher) newMessages(chatID string, since time.Time) ([]api.Message, error) {
+	pageSize := w.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	last, seen := w.State.Cursors[chatID]
+
+	var messages []api.Message
+	var truncated error
+	newest := last
+	cursor := ""
+	for pages := 1; ; pages++ {
+		page, err := w.Source.ListMessagesPage(chatID, cursor, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		reached := false
+		for _, msg := range page.Items {
+			if newest == "" || api.CompareSortKeys(msg.SortKey, newest) > 0 {
+				newest = msg.SortKey
+			}
+			if seen && api.CompareSortKeys(msg.SortKey, last) <= 0 {
+				reached = true
+				continue
+			}
+			// Timestamps may be whole seconds, so a message from the
+			// second the previous poll began counts as new
+			if !seen {
+				if t, ok := msg.Time(); !ok || t.Before(since.Truncate(time.Second)) {
+					reached = true
+					continue
+				}
+			}
+			messages = append(messages, msg)
+		}
+		next := page.NextCursor()
+		if reached || !page.HasMore || next == "" || next == cursor {
+			break
+		}
+		if w.MaxPages > 0 && pages >= w.MaxPages {
+			truncated = errMaxPages
+			break
+		}
+		cursor = next
+	}
+
+	if newest != "" {
+		w.State.Cursors[chatID] = newest
+	}
+	// The API returns newest first
+	slices.Reverse(messages)
+	return messages, truncated
+}
+
+// Run polls right away and then every interval until ctx is done, passing
+// each poll's events to handle, even when there are none, so the caller can
+// save the state. A failed poll is passed to onError and polling carries
+// on; an error from handle stops Run and is returned.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration, handle func([]Event) error, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		events, err := w.Poll()
+		if err != nil {
+			onError(err)
+		}
+		if err := handle(events); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) now() time.Time {
+	if w.Now != nil {
+		return w.Now()
+	}
+	return time.Now()
+}
+
+// LoadState reads a state file into v. A missing file leaves v unchanged.
+func LoadState(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read state: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse state %s: %w", path, err)
+	}
+	return nil
+}
+
+// SaveState writes v to a state file through a temporary file, so a crash
+// never leaves a partial state behind
+func SaveState(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nerveband/beeper-api-cli/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+
+// fakeSource serves chats and their messages, newest first, two per page
+type fakeSource struct {
+	chats    []api.Chat
+	messages map[string][]api.Message
+	failing  string
+}
+
+func (s *fakeSource) ListChatsPage(cursor string) (*api.ChatsResponse, error) {
+	return &api.ChatsResponse{Items: s.chats}, nil
+}
+
+func (s *fakeSource) ListMessagesPage(chatID, cursor string, limit int) (*api.MessagesResponse, error) {
+	if chatID == s.failing {
+		return nil, errors.New("timed out")
+	}
+	messages := s.messages[chatID]
+	begin := 0
+	for i, m := range messages {
+		if m.SortKey == cursor {
+			begin = i + 1
+		}
+	}
+	end := min(begin+2, len(messages))
+	return &api.MessagesResponse{Items: messages[begin:end], HasMore: end < len(messages)}, nil
+}
+
+// add posts a message n minutes after start
+func (s *fakeSource) add(chatID string, n int) {
+	msg := api.Message{
+		ID:        fmt.Sprintf("%s-%d", chatID, n),
+		SortKey:   fmt.Sprint(n),
+		Text:      fmt.Sprint(n),
+		Timestamp: start.Add(time.Duration(n) * time.Minute).Format(time.RFC3339),
+	}
+	s.messages[chatID] = append([]api.Message{msg}, s.messages[chatID]...)
+}
+
+func texts(events []Event) []string {
+	var out []string
+	for _, ev := range events {
+		out = append(out, ev.Chat.ID+":"+ev.Message.Text)
+	}
+	return out
+}
+
+func TestPoll(t *testing.T) {
+	src := &fakeSource{
+		chats:    []api.Chat{{ID: "a"}, {ID: "b"}},
+		messages: map[string][]api.Message{},
+	}
+	src.add("a", 1)
+	src.add("a", 2)
+
+	now := start.Add(10 * time.Minute)
+	w := &Watcher{Source: src, State: &State{}, Now: func() time.Time { return now }}
+
+	// History before the first poll is not new
+	events, err := w.Poll()
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Equal(t, "2", w.State.Cursors["a"])
+
+	// Five new messages span three pages and come back oldest first; a
+	// chat without a cursor reports only messages from the previous poll on
+	for n := 11; n <= 15; n++ {
+		src.add("a", n)
+	}
+	src.add("b", 5)
+	src.add("b", 10)
+	src.add("b", 12)
+	now = start.Add(20 * time.Minute)
+	events, err = w.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a:11", "a:12", "a:13", "a:14", "a:15", "b:10", "b:12"}, texts(events))
+
+	events, err = w.Poll()
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestPoll_Backlog(t *testing.T) {
+	src := &fakeSource{chats: []api.Chat{{ID: "a"}}, messages: map[string][]api.Message{}}
+	w := &Watcher{Source: src, State: &State{Cursors: map[string]string{"a": "0"}}}
+	var want []string
+	for n := 1; n <= 25; n++ {
+		src.add("a", n)
+		want = append(want, fmt.Sprintf("a:%d", n))
+	}
+
+	// After a downtime every page back to the cursor is read
+	events, err := w.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, want, texts(events))
+}
+
+func TestPoll_MaxPages(t *testing.T) {
+	src := &fakeSource{chats: []api.Chat{{ID: "a"}}, messages: map[string][]api.Message{}}
+	w := &Watcher{Source: src, State: &State{Cursors: map[string]string{"a": "0"}}, MaxPages: 2}
+	for n := 1; n <= 6; n++ {
+		src.add("a", n)
+	}
+
+	// Only two pages are read; the older new messages are skipped with a
+	// warning
+	events, err := w.Poll()
+	assert.ErrorContains(t, err, "chat a: too many new messages to read")
+	assert.Equal(t, []string{"a:3", "a:4", "a:5", "a:6"}, texts(events))
+	assert.Equal(t, "6", w.State.Cursors["a"])
+}
+
+func TestPoll_ChatError(t *testing.T) {
+	src := &fakeSource{
+		chats:    []api.Chat{{ID: "a"}, {ID: "b"}},
+		messages: map[string][]api.Message{},
+		failing:  "a",
+	}
+	now := start.Add(10 * time.Minute)
+	w := &Watcher{Source: src, State: &State{Polled: start}, Now: func() time.Time { return now }}
+	src.add("a", 1)
+	src.add("b", 1)
+
+	events, err := w.Poll()
+	assert.ErrorContains(t, err, "failed to poll chat a: timed out")
+	assert.Equal(t, []string{"b:1"}, texts(events))
+	assert.Equal(t, start, w.State.Pending["a"])
+
+	// Once the new chat can be read, its messages since it was first
+	// polled are still new
+	src.failing = ""
+	now = start.Add(20 * time.Minute)
+	events, err = w.Poll()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a:1"}, texts(events))
+	assert.Empty(t, w.State.Pending)
+}
+
+func TestPoll_Chats(t *testing.T) {
+	src := &fakeSource{chats: []api.Chat{{ID: "a"}, {ID: "b"}}, messages: map[string][]api.Message{}}
+	w := &Watcher{Source: src, Chats: []api.Chat{{ID: "b", Title: "Bob"}}, State: &State{Polled: start}}
+	src.add("a", 1)
+	src.add("b", 1)
+
+	events, err := w.Poll()
+	require.NoError(t, err)
+	require.Equal(t, []string{"b:1"}, texts(events))
+	assert.Equal(t, "Bob", events[0].Chat.Title)
+}
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "alert.json")
+
+	var state State
+	require.NoError(t, LoadState(path, &state))
+	assert.Empty(t, state.Cursors)
+
+	state = State{Polled: start, Cursors: map[string]string{"a": "42"}}
+	require.NoError(t, SaveState(path, state))
+
+	var loaded State
+	require.NoError(t, LoadState(path, &loaded))
+	assert.Equal(t, "42", loaded.Cursors["a"])
+	assert.True(t, loaded.Polled.Equal(start))
+}
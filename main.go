@@ -1,6 +1,9 @@
 package main
 
 import (
+	// Embed the time zone database so --tz works on systems without one
+	_ "time/tzdata"
+
 	"github.com/nerveband/beeper-api-cli/cmd"
 )
 